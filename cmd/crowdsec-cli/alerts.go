@@ -13,6 +13,7 @@ import (
 	"github.com/crowdsecurity/crowdsec/pkg/apiclient"
 	"github.com/crowdsecurity/crowdsec/pkg/cwversion"
 	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
 	"github.com/go-openapi/strfmt"
 	"github.com/olekukonko/tablewriter"
 	log "github.com/sirupsen/logrus"
@@ -43,6 +44,37 @@ func DecisionsFromAlert(alert *models.Alert) string {
 	return ret
 }
 
+//groupIncidents merges the alerts of the sources of each incident, so that the incident is displayed as a single alert
+func groupIncidents(alerts models.GetAlertsResponse) []*models.Alert {
+	ret := []*models.Alert{}
+	incidents := make(map[string]*models.Alert)
+	for _, alertItem := range alerts {
+		incident := alertItem.GetIncident()
+		if incident == "" {
+			ret = append(ret, alertItem)
+			continue
+		}
+		if merged, ok := incidents[incident]; ok {
+			merged.Decisions = append(merged.Decisions, alertItem.Decisions...)
+			continue
+		}
+		merged := *alertItem
+		merged.Decisions = append([]*models.Decision{}, alertItem.Decisions...)
+		incidents[incident] = &merged
+		ret = append(ret, &merged)
+	}
+	return ret
+}
+
+//incidentSummary describes the sources and the target of the incident of the alert
+func incidentSummary(alert *models.Alert) string {
+	summary := fmt.Sprintf("%s:%d sources", *alert.Source.Scope, alert.GetSourcesCount())
+	if target := alert.GetMeta(types.IncidentTargetMeta); target != "" {
+		summary += " against " + target
+	}
+	return summary
+}
+
 func AlertsToTable(alerts *models.GetAlertsResponse, printMachine bool) error {
 
 	if csConfig.Cscli.Output == "raw" {
@@ -92,19 +124,27 @@ func AlertsToTable(alerts *models.GetAlertsResponse, printMachine bool) error {
 			fmt.Println("No active alerts")
 			return nil
 		}
-		for _, alertItem := range *alerts {
+		for _, alertItem := range groupIncidents(*alerts) {
 
 			displayVal := *alertItem.Source.Scope
 			if *alertItem.Source.Value != "" {
 				displayVal += ":" + *alertItem.Source.Value
 			}
+			country := alertItem.Source.Cn
+			as := alertItem.Source.AsNumber + " " + alertItem.Source.AsName
+			/*the sources of an incident are displayed as one alert*/
+			if alertItem.GetIncident() != "" {
+				displayVal = incidentSummary(alertItem)
+				country = ""
+				as = ""
+			}
 			if printMachine {
 				table.Append([]string{
 					strconv.Itoa(int(alertItem.ID)),
 					displayVal,
 					*alertItem.Scenario,
-					alertItem.Source.Cn,
-					alertItem.Source.AsNumber + " " + alertItem.Source.AsName,
+					country,
+					as,
 					DecisionsFromAlert(alertItem),
 					*alertItem.StartAt,
 					alertItem.MachineID,
@@ -114,8 +154,8 @@ func AlertsToTable(alerts *models.GetAlertsResponse, printMachine bool) error {
 					strconv.Itoa(int(alertItem.ID)),
 					displayVal,
 					*alertItem.Scenario,
-					alertItem.Source.Cn,
-					alertItem.Source.AsNumber + " " + alertItem.Source.AsName,
+					country,
+					as,
 					DecisionsFromAlert(alertItem),
 					*alertItem.StartAt,
 				})
@@ -141,7 +181,14 @@ func DisplayOneAlert(alert *models.Alert, withDetail bool) error {
 		fmt.Printf(" - Events Count : %d\n", *alert.EventsCount)
 		fmt.Printf(" - Scope:Value: %s\n", scopeAndValue)
 		fmt.Printf(" - Country    : %s\n", alert.Source.Cn)
-		fmt.Printf(" - AS         : %s\n\n", alert.Source.AsName)
+		fmt.Printf(" - AS         : %s\n", alert.Source.AsName)
+		if incident := alert.GetIncident(); incident != "" {
+			fmt.Printf(" - Incident   : %s (%s)\n", incident, incidentSummary(alert))
+		}
+		if len(alert.Labels) > 0 {
+			fmt.Printf(" - Labels     : %s\n", strings.Join(alert.Labels, ", "))
+		}
+		fmt.Printf("\n")
 		foundActive := false
 		table := tablewriter.NewWriter(os.Stdout)
 		table.SetHeader([]string{"ID", "scope:value", "action", "expiration", "created_at"})
@@ -172,6 +219,19 @@ func DisplayOneAlert(alert *models.Alert, withDetail bool) error {
 			table.Render() // Send output
 		}
 
+		if len(alert.Meta) > 0 {
+			fmt.Printf("\n - Context  :\n")
+			table = tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Key", "Value"})
+			for _, meta := range alert.Meta {
+				table.Append([]string{
+					meta.Key,
+					meta.Value,
+				})
+			}
+			table.Render() // Send output
+		}
+
 		if withDetail {
 			fmt.Printf("\n - Events  :\n")
 			for _, event := range alert.Events {
@@ -241,7 +301,8 @@ func NewAlertsCmd() *cobra.Command {
 cscli alerts list --ip 1.2.3.4
 cscli alerts list --range 1.2.3.0/24
 cscli alerts list -s crowdsecurity/ssh-bf
-cscli alerts list --type ban`,
+cscli alerts list --type ban
+cscli alerts list --label severity:high --label confidence:90`,
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 
@@ -320,6 +381,7 @@ cscli alerts list --type ban`,
 	cmdAlertsList.Flags().StringVar(alertListFilter.TypeEquals, "type", "", "restrict to alerts with given decision type (ie. ban, captcha)")
 	cmdAlertsList.Flags().StringVar(alertListFilter.ScopeEquals, "scope", "", "restrict to alerts of this scope (ie. ip,range)")
 	cmdAlertsList.Flags().StringVarP(alertListFilter.ValueEquals, "value", "v", "", "the value to match for in the specified scope")
+	cmdAlertsList.Flags().StringSliceVar(&alertListFilter.Labels, "label", nil, "restrict to alerts with this label (ie. severity:high), can be repeated")
 	cmdAlertsList.Flags().BoolVarP(&printMachine, "machine", "m", false, "print machines that sended alerts")
 	cmdAlertsList.Flags().IntVarP(limit, "limit", "l", 50, "limit size of alerts list table (0 to view all alerts)")
 	cmdAlerts.AddCommand(cmdAlertsList)
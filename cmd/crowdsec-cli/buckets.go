@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	leaky "github.com/crowdsecurity/crowdsec/pkg/leakybucket"
+	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var adminSocket string
+
+//adminRequest sends a request to the local admin api of crowdsec, on its unix socket
+func adminRequest(method string, path string, query url.Values) ([]byte, error) {
+	client := http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", adminSocket)
+			},
+		},
+	}
+	/*the host is ignored, everything goes through the socket*/
+	reqURL := url.URL{Scheme: "http", Host: "crowdsec", Path: path, RawQuery: query.Encode()}
+	req, err := http.NewRequest(method, reqURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach crowdsec on %s (is crowdsec running ?) : %s", adminSocket, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response : %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func listBuckets(scenario string, partition string) ([]leaky.BucketInfo, error) {
+	var ret []leaky.BucketInfo
+
+	query := url.Values{}
+	if scenario != "" {
+		query.Set("scenario", scenario)
+	}
+	if partition != "" {
+		query.Set("partition", partition)
+	}
+	body, err := adminRequest(http.MethodGet, "/v1/buckets", query)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &ret); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal buckets : %s", err)
+	}
+	return ret, nil
+}
+
+func bucketFill(info leaky.BucketInfo) string {
+	if info.Capacity > 0 {
+		return fmt.Sprintf("%.1f/%d", info.Fill, info.Capacity)
+	}
+	return fmt.Sprintf("%.0f", info.Fill)
+}
+
+func bucketExpiration(info leaky.BucketInfo) string {
+	if info.ExpiresAt.IsZero() {
+		return ""
+	}
+	return time.Until(info.ExpiresAt).Round(time.Second).String()
+}
+
+func eventMeta(evt *models.Event) string {
+	meta := []string{}
+	for _, item := range evt.Meta {
+		meta = append(meta, fmt.Sprintf("%s:%s", item.Key, item.Value))
+	}
+	return strings.Join(meta, " ")
+}
+
+func eventTimestamp(evt *models.Event) string {
+	if evt.Timestamp == nil {
+		return ""
+	}
+	return *evt.Timestamp
+}
+
+func BucketsToTable(infos []leaky.BucketInfo) {
+	if csConfig.Cscli.Output == "json" {
+		x, _ := json.MarshalIndent(infos, "", " ")
+		fmt.Printf("%s\n", string(x))
+	} else if csConfig.Cscli.Output == "raw" {
+		fmt.Printf("key,scenario,partition,fill,capacity,events_count,first_ts,last_ts,expires_at\n")
+		for _, info := range infos {
+			fmt.Printf("%s,%s,%s,%f,%d,%d,%s,%s,%s\n", info.Key, info.Scenario, info.Partition, info.Fill, info.Capacity, info.EventsCount,
+				info.FirstTs.Format(time.RFC3339), info.LastTs.Format(time.RFC3339), info.ExpiresAt.Format(time.RFC3339))
+		}
+	} else {
+		if len(infos) == 0 {
+			fmt.Println("No live buckets")
+			return
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Key", "Scenario", "Partition", "Fill", "Events", "First Event", "Last Event", "Expires In"})
+		for _, info := range infos {
+			table.Append([]string{info.Key, info.Scenario, info.Partition, bucketFill(info), fmt.Sprintf("%d", info.EventsCount),
+				info.FirstTs.Format(time.RFC3339), info.LastTs.Format(time.RFC3339), bucketExpiration(info)})
+		}
+		table.Render()
+	}
+}
+
+func BucketToHuman(info leaky.BucketInfo) {
+	if csConfig.Cscli.Output != "human" {
+		x, _ := json.MarshalIndent(info, "", " ")
+		fmt.Printf("%s\n", string(x))
+		return
+	}
+	fmt.Printf(" - Key       : %s\n", info.Key)
+	fmt.Printf(" - Scenario  : %s (%s)\n", info.Scenario, info.Type)
+	fmt.Printf(" - Partition : %s\n", info.Partition)
+	fmt.Printf(" - Fill      : %s\n", bucketFill(info))
+	fmt.Printf(" - Events    : %d\n", info.EventsCount)
+	fmt.Printf(" - First     : %s\n", info.FirstTs.Format(time.RFC3339))
+	fmt.Printf(" - Last      : %s\n", info.LastTs.Format(time.RFC3339))
+	if expiration := bucketExpiration(info); expiration != "" {
+		fmt.Printf(" - Expires in: %s\n", expiration)
+	}
+	if len(info.Events) == 0 {
+		return
+	}
+	fmt.Printf("\n - Events (%d) :\n", len(info.Events))
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Date", "Meta"})
+	table.SetColWidth(120)
+	for _, evt := range info.Events {
+		table.Append([]string{eventTimestamp(evt), eventMeta(evt)})
+	}
+	table.Render()
+}
+
+func NewBucketsCmd() *cobra.Command {
+	var scenario, partition string
+
+	var cmdBuckets = &cobra.Command{
+		Use:   "buckets [action]",
+		Short: "Inspect the live buckets of crowdsec",
+		Long: `List, inspect and delete the live buckets of the local crowdsec agent.
+The agent must have an 'admin_socket' in its crowdsec_service configuration.`,
+		Args: cobra.MinimumNArgs(1),
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if adminSocket != "" {
+				return
+			}
+			if csConfig.Crowdsec == nil || csConfig.Crowdsec.AdminSocket == "" {
+				log.Fatalf("There is no 'admin_socket' in 'crowdsec_service:', and no --socket given")
+			}
+			adminSocket = csConfig.Crowdsec.AdminSocket
+		},
+	}
+	cmdBuckets.PersistentFlags().StringVar(&adminSocket, "socket", "", "path of the admin socket of crowdsec (defaults to crowdsec_service.admin_socket)")
+
+	var cmdBucketsList = &cobra.Command{
+		Use:   "list [options]",
+		Short: "List the live buckets",
+		Example: `cscli buckets list
+cscli buckets list -p 1.2.3.4
+cscli buckets list -s crowdsecurity/ssh-bf`,
+		Args: cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			infos, err := listBuckets(scenario, partition)
+			if err != nil {
+				log.Fatalf("unable to list buckets : %s", err)
+			}
+			BucketsToTable(infos)
+		},
+	}
+	cmdBucketsList.Flags().StringVarP(&scenario, "scenario", "s", "", "only the buckets of this scenario")
+	cmdBucketsList.Flags().StringVarP(&partition, "partition", "p", "", "only the buckets of this partition (ie. the source ip, for most scenarios)")
+	cmdBuckets.AddCommand(cmdBucketsList)
+
+	var cmdBucketsInspect = &cobra.Command{
+		Use:     "inspect [key]",
+		Short:   "Show a live bucket and its events",
+		Example: `cscli buckets inspect 1ca812e0a7ea7699c28529a214ff2bd8be990109`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var info leaky.BucketInfo
+
+			body, err := adminRequest(http.MethodGet, "/v1/buckets/"+args[0], nil)
+			if err != nil {
+				log.Fatalf("unable to inspect bucket : %s", err)
+			}
+			if err := json.Unmarshal(body, &info); err != nil {
+				log.Fatalf("unable to unmarshal bucket : %s", err)
+			}
+			BucketToHuman(info)
+		},
+	}
+	cmdBuckets.AddCommand(cmdBucketsInspect)
+
+	var cmdBucketsDelete = &cobra.Command{
+		Use:   "delete [key...] [options]",
+		Short: "Destroy live buckets without overflow",
+		Example: `cscli buckets delete 1ca812e0a7ea7699c28529a214ff2bd8be990109
+cscli buckets delete -p 1.2.3.4
+cscli buckets delete -p 1.2.3.4 -s crowdsecurity/ssh-bf`,
+		Run: func(cmd *cobra.Command, args []string) {
+			keys := args
+			if len(keys) == 0 {
+				if partition == "" && scenario == "" {
+					cmd.Help()
+					log.Fatalf("give the keys of the buckets, or a partition and/or a scenario")
+				}
+				infos, err := listBuckets(scenario, partition)
+				if err != nil {
+					log.Fatalf("unable to list buckets : %s", err)
+				}
+				for _, info := range infos {
+					keys = append(keys, info.Key)
+				}
+			}
+			deleted := 0
+			for _, key := range keys {
+				if _, err := adminRequest(http.MethodDelete, "/v1/buckets/"+key, nil); err != nil {
+					log.Errorf("unable to delete bucket %s : %s", key, err)
+					continue
+				}
+				deleted++
+			}
+			log.Infof("%d buckets deleted", deleted)
+		},
+	}
+	cmdBucketsDelete.Flags().StringVarP(&scenario, "scenario", "s", "", "delete the buckets of this scenario")
+	cmdBucketsDelete.Flags().StringVarP(&partition, "partition", "p", "", "delete the buckets of this partition (ie. the source ip, for most scenarios)")
+	cmdBuckets.AddCommand(cmdBucketsDelete)
+
+	return cmdBuckets
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
 	"github.com/crowdsecurity/crowdsec/pkg/cwhub"
@@ -221,6 +222,26 @@ func NewConfigCmd() *cobra.Command {
 				fmt.Printf("Crowdsec:\n")
 				fmt.Printf("  - Acquisition File        : %s\n", csConfig.Crowdsec.AcquisitionFilePath)
 				fmt.Printf("  - Parsers routines        : %d\n", csConfig.Crowdsec.ParserRoutinesCount)
+				if csConfig.Crowdsec.Redaction != nil {
+					fmt.Printf("  - Redaction:\n")
+					if len(csConfig.Crowdsec.Redaction.AllowMeta) > 0 {
+						fmt.Printf("      - Allowed meta        : %s\n", strings.Join(csConfig.Crowdsec.Redaction.AllowMeta, ", "))
+					}
+					if len(csConfig.Crowdsec.Redaction.DenyMeta) > 0 {
+						fmt.Printf("      - Denied meta         : %s\n", strings.Join(csConfig.Crowdsec.Redaction.DenyMeta, ", "))
+					}
+					if len(csConfig.Crowdsec.Redaction.Hash) > 0 {
+						fmt.Printf("      - Hashed meta         : %s\n", strings.Join(csConfig.Crowdsec.Redaction.Hash, ", "))
+						fmt.Printf("      - Hash key file       : %s\n", csConfig.Crowdsec.Redaction.HashKeyPath)
+					}
+					for _, mask := range csConfig.Crowdsec.Redaction.Mask {
+						key := mask.Key
+						if key == "" {
+							key = "*"
+						}
+						fmt.Printf("      - Mask                : %s =~ /%s/ -> '%s'\n", key, mask.Regexp, mask.Replacement)
+					}
+				}
 				fmt.Printf("cscli:\n")
 				fmt.Printf("  - Output                  : %s\n", csConfig.Cscli.Output)
 				fmt.Printf("  - Hub Branch              : %s\n", csConfig.Cscli.HubBranch)
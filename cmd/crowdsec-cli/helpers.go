@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewHelpersCmd() *cobra.Command {
+	/* ---- HELPERS COMMAND */
+	var cmdHelpers = &cobra.Command{
+		Use:   "helpers [action]",
+		Short: "List helpers available in expressions",
+		Long: `
+List the helpers that can be used in expressions (filters, groupby, statics, whitelists, profiles)
+`,
+		Args: cobra.MinimumNArgs(1),
+	}
+
+	var cmdHelpersList = &cobra.Command{
+		Use:     "list",
+		Short:   "List expression helpers",
+		Long:    `List expression helpers with their signature`,
+		Example: `cscli helpers list`,
+		Args:    cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, arg []string) {
+			helpers := exprhelpers.GetExprHelpers()
+			if csConfig.Cscli.Output == "human" {
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetCenterSeparator("")
+				table.SetColumnSeparator("")
+
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+				table.SetHeader([]string{"Name", "Signature", "Description"})
+				for _, h := range helpers {
+					table.Append([]string{h.Name, h.Signature, h.Description})
+				}
+				table.Render()
+			} else if csConfig.Cscli.Output == "json" {
+				x, err := json.MarshalIndent(helpers, "", " ")
+				if err != nil {
+					log.Fatalf("failed to marshal helpers : %s", err)
+				}
+				fmt.Printf("%s", string(x))
+			} else if csConfig.Cscli.Output == "raw" {
+				for _, h := range helpers {
+					fmt.Printf("%s,%s\n", h.Name, h.Signature)
+				}
+			}
+		},
+	}
+	cmdHelpers.AddCommand(cmdHelpersList)
+
+	return cmdHelpers
+}
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/crowdsecurity/crowdsec/pkg/cwhub"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/olekukonko/tablewriter"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -84,5 +91,123 @@ Fetches the [.index.json](https://github.com/crowdsecurity/hub/blob/master/.inde
 	}
 	cmdHub.AddCommand(cmdHubUpdate)
 
+	var cmdHubData = &cobra.Command{
+		Use:   "data [action]",
+		Short: "Manage data files of parsers and scenarios",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	cmdHub.AddCommand(cmdHubData)
+
+	var cmdHubDataStatus = &cobra.Command{
+		Use:     "status",
+		Short:   "Show the status of the data files used by installed parsers, postoverflows and scenarios",
+		Example: `cscli hub data status`,
+		Args:    cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cwhub.GetHubIdx(csConfig.Cscli); err != nil {
+				log.Fatalf("Failed to get Hub index : %v", err)
+			}
+			DataStatus()
+		},
+	}
+	cmdHubData.AddCommand(cmdHubDataStatus)
+
 	return cmdHub
 }
+
+type dataFileStatus struct {
+	Item            string    `json:"item"`
+	File            string    `json:"file"`
+	Type            string    `json:"type"`
+	SourceURL       string    `json:"source_url"`
+	RefreshInterval string    `json:"refresh_interval,omitempty"`
+	Size            int64     `json:"size"`
+	ETag            string    `json:"etag,omitempty"`
+	LastCheck       time.Time `json:"last_check"`
+	LastUpdate      time.Time `json:"last_update"`
+	LastError       string    `json:"last_error,omitempty"`
+}
+
+func formatDataTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func DataStatus() {
+	statuses := []dataFileStatus{}
+	for _, itemType := range []string{cwhub.PARSERS, cwhub.PARSERS_OVFLW, cwhub.SCENARIOS} {
+		for _, item := range cwhub.GetItemMap(itemType) {
+			if !item.Installed {
+				continue
+			}
+			sources, err := cwhub.GetItemData(item)
+			if err != nil {
+				log.Errorf("%s : %s", item.Name, err)
+				continue
+			}
+			for _, source := range sources {
+				destPath := filepath.Join(csConfig.Cscli.DataDir, source.DestPath)
+				status := dataFileStatus{
+					Item:            item.Name,
+					File:            destPath,
+					Type:            source.Type,
+					SourceURL:       source.SourceURL,
+					RefreshInterval: source.RefreshInterval,
+					Size:            -1,
+				}
+				if fi, err := os.Stat(destPath); err == nil {
+					status.Size = fi.Size()
+				}
+				dataStatus, err := types.GetDataStatus(destPath)
+				if err != nil {
+					log.Warningf("%s : %s", destPath, err)
+				}
+				status.ETag = dataStatus.ETag
+				status.LastCheck = dataStatus.LastCheck
+				status.LastUpdate = dataStatus.LastUpdate
+				status.LastError = dataStatus.LastError
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Item != statuses[j].Item {
+			return statuses[i].Item < statuses[j].Item
+		}
+		return statuses[i].File < statuses[j].File
+	})
+
+	if csConfig.Cscli.Output == "human" {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetCenterSeparator("")
+		table.SetColumnSeparator("")
+
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetHeader([]string{"Item", "File", "Type", "Refresh", "Size", "Last update", "Last check", "Error"})
+		for _, s := range statuses {
+			size := "missing"
+			if s.Size >= 0 {
+				size = fmt.Sprintf("%d", s.Size)
+			}
+			refresh := s.RefreshInterval
+			if refresh == "" {
+				refresh = "-"
+			}
+			table.Append([]string{s.Item, s.File, s.Type, refresh, size, formatDataTime(s.LastUpdate), formatDataTime(s.LastCheck), s.LastError})
+		}
+		table.Render()
+	} else if csConfig.Cscli.Output == "json" {
+		x, err := json.MarshalIndent(statuses, "", " ")
+		if err != nil {
+			log.Fatalf("failed to marshal data status : %s", err)
+		}
+		fmt.Printf("%s", string(x))
+	} else if csConfig.Cscli.Output == "raw" {
+		for _, s := range statuses {
+			fmt.Printf("%s,%s,%s,%d,%s,%s\n", s.Item, s.File, s.Type, s.Size, formatDataTime(s.LastUpdate), s.LastError)
+		}
+	}
+}
@@ -179,6 +179,26 @@ func ShowPrometheus(url string) {
 					buckets_stats[name] = make(map[string]int)
 				}
 				buckets_stats[name]["underflow"] += ival
+			case "cs_bucket_canceled_total":
+				if _, ok := buckets_stats[name]; !ok {
+					buckets_stats[name] = make(map[string]int)
+				}
+				buckets_stats[name]["canceled"] += ival
+			case "cs_bucket_evicted_total":
+				if _, ok := buckets_stats[name]; !ok {
+					buckets_stats[name] = make(map[string]int)
+				}
+				buckets_stats[name]["evicted"] += ival
+			case "cs_bucket_late_events_total":
+				if _, ok := buckets_stats[name]; !ok {
+					buckets_stats[name] = make(map[string]int)
+				}
+				buckets_stats[name]["late"] += ival
+			case "cs_alerts_throttled_total":
+				if _, ok := buckets_stats[name]; !ok {
+					buckets_stats[name] = make(map[string]int)
+				}
+				buckets_stats[name]["throttled"] += ival
 				/*acquis*/
 			case "cs_reader_hits_total":
 				if _, ok := acquis_stats[source]; !ok {
@@ -260,8 +280,8 @@ func ShowPrometheus(url string) {
 			log.Warningf("while collecting acquis stats : %s", err)
 		}
 		bucketsTable := tablewriter.NewWriter(os.Stdout)
-		bucketsTable.SetHeader([]string{"Bucket", "Current Count", "Overflows", "Instanciated", "Poured", "Expired"})
-		keys = []string{"curr_count", "overflow", "instanciation", "pour", "underflow"}
+		bucketsTable.SetHeader([]string{"Bucket", "Current Count", "Overflows", "Instanciated", "Poured", "Expired", "Canceled", "Evicted", "Late", "Throttled"})
+		keys = []string{"curr_count", "overflow", "instanciation", "pour", "underflow", "canceled", "evicted", "late", "throttled"}
 		if err := metricsToTable(bucketsTable, buckets_stats, keys); err != nil {
 			log.Warningf("while collecting acquis stats : %s", err)
 		}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/crowdsecurity/crowdsec/pkg/cwhub"
 	"github.com/crowdsecurity/crowdsec/pkg/cwversion"
+	leaky "github.com/crowdsecurity/crowdsec/pkg/leakybucket"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 	"github.com/enescakir/emoji"
 	"github.com/olekukonko/tablewriter"
@@ -258,11 +259,33 @@ func InspectItem(name string, objecitemType string) {
 	}
 	fmt.Printf("%s", string(buff))
 
+	if hubItem.Type == cwhub.SCENARIOS {
+		ShowScenarioOverride(hubItem.Name)
+	}
+
 	fmt.Printf("\nCurrent metrics : \n\n")
 	ShowMetrics(hubItem)
 
 }
 
+//ShowScenarioOverride shows the local overrides of the scenario, if any
+func ShowScenarioOverride(name string) {
+	overrides, err := leaky.LoadScenariosOverrides(csConfig.Cscli.OverridesFilePath)
+	if err != nil {
+		log.Errorf("unable to load scenarios overrides : %s", err)
+		return
+	}
+	override, ok := overrides[name]
+	if !ok {
+		return
+	}
+	buff, err := yaml.Marshal(override)
+	if err != nil {
+		log.Fatalf("unable to marshal overrides : %s", err)
+	}
+	fmt.Printf("\nLocal overrides (%s) : \n\n%s", csConfig.Cscli.OverridesFilePath, string(buff))
+}
+
 func ShowMetrics(hubItem *cwhub.Item) {
 	switch hubItem.Type {
 	case cwhub.PARSERS:
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	leaky "github.com/crowdsecurity/crowdsec/pkg/leakybucket"
+	log "github.com/sirupsen/logrus"
+)
+
+//number of events shown for each bucket when listing them
+const adminListEvents = 3
+
+/*
+bucketsHandler lists the live buckets (GET /v1/buckets?scenario=crowdsecurity/ssh-bf&partition=1.2.3.4&events=3),
+shows one bucket with all its events (GET /v1/buckets/<key>) and destroys one bucket without overflow (DELETE /v1/buckets/<key>)
+*/
+func bucketsHandler(w http.ResponseWriter, r *http.Request) {
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/buckets"), "/")
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		maxEvents := adminListEvents
+		if value := r.URL.Query().Get("events"); value != "" {
+			var err error
+			if maxEvents, err = strconv.Atoi(value); err != nil {
+				http.Error(w, fmt.Sprintf("invalid events '%s'", value), http.StatusBadRequest)
+				return
+			}
+		}
+		writeAdminJSON(w, leaky.ListBuckets(buckets, r.URL.Query().Get("scenario"), r.URL.Query().Get("partition"), maxEvents))
+	case r.Method == http.MethodGet:
+		info, err := leaky.InspectBucket(buckets, key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeAdminJSON(w, info)
+	case r.Method == http.MethodDelete && key != "":
+		if err := leaky.DeleteBucket(buckets, key); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Infof("bucket %s deleted through admin socket", key)
+		fmt.Fprintf(w, "bucket %s deleted\n", key)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeAdminJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Errorf("admin: unable to marshal response : %s", err)
+	}
+}
+
+//serveAdminSocket serves the local admin api on the unix socket at path, until crowdsec is stopped or reloaded
+func serveAdminSocket(path string) error {
+	/*a socket left by a previous run would prevent to listen*/
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove %s : %s", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s : %s", path, err)
+	}
+	/*it gives access to the events, which might hold personal data*/
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("unable to set permissions of %s : %s", path, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/buckets", bucketsHandler)
+	mux.HandleFunc("/v1/buckets/", bucketsHandler)
+	mux.HandleFunc("/v1/quarantine", quarantineHandler)
+	server := &http.Server{Handler: mux}
+
+	crowdsecTomb.Go(func() error {
+		<-crowdsecTomb.Dying()
+		log.Debugf("closing admin socket %s", path)
+		return server.Close()
+	})
+	log.Infof("Local admin api listening on %s", path)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("admin socket : %s", err)
+	}
+	return nil
+}
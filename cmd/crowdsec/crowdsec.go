@@ -18,6 +18,8 @@ func initCrowdsec() (*parser.Parsers, error) {
 	if err != nil {
 		return &parser.Parsers{}, fmt.Errorf("Failed to init expr helpers : %s", err)
 	}
+	exprhelpers.SetExprLimits(cConfig.Crowdsec.ExprSlowAfter, cConfig.Crowdsec.ExprMaxErrors)
+	exprhelpers.ResetExprGuards()
 
 	// Populate cwhub package tools
 	if err := cwhub.GetHubIdx(cConfig.Cscli); err != nil {
@@ -42,13 +44,25 @@ func initCrowdsec() (*parser.Parsers, error) {
 
 func runCrowdsec(parsers *parser.Parsers) error {
 	inputLineChan := make(chan types.Event)
-	inputEventChan := make(chan types.Event)
+	/*lines and events are sharded (by source by default) across routines to keep them ordered*/
+	parserShards := makeShards(cConfig.Crowdsec.ParserRoutinesCount)
+	pourShards := makeShards(cConfig.Crowdsec.BucketsRoutinesCount)
+
+	if err := compileShardKey(cConfig.Crowdsec.ParserShardKey); err != nil {
+		return err
+	}
 
 	//start go-routines for parsing, buckets pour and ouputs.
+	parsersTomb.Go(func() error {
+		defer types.CatchPanic("crowdsec/runShardDispatch")
+		return runShardDispatch(inputLineChan, parserShards)
+	})
 	for i := 0; i < cConfig.Crowdsec.ParserRoutinesCount; i++ {
+		parserShard := parserShards[i]
+		depth := shardDepth(i)
 		parsersTomb.Go(func() error {
 			defer types.CatchPanic("crowdsec/runParse")
-			err := runParse(inputLineChan, inputEventChan, *parsers.Ctx, parsers.Nodes)
+			err := runParse(parserShard, depth, pourShards, *parsers.Ctx, parsers.Nodes)
 			if err != nil {
 				log.Fatalf("starting parse error : %s", err)
 				return err
@@ -58,9 +72,10 @@ func runCrowdsec(parsers *parser.Parsers) error {
 	}
 
 	for i := 0; i < cConfig.Crowdsec.BucketsRoutinesCount; i++ {
+		pourShard := pourShards[i]
 		bucketsTomb.Go(func() error {
 			defer types.CatchPanic("crowdsec/runPour")
-			err := runPour(inputEventChan, holders, buckets)
+			err := runPour(pourShard, holders, buckets)
 			if err != nil {
 				log.Fatalf("starting pour error : %s", err)
 				return err
@@ -68,11 +83,13 @@ func runCrowdsec(parsers *parser.Parsers) error {
 			return nil
 		})
 	}
+	/*the caps on the rate of alerts are shared by the output routines*/
+	throttle := leaky.NewAlertsThrottle(cConfig.Crowdsec.AlertsCap)
 	for i := 0; i < cConfig.Crowdsec.OutputRoutinesCount; i++ {
 
 		outputsTomb.Go(func() error {
 			defer types.CatchPanic("crowdsec/runOutput")
-			err := runOutput(inputEventChan, outputEventChan, buckets, *parsers.Povfwctx, parsers.Povfwnodes, *cConfig.API.Client.Credentials)
+			err := runOutput(pourShards, outputEventChan, throttle, *parsers.Povfwctx, parsers.Povfwnodes, *cConfig.API.Client.Credentials)
 			if err != nil {
 				log.Fatalf("starting outputs error : %s", err)
 				return err
@@ -80,6 +97,20 @@ func runCrowdsec(parsers *parser.Parsers) error {
 			return nil
 		})
 	}
+	/*live buckets are saved at regular interval to survive a crash or an upgrade*/
+	if snapshotsEnabled() {
+		bucketsTomb.Go(func() error {
+			defer types.CatchPanic("crowdsec/runBucketsSnapshot")
+			return runBucketsSnapshot(cConfig.Crowdsec.BucketsSnapshot)
+		})
+	}
+	/*data files with a refresh_interval are reloaded live, not needed when replaying logs*/
+	if refreshes := collectDataRefresh(parsers, holders); len(refreshes) > 0 && !cConfig.Crowdsec.BucketsGCEnabled {
+		parsersTomb.Go(func() error {
+			defer types.CatchPanic("crowdsec/runDataRefresh")
+			return runDataRefresh(refreshes, cConfig.Crowdsec.DataDir)
+		})
+	}
 	log.Warningf("Starting processing data")
 
 	if err := acquisition.StartAcquisition(dataSources, inputLineChan, &acquisTomb); err != nil {
@@ -110,6 +141,16 @@ func serveCrowdsec(parsers *parser.Parsers) {
 		log.Debugf("everything is dead, return crowdsecTomb")
 		return nil
 	})
+	if cConfig.Crowdsec.AdminSocket != "" {
+		crowdsecTomb.Go(func() error {
+			defer types.CatchPanic("crowdsec/serveAdminSocket")
+			/*the agent can run without its admin socket*/
+			if err := serveAdminSocket(cConfig.Crowdsec.AdminSocket); err != nil {
+				log.Errorf("%s", err)
+			}
+			return nil
+		})
+	}
 }
 
 func waitOnTomb() {
@@ -124,12 +165,12 @@ func waitOnTomb() {
 			successiveStillRounds := 0
 			/*
 				While it might make sense to want to shut-down parser/buckets/etc. as soon as acquisition is finished,
-				we might have some pending buckets : buckets that overflowed, but which LeakRoutine are still alive because they
+				we might have some pending buckets : buckets that overflowed, but which are still accounted as live because they
 				are waiting to be able to "commit" (push to api). This can happens specifically in a context where a lot of logs
 				are going to trigger overflow (ie. trigger buckets with ~100% of the logs triggering an overflow).
 
 				To avoid this (which would mean that we would "lose" some overflows), let's monitor the number of live buckets.
-				However, because of the blackhole mechanism, you can't really wait for the number of LeakRoutine to go to zero (we might have to wait $blackhole_duration).
+				However, because of the blackhole mechanism, you can't really wait for the number of live buckets to go to zero (we might have to wait $blackhole_duration).
 
 				So : we are waiting for the number of buckets to stop decreasing before returning. "how long" we should wait is a bit of the trick question,
 				as some operations (ie. reverse dns or such in post-overflow) can take some time :)
@@ -143,13 +184,13 @@ func waitOnTomb() {
 				}
 				if currBucketCount != bucketCount {
 					if rounds == 0 || rounds%2 == 0 {
-						log.Printf("Still %d live LeakRoutines, waiting (was %d)", currBucketCount, bucketCount)
+						log.Printf("Still %d live buckets, waiting (was %d)", currBucketCount, bucketCount)
 					}
 					bucketCount = currBucketCount
 					successiveStillRounds = 0
 				} else {
 					if successiveStillRounds > 1 {
-						log.Printf("LeakRoutines commit over.")
+						log.Printf("Buckets commit over.")
 						break
 					}
 					successiveStillRounds++
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	leaky "github.com/crowdsecurity/crowdsec/pkg/leakybucket"
+	"github.com/crowdsecurity/crowdsec/pkg/parser"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+//how often we check if a data file is due for refresh
+const dataRefreshTick = 10 * time.Second
+
+type dataRefresh struct {
+	source   *types.DataSource
+	interval time.Duration
+	next     time.Time
+}
+
+//collectDataRefresh returns the data files of parsers and scenarios that have a refresh_interval, using the shortest one when a file is shared
+func collectDataRefresh(parsers *parser.Parsers, holders []leaky.BucketFactory) []*dataRefresh {
+	byDest := make(map[string]*dataRefresh)
+	ret := []*dataRefresh{}
+
+	add := func(sources []*types.DataSource) {
+		for _, source := range sources {
+			interval, err := source.GetRefreshInterval()
+			if err != nil {
+				log.Errorf("%s", err)
+				continue
+			}
+			if interval == 0 || source.DestPath == "" || source.SourceURL == "" {
+				continue
+			}
+			if existing, ok := byDest[source.DestPath]; ok {
+				if interval < existing.interval {
+					existing.interval = interval
+				}
+				continue
+			}
+			refresh := &dataRefresh{source: source, interval: interval}
+			byDest[source.DestPath] = refresh
+			ret = append(ret, refresh)
+		}
+	}
+	for _, node := range parsers.Nodes {
+		add(node.Data)
+	}
+	for _, node := range parsers.Povfwnodes {
+		add(node.Data)
+	}
+	for _, holder := range holders {
+		add(holder.Data)
+	}
+	for _, refresh := range ret {
+		refresh.next = time.Now().Add(refresh.interval)
+	}
+	return ret
+}
+
+//runDataRefresh downloads the data files when their source changed and reloads them in the expression helpers
+func runDataRefresh(refreshes []*dataRefresh, dataDir string) error {
+	ticker := time.NewTicker(dataRefreshTick)
+	defer ticker.Stop()
+	/*a download in progress is aborted when the parsers are stopped*/
+	ctx := parsersTomb.Context(nil)
+	for {
+		select {
+		case <-parsersTomb.Dying():
+			log.Infof("Killing data refresh routine")
+			return nil
+		case now := <-ticker.C:
+			for _, refresh := range refreshes {
+				if now.Before(refresh.next) {
+					continue
+				}
+				refresh.next = now.Add(refresh.interval)
+				updated, err := types.RefreshData(ctx, refresh.source, dataDir)
+				if err != nil {
+					log.Errorf("unable to refresh data '%s' : %s", refresh.source.DestPath, err)
+					continue
+				}
+				if !updated {
+					continue
+				}
+				if err := exprhelpers.FileInit(dataDir, refresh.source.DestPath, refresh.source.Type); err != nil {
+					log.Errorf("unable to reload data '%s' : %s", refresh.source.DestPath, err)
+				}
+			}
+		}
+	}
+}
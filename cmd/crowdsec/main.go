@@ -132,7 +132,7 @@ func LoadBuckets(cConfig *csconfig.GlobalConfig) error {
 	if err != nil {
 		return fmt.Errorf("Scenario loading failed : %v", err)
 	}
-	buckets = leaky.NewBuckets()
+	buckets = leaky.NewBucketsWithShards(cConfig.Crowdsec.BucketsShards)
 
 	/*restore as well previous state if present*/
 	if cConfig.Crowdsec.BucketStateFile != "" {
@@ -8,6 +8,7 @@ import (
 	v1 "github.com/crowdsecurity/crowdsec/pkg/apiserver/controllers/v1"
 	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
 	"github.com/crowdsecurity/crowdsec/pkg/cwversion"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
 	leaky "github.com/crowdsecurity/crowdsec/pkg/leakybucket"
 	"github.com/crowdsecurity/crowdsec/pkg/parser"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
@@ -42,6 +43,14 @@ var globalParserHitsKo = prometheus.NewCounterVec(
 	[]string{"source"},
 )
 
+var globalParserShardDepth = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cs_parser_shard_queue_depth",
+		Help: "Number of lines waiting in the queue of a parser routine.",
+	},
+	[]string{"shard"},
+)
+
 var globalBucketPourKo = prometheus.NewCounter(
 	prometheus.CounterOpts{
 		Name: "cs_bucket_pour_ko_total",
@@ -70,7 +79,7 @@ func dumpMetrics() {
 
 	if cConfig.Crowdsec.BucketStateDumpDir != "" {
 		log.Infof("!! Dumping buckets state")
-		if tmpFile, err = leaky.DumpBucketsStateAt(time.Now(), cConfig.Crowdsec.BucketStateDumpDir, buckets); err != nil {
+		if tmpFile, err = leaky.DumpBucketsStateAt(time.Now(), cConfig.Crowdsec.BucketStateDumpDir, buckets, holders); err != nil {
 			log.Fatalf("Failed dumping bucket state : %s", err)
 		}
 		log.Infof("Buckets state dumped to %s", tmpFile)
@@ -95,18 +104,21 @@ func registerPrometheus(config *csconfig.PrometheusCfg) {
 	/*If in aggregated mode, do not register events associated to a source, keeps cardinality low*/
 	if config.Level == "aggregated" {
 		log.Infof("Loading aggregated prometheus collectors")
-		prometheus.MustRegister(globalParserHits, globalParserHitsOk, globalParserHitsKo,
+		prometheus.MustRegister(globalParserHits, globalParserHitsOk, globalParserHitsKo, globalParserShardDepth,
 			acquisition.ReaderHits, globalCsInfo,
-			leaky.BucketsUnderflow, leaky.BucketsInstanciation, leaky.BucketsOverflow,
+			leaky.BucketsUnderflow, leaky.BucketsCanceled, leaky.BucketsEvicted, leaky.AnomalyBaselines, leaky.BucketsInstanciation, leaky.BucketsOverflow,
 			v1.LapiRouteHits,
-			leaky.BucketsCurrentCount)
+			leaky.BucketsCurrentCount, leaky.BucketsLateEvents, leaky.BucketsOutputDropped, leaky.AlertsThrottled, exprhelpers.ExprErrors, exprhelpers.ExprQuarantined,
+			leaky.ScenarioMetrics, leaky.ScenarioMetricsDropped)
 	} else {
 		log.Infof("Loading prometheus collectors")
-		prometheus.MustRegister(globalParserHits, globalParserHitsOk, globalParserHitsKo,
+		prometheus.MustRegister(globalParserHits, globalParserHitsOk, globalParserHitsKo, globalParserShardDepth,
 			parser.NodesHits, parser.NodesHitsOk, parser.NodesHitsKo,
 			acquisition.ReaderHits, globalCsInfo,
 			v1.LapiRouteHits, v1.LapiMachineHits, v1.LapiBouncerHits, v1.LapiNilDecisions, v1.LapiNonNilDecisions,
-			leaky.BucketsPour, leaky.BucketsUnderflow, leaky.BucketsInstanciation, leaky.BucketsOverflow, leaky.BucketsCurrentCount)
+			leaky.BucketsPour, leaky.BucketsUnderflow, leaky.BucketsCanceled, leaky.BucketsEvicted, leaky.AnomalyBaselines, leaky.BucketsInstanciation, leaky.BucketsOverflow, leaky.BucketsCurrentCount,
+			leaky.BucketsLateEvents, leaky.BucketsOutputDropped, leaky.AlertsThrottled, exprhelpers.ExprErrors, exprhelpers.ExprQuarantined,
+			leaky.ScenarioMetrics, leaky.ScenarioMetricsDropped)
 
 	}
 	http.Handle("/metrics", promhttp.Handler())
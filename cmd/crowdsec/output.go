@@ -58,7 +58,7 @@ func PushAlerts(alerts []types.RuntimeAlert, client *apiclient.ApiClient) error
 	return nil
 }
 
-func runOutput(input chan types.Event, overflow chan types.Event, buckets *leaky.Buckets,
+func runOutput(input []chan types.Event, overflow chan types.Event, throttle *leaky.AlertsThrottle,
 	postOverflowCTX parser.UnixParserCtx, postOverflowNodes []parser.Node, apiConfig csconfig.ApiCredentialsCfg) error {
 
 	var err error
@@ -102,6 +102,12 @@ LOOP:
 	for {
 		select {
 		case <-ticker.C:
+			/*the alerts dropped by the caps are summarized once their interval is over*/
+			if summaries := throttle.Summaries(time.Now(), false); len(summaries) > 0 {
+				cacheMutex.Lock()
+				cache = append(cache, summaries...)
+				cacheMutex.Unlock()
+			}
 			if len(cache) > 0 {
 				cacheMutex.Lock()
 				cachecopy := cache
@@ -113,6 +119,11 @@ LOOP:
 				}
 			}
 		case <-outputsTomb.Dying():
+			if summaries := throttle.Summaries(time.Now(), true); len(summaries) > 0 {
+				cacheMutex.Lock()
+				cache = append(cache, summaries...)
+				cacheMutex.Unlock()
+			}
 			if len(cache) > 0 {
 				cacheMutex.Lock()
 				cachecopy := cache
@@ -126,14 +137,13 @@ LOOP:
 			break LOOP
 		case event := <-overflow:
 
-			/*if alert is empty and mapKey is present, the overflow is just to cleanup bucket*/
+			/*if alert is empty and mapKey is present, the overflow is just to cleanup bucket : its shard already forgot it*/
 			if event.Overflow.Alert == nil && event.Overflow.Mapkey != "" {
-				buckets.Bucket_map.Delete(event.Overflow.Mapkey)
 				break
 			}
 			if event.Overflow.Reprocess {
 				log.Debugf("Overflow being reprocessed.")
-				input <- event
+				input[shardIndex(&event, len(input))] <- event
 			}
 			/* process post overflow parser nodes */
 			event, err := parser.Parse(postOverflowCTX, event, postOverflowNodes)
@@ -145,6 +155,9 @@ LOOP:
 				log.Printf("[%s] is whitelisted, skip.", *event.Overflow.Alert.Message)
 				continue
 			}
+			if !throttle.Allow(event.Overflow, time.Now()) {
+				continue
+			}
 			cacheMutex.Lock()
 			cache = append(cache, event.Overflow)
 			cacheMutex.Unlock()
@@ -2,15 +2,97 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
 
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
 	"github.com/crowdsecurity/crowdsec/pkg/parser"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 )
 
-func runParse(input chan types.Event, output chan types.Event, parserCTX parser.UnixParserCtx, nodes []parser.Node) error {
+//size of the per-routine queues, lines of a given shard are processed in order
+const shardQueueSize = 100
+
+/*the (optional) compiled parser_shard_key, when nil the Line.Src is used*/
+var shardKeyFilter *vm.Program
+
+func compileShardKey(key string) error {
+	var err error
+
+	shardKeyFilter = nil
+	if key == "" {
+		return nil
+	}
+	shardKeyFilter, err = expr.Compile(key, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
+	if err != nil {
+		return fmt.Errorf("invalid parser_shard_key '%s' : %s", key, err)
+	}
+	return nil
+}
+
+//shardIndex returns the index of the routine that must process the event, so that events sharing the same key are kept in order
+func shardIndex(evt *types.Event, count int) int {
+	if count <= 1 {
+		return 0
+	}
+	key := evt.Line.Src
+	if shardKeyFilter != nil {
+		env := exprhelpers.AcquireEvtEnv(evt)
+		output, err := expr.Run(shardKeyFilter, env)
+		exprhelpers.ReleaseEvtEnv(env)
+		if err != nil {
+			log.Warningf("failed to run parser_shard_key : %s", err)
+		} else if out, ok := output.(string); ok {
+			key = out
+		} else {
+			log.Warningf("parser_shard_key returned non-string : %T", output)
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(count))
+}
+
+func makeShards(count int) []chan types.Event {
+	shards := make([]chan types.Event, count)
+	for i := range shards {
+		shards[i] = make(chan types.Event, shardQueueSize)
+	}
+	return shards
+}
+
+//runShardDispatch reads the acquired lines and dispatches them to the parser routines
+func runShardDispatch(input chan types.Event, shards []chan types.Event) error {
+	for {
+		select {
+		case <-parsersTomb.Dying():
+			log.Infof("Killing parser dispatch routine")
+			return nil
+		case event := <-input:
+			idx := shardIndex(&event, len(shards))
+			select {
+			case shards[idx] <- event:
+			case <-parsersTomb.Dying():
+				log.Infof("Killing parser dispatch routine")
+				return nil
+			}
+			shardDepth(idx).Set(float64(len(shards[idx])))
+		}
+	}
+}
+
+//shardDepth is the gauge of the queue depth of the parser routine idx, updated when lines are queued and dequeued
+func shardDepth(idx int) prometheus.Gauge {
+	return globalParserShardDepth.With(prometheus.Labels{"shard": strconv.Itoa(idx)})
+}
+
+func runParse(input chan types.Event, depth prometheus.Gauge, output []chan types.Event, parserCTX parser.UnixParserCtx, nodes []parser.Node) error {
 
 LOOP:
 	for {
@@ -19,6 +101,7 @@ LOOP:
 			log.Infof("Killing parser routines")
 			break LOOP
 		case event := <-input:
+			depth.Set(float64(len(input)))
 			if !event.Process {
 				continue
 			}
@@ -40,7 +123,12 @@ LOOP:
 				log.Debugf("event whitelisted, discard")
 				continue
 			}
-			output <- parsed
+			select {
+			case output[shardIndex(&parsed, len(output))] <- parsed:
+			case <-parsersTomb.Dying():
+				log.Infof("Killing parser routines")
+				break LOOP
+			}
 		}
 	}
 	return nil
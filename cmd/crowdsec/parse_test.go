@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"gopkg.in/tomb.v2"
+)
+
+func TestShardIndex(t *testing.T) {
+	if err := compileShardKey(""); err != nil {
+		t.Fatalf("while compiling shard key : %s", err)
+	}
+	evt := types.Event{Line: types.Line{Src: "/var/log/auth.log"}, Meta: map[string]string{"source_ip": "1.2.3.4"}}
+	if idx := shardIndex(&evt, 1); idx != 0 {
+		t.Fatalf("expected shard 0 with a single routine, got %d", idx)
+	}
+	idx := shardIndex(&evt, 8)
+	if idx < 0 || idx >= 8 {
+		t.Fatalf("shard %d out of range", idx)
+	}
+	/*the same source always goes to the same routine*/
+	for i := 0; i < 10; i++ {
+		if other := shardIndex(&evt, 8); other != idx {
+			t.Fatalf("expected shard %d, got %d", idx, other)
+		}
+	}
+	/*the sources spread over the routines*/
+	used := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		used[shardIndex(&types.Event{Line: types.Line{Src: fmt.Sprintf("/var/log/%d.log", i)}}, 8)] = true
+	}
+	if len(used) < 4 {
+		t.Fatalf("expected the sources to spread over the routines, got %d routines", len(used))
+	}
+
+	/*with a shard key, the key replaces the source*/
+	if err := compileShardKey("evt.Meta.source_ip"); err != nil {
+		t.Fatalf("while compiling shard key : %s", err)
+	}
+	defer compileShardKey("")
+	other := types.Event{Line: types.Line{Src: "/var/log/nginx/access.log"}, Meta: map[string]string{"source_ip": "1.2.3.4"}}
+	if shardIndex(&evt, 8) != shardIndex(&other, 8) {
+		t.Fatalf("expected the events of the same key in the same routine")
+	}
+	if err := compileShardKey("evt.Meta.source_ip =="); err == nil {
+		t.Fatalf("expected invalid shard key to fail")
+	}
+}
+
+func TestShardDispatchOrder(t *testing.T) {
+	if err := compileShardKey(""); err != nil {
+		t.Fatalf("while compiling shard key : %s", err)
+	}
+	parsersTomb = tomb.Tomb{}
+	input := make(chan types.Event)
+	shards := makeShards(4)
+	parsersTomb.Go(func() error {
+		return runShardDispatch(input, shards)
+	})
+
+	sources := []string{"/var/log/auth.log", "/var/log/syslog", "/var/log/nginx/access.log"}
+	count := 30
+	for i := 0; i < count; i++ {
+		for _, src := range sources {
+			input <- types.Event{Line: types.Line{Src: src, Raw: fmt.Sprintf("%d", i)}}
+		}
+	}
+
+	queued := func() int {
+		total := 0
+		for _, shard := range shards {
+			total += len(shard)
+		}
+		return total
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for queued() < count*len(sources) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	/*all the lines of a source are in the queue of its routine, in order*/
+	seen := make(map[string]int)
+	for idx, shard := range shards {
+		for len(shard) > 0 {
+			evt := <-shard
+			if expected := shardIndex(&evt, len(shards)); expected != idx {
+				t.Fatalf("line of %s in routine %d instead of %d", evt.Line.Src, idx, expected)
+			}
+			if evt.Line.Raw != fmt.Sprintf("%d", seen[evt.Line.Src]) {
+				t.Fatalf("line %s of %s out of order, expected %d", evt.Line.Raw, evt.Line.Src, seen[evt.Line.Src])
+			}
+			seen[evt.Line.Src]++
+		}
+	}
+	for _, src := range sources {
+		if seen[src] != count {
+			t.Fatalf("expected %d lines of %s, got %d", count, src, seen[src])
+		}
+	}
+
+	/*the dispatch stops even if it's waiting for a full queue*/
+	for i := 0; i < shardQueueSize+1; i++ {
+		input <- types.Event{Line: types.Line{Src: sources[0]}}
+	}
+	parsersTomb.Kill(nil)
+	if err := parsersTomb.Wait(); err != nil {
+		t.Fatalf("dispatch returned error : %s", err)
+	}
+}
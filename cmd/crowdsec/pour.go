@@ -22,7 +22,7 @@ func runPour(input chan types.Event, holders []leaky.BucketFactory, buckets *lea
 		case parsed := <-input:
 			count++
 			if count%5000 == 0 {
-				log.Warningf("%d existing LeakyRoutine", leaky.LeakyRoutineCount)
+				log.Warningf("%d live buckets", leaky.LeakyRoutineCount)
 				//when in forensics mode, garbage collect buckets
 				if cConfig.Crowdsec.BucketsGCEnabled {
 					if parsed.MarshaledTime != "" {
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+quarantineHandler lists the parser nodes and scenarios tracked for expression errors (GET),
+and releases one of them from quarantine (DELETE /v1/quarantine?kind=scenario&name=crowdsecurity/ssh-bf). It is served on the admin socket
+*/
+func quarantineHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(exprhelpers.GetQuarantine()); err != nil {
+			log.Errorf("quarantine: unable to marshal status : %s", err)
+		}
+	case http.MethodDelete:
+		kind := r.URL.Query().Get("kind")
+		name := r.URL.Query().Get("name")
+		if kind == "" || name == "" {
+			http.Error(w, "kind and name are required", http.StatusBadRequest)
+			return
+		}
+		if err := exprhelpers.Unquarantine(kind, name); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Infof("%s '%s' released from quarantine through admin socket", kind, name)
+		fmt.Fprintf(w, "%s '%s' released\n", kind, name)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
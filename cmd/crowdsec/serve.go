@@ -26,7 +26,7 @@ func debugHandler(sig os.Signal) error {
 		log.Warningf("Failed to shut down routines: %s", err)
 	}
 	//todo : properly stop acquis with the tail readers
-	if tmpFile, err = leaky.DumpBucketsStateAt(time.Now(), cConfig.Crowdsec.BucketStateDumpDir, buckets); err != nil {
+	if tmpFile, err = leaky.DumpBucketsStateAt(time.Now(), cConfig.Crowdsec.BucketStateDumpDir, buckets, holders); err != nil {
 		log.Warningf("Failed dumping bucket state : %s", err)
 	}
 	if err := leaky.ShutdownAllBuckets(buckets); err != nil {
@@ -46,7 +46,7 @@ func reloadHandler(sig os.Signal) error {
 			log.Fatalf("Failed to shut down crowdsec routines: %s", err)
 		}
 		if cConfig.Crowdsec != nil && cConfig.Crowdsec.BucketStateDumpDir != "" {
-			if tmpFile, err = leaky.DumpBucketsStateAt(time.Now(), cConfig.Crowdsec.BucketStateDumpDir, buckets); err != nil {
+			if tmpFile, err = leaky.DumpBucketsStateAt(time.Now(), cConfig.Crowdsec.BucketStateDumpDir, buckets, holders); err != nil {
 				log.Fatalf("Failed dumping bucket state : %s", err)
 			}
 		}
@@ -100,6 +100,9 @@ func reloadHandler(sig os.Signal) error {
 			if err := leaky.LoadBucketsState(tmpFile, buckets, holders); err != nil {
 				log.Fatalf("unable to restore buckets : %s", err)
 			}
+		} else if err := restoreBucketsSnapshot(); err != nil {
+			/*the snapshot was written when the buckets routines were stopped*/
+			log.Fatalf("unable to restore buckets snapshot : %s", err)
 		}
 		//reload the simulation state
 		if err := cConfig.LoadSimulation(); err != nil {
@@ -244,6 +247,9 @@ func Serve() error {
 		}
 		/* if it's just linting, we're done */
 		if !flags.TestMode {
+			if err := restoreBucketsSnapshot(); err != nil {
+				return errors.Wrap(err, "restoring buckets snapshot")
+			}
 			serveCrowdsec(csParsers)
 		}
 	}
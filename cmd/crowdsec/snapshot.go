@@ -0,0 +1,43 @@
+package main
+
+import (
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	leaky "github.com/crowdsecurity/crowdsec/pkg/leakybucket"
+	log "github.com/sirupsen/logrus"
+)
+
+//snapshots are only relevant in live mode : when replaying logs, the buckets follow the time of the logs
+func snapshotsEnabled() bool {
+	return cConfig.Crowdsec.BucketsSnapshot != nil && flags.SingleFilePath == "" && flags.SingleJournalctlFilter == ""
+}
+
+//restoreBucketsSnapshot restores the buckets of the last snapshot when crowdsec starts, unless a state file is explicitly given
+func restoreBucketsSnapshot() error {
+	if !snapshotsEnabled() || cConfig.Crowdsec.BucketStateFile != "" {
+		return nil
+	}
+	snapshotCfg := cConfig.Crowdsec.BucketsSnapshot
+	return leaky.RestoreBucketsSnapshot(snapshotCfg.Path, snapshotCfg.MaxAge, buckets, holders)
+}
+
+//runBucketsSnapshot writes the state of the live buckets at regular interval, and a last time when the buckets routines are stopped
+func runBucketsSnapshot(snapshotCfg *csconfig.SnapshotCfg) error {
+	ticker := time.NewTicker(snapshotCfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-bucketsTomb.Dying():
+			if err := leaky.SnapshotBucketsState(snapshotCfg.Path, buckets, holders); err != nil {
+				log.Errorf("unable to write buckets snapshot : %s", err)
+			}
+			log.Infof("Killing buckets snapshot routine")
+			return nil
+		case <-ticker.C:
+			if err := leaky.SnapshotBucketsState(snapshotCfg.Path, buckets, holders); err != nil {
+				log.Errorf("unable to write buckets snapshot : %s", err)
+			}
+		}
+	}
+}
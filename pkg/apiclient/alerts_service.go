@@ -14,18 +14,19 @@ import (
 type AlertsService service
 
 type AlertsListOpts struct {
-	ScopeEquals          *string `url:"scope,omitempty"`
-	ValueEquals          *string `url:"value,omitempty"`
-	ScenarioEquals       *string `url:"scenario,omitempty"`
-	IPEquals             *string `url:"ip,omitempty"`
-	RangeEquals          *string `url:"range,omitempty"`
-	Since                *string `url:"since,omitempty"`
-	TypeEquals           *string `url:"decision_type,omitempty"`
-	Until                *string `url:"until,omitempty"`
-	IncludeSimulated     *bool   `url:"simulated,omitempty"`
-	ActiveDecisionEquals *bool   `url:"has_active_decision,omitempty"`
-	IncludeCAPI          *bool   `url:"include_capi,omitempty"`
-	Limit                *int    `url:"limit,omitempty"`
+	ScopeEquals          *string  `url:"scope,omitempty"`
+	ValueEquals          *string  `url:"value,omitempty"`
+	ScenarioEquals       *string  `url:"scenario,omitempty"`
+	IPEquals             *string  `url:"ip,omitempty"`
+	RangeEquals          *string  `url:"range,omitempty"`
+	Since                *string  `url:"since,omitempty"`
+	TypeEquals           *string  `url:"decision_type,omitempty"`
+	Until                *string  `url:"until,omitempty"`
+	IncludeSimulated     *bool    `url:"simulated,omitempty"`
+	ActiveDecisionEquals *bool    `url:"has_active_decision,omitempty"`
+	IncludeCAPI          *bool    `url:"include_capi,omitempty"`
+	Labels               []string `url:"label,omitempty"`
+	Limit                *int     `url:"limit,omitempty"`
 	ListOpts
 }
 
@@ -11,6 +11,7 @@ import (
 	"github.com/crowdsecurity/crowdsec/pkg/apiserver/controllers"
 	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
 	"github.com/crowdsecurity/crowdsec/pkg/database"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 	"github.com/gin-gonic/gin"
 	"github.com/go-co-op/gocron"
@@ -43,6 +44,8 @@ func NewServer(config *csconfig.LocalApiServerCfg) (*APIServer, error) {
 	if err != nil {
 		return &APIServer{}, fmt.Errorf("unable to init database client: %s", err)
 	}
+	/*let profiles (and postoverflows when running in the same process) look at the past decisions*/
+	exprhelpers.SetDecisionsHistory(dbClient)
 
 	if config.DbConfig.Flush != nil {
 		flushScheduler, err = dbClient.StartFlushScheduler(config.DbConfig.Flush)
@@ -72,6 +72,9 @@ func FormatOneAlert(alert *ent.Alert) *models.Alert {
 			Value: metaItem.Value,
 		})
 	}
+	for _, labelItem := range alert.Edges.Labels {
+		outputAlert.Labels = append(outputAlert.Labels, types.JoinLabel(labelItem.Key, labelItem.Value))
+	}
 	for _, decisionItem := range alert.Edges.Decisions {
 		duration := decisionItem.Until.Sub(time.Now()).String()
 		outputAlert.Decisions = append(outputAlert.Decisions, &models.Decision{
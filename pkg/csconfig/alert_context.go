@@ -0,0 +1,78 @@
+package csconfig
+
+import (
+	"fmt"
+
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+const (
+	EventsKeepAll    = "all"
+	EventsKeepNone   = "none"
+	EventsKeepFirst  = "first"
+	EventsKeepLast   = "last"
+	EventsKeepSample = "sample"
+
+	defaultContextMaxValues = 10
+	defaultContextMaxSize   = 4000
+	defaultEventsCount      = 10
+)
+
+/*Context of the alerts : the fields of the events extracted into the alert meta, and the events kept in the alert*/
+type AlertContextCfg struct {
+	Context   map[string]string `yaml:"context,omitempty"`    //the keys of the alert meta, and the expressions of their values on the events
+	MaxValues int               `yaml:"max_values,omitempty"` //max distinct values per key, defaults to 10
+	MaxSize   int               `yaml:"max_size,omitempty"`   //max total size of the values once serialized, defaults to 4000
+	Events    *AlertEventsCfg   `yaml:"events,omitempty"`     //the events kept in the alert, all of them if not set
+}
+
+type AlertEventsCfg struct {
+	Keep  string `yaml:"keep,omitempty"`  //all (default), none, first, last or sample
+	Count int    `yaml:"count,omitempty"` //the number of events kept by first, last and sample, defaults to 10
+}
+
+/*the meta set by the distributed scenarios on their alerts, the context can't override them*/
+var reservedContextKeys = []string{types.IncidentMeta, types.IncidentTargetMeta, types.IncidentSourcesMeta, types.IncidentLeaderMeta}
+
+func (a *AlertContextCfg) Load() error {
+	for key, expression := range a.Context {
+		if key == "" || expression == "" {
+			return fmt.Errorf("context key '%s' must have a name and an expression", key)
+		}
+		for _, reserved := range reservedContextKeys {
+			if key == reserved {
+				return fmt.Errorf("context key '%s' is reserved", key)
+			}
+		}
+	}
+	if a.MaxValues < 0 {
+		return fmt.Errorf("max_values must be positive, got %d", a.MaxValues)
+	}
+	if a.MaxValues == 0 {
+		a.MaxValues = defaultContextMaxValues
+	}
+	if a.MaxSize < 0 {
+		return fmt.Errorf("max_size must be positive, got %d", a.MaxSize)
+	}
+	if a.MaxSize == 0 {
+		a.MaxSize = defaultContextMaxSize
+	}
+	if a.Events == nil {
+		return nil
+	}
+	switch a.Events.Keep {
+	case "":
+		a.Events.Keep = EventsKeepAll
+	case EventsKeepAll, EventsKeepNone, EventsKeepFirst, EventsKeepLast, EventsKeepSample:
+	default:
+		return fmt.Errorf("unknown events keep '%s', must be %s, %s, %s, %s or %s", a.Events.Keep,
+			EventsKeepAll, EventsKeepNone, EventsKeepFirst, EventsKeepLast, EventsKeepSample)
+	}
+	if a.Events.Count < 0 {
+		return fmt.Errorf("events count must be positive, got %d", a.Events.Count)
+	}
+	if a.Events.Count == 0 {
+		a.Events.Count = defaultEventsCount
+	}
+	return nil
+}
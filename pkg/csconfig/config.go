@@ -73,6 +73,10 @@ func (c *GlobalConfig) LoadConfiguration() error {
 		c.ConfigPaths.HubIndexFile = filepath.Clean(c.ConfigPaths.HubDir + "/.index.json")
 	}
 
+	if c.ConfigPaths.OverridesFilePath == "" {
+		c.ConfigPaths.OverridesFilePath = filepath.Clean(c.ConfigPaths.ConfigDir + "/scenarios_overrides.yaml")
+	}
+
 	if err := c.LoadSimulation(); err != nil {
 		return err
 	}
@@ -85,6 +89,7 @@ func (c *GlobalConfig) LoadConfiguration() error {
 		c.Crowdsec.DataDir = c.ConfigPaths.DataDir
 		c.Crowdsec.HubDir = c.ConfigPaths.HubDir
 		c.Crowdsec.HubIndexFile = c.ConfigPaths.HubIndexFile
+		c.Crowdsec.OverridesFilePath = c.ConfigPaths.OverridesFilePath
 		if c.Crowdsec.ParserRoutinesCount <= 0 {
 			c.Crowdsec.ParserRoutinesCount = 1
 		}
@@ -96,6 +101,32 @@ func (c *GlobalConfig) LoadConfiguration() error {
 		if c.Crowdsec.OutputRoutinesCount <= 0 {
 			c.Crowdsec.OutputRoutinesCount = 1
 		}
+
+		if c.Crowdsec.Redaction != nil {
+			if err := c.Crowdsec.Redaction.Load(c.ConfigPaths.ConfigDir); err != nil {
+				return errors.Wrap(err, "while loading redaction policy")
+			}
+		}
+		if c.Crowdsec.BucketsSnapshot != nil {
+			if err := c.Crowdsec.BucketsSnapshot.Load(c.ConfigPaths.DataDir); err != nil {
+				return errors.Wrap(err, "while loading buckets snapshot config")
+			}
+		}
+		if c.Crowdsec.AlertsCap != nil {
+			if err := c.Crowdsec.AlertsCap.Load(); err != nil {
+				return errors.Wrap(err, "while loading alerts cap config")
+			}
+		}
+		if c.Crowdsec.EventTime != nil {
+			if err := c.Crowdsec.EventTime.Load(); err != nil {
+				return errors.Wrap(err, "while loading event time config")
+			}
+		}
+		if c.Crowdsec.AlertContext != nil {
+			if err := c.Crowdsec.AlertContext.Load(); err != nil {
+				return errors.Wrap(err, "while loading alert context config")
+			}
+		}
 	}
 
 	if err := c.CleanupPaths(); err != nil {
@@ -108,6 +139,7 @@ func (c *GlobalConfig) LoadConfiguration() error {
 		c.Cscli.DataDir = c.ConfigPaths.DataDir
 		c.Cscli.HubDir = c.ConfigPaths.HubDir
 		c.Cscli.HubIndexFile = c.ConfigPaths.HubIndexFile
+		c.Cscli.OverridesFilePath = c.ConfigPaths.OverridesFilePath
 	}
 
 	if c.API.Client != nil && c.API.Client.CredentialsFilePath != "" {
@@ -1,20 +1,124 @@
 package csconfig
 
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
 /*Configurations needed for crowdsec to load parser/scenarios/... + acquisition*/
 type CrowdsecServiceCfg struct {
 	AcquisitionFilePath  string            `yaml:"acquisition_path,omitempty"`
 	ParserRoutinesCount  int               `yaml:"parser_routines"`
+	ParserShardKey       string            `yaml:"parser_shard_key,omitempty"` //expr used to dispatch lines to parser routines, defaults to evt.Line.Src
 	BucketsRoutinesCount int               `yaml:"buckets_routines"`
+	BucketsShards        int               `yaml:"buckets_shards,omitempty"` //number of routines owning the live buckets, defaults to the number of cpus
 	OutputRoutinesCount  int               `yaml:"output_routines"`
 	SimulationConfig     *SimulationConfig `yaml:"-"`
 	LintOnly             bool              `yaml:"-"`                          //if set to true, exit after loading configs
 	BucketStateFile      string            `yaml:"state_input_file,omitempty"` //if we need to unserialize buckets at start
 	BucketStateDumpDir   string            `yaml:"state_output_dir,omitempty"` //if we need to unserialize buckets on shutdown
 	BucketsGCEnabled     bool              `yaml:"-"`                          //we need to garbage collect buckets when in forensic mode
+	Redaction            *RedactionCfg     `yaml:"redaction,omitempty"`        //privacy rules applied to events meta before they are sent to LAPI
+	ExprSlowAfter        time.Duration     `yaml:"expr_slow_after,omitempty"`  //expression evaluations found to take longer, once they returned, are counted as errors
+	ExprMaxErrors        int               `yaml:"expr_max_errors,omitempty"`  //consecutive expression errors before a parser node or scenario is quarantined
+	BucketsCap           *BucketsCapCfg    `yaml:"buckets_cap,omitempty"`      //max number of live buckets and what to do when it's reached
+	AlertsCap            *AlertsCapCfg     `yaml:"alerts_cap,omitempty"`       //max rate of the alerts sent to LAPI, the alerts beyond are summarized
+	BucketsSnapshot      *SnapshotCfg      `yaml:"buckets_snapshot,omitempty"` //periodic snapshots of the live buckets, restored at start
+	EventTime            *EventTimeCfg     `yaml:"event_time,omitempty"`       //live buckets use the time of the events rather than the time they are read at
+	AdminSocket          string            `yaml:"admin_socket,omitempty"`     //unix socket of the local admin api, used by cscli to inspect the live buckets
+	AlertContext         *AlertContextCfg  `yaml:"alert_context,omitempty"`    //the fields of the events extracted into the alerts, and the events kept in them
 
 	HubDir             string `yaml:"-"`
 	DataDir            string `yaml:"-"`
 	ConfigDir          string `yaml:"-"`
 	HubIndexFile       string `yaml:"-"`
 	SimulationFilePath string `yaml:"-"`
+	OverridesFilePath  string `yaml:"-"` //local overrides of the scenarios settings
+}
+
+//BucketsCapCfg bounds the number of live buckets, so that a flood of distinct partitions can't exhaust the memory
+type BucketsCapCfg struct {
+	MaxBuckets int    `yaml:"max_buckets,omitempty"`  //max number of live buckets, all scenarios included. 0 means no limit
+	Eviction   string `yaml:"eviction,omitempty"`     //what to do when the max is reached : oldest (default), least_filled or refuse
+	AlertOnCap bool   `yaml:"alert_on_cap,omitempty"` //send an alert when a max is reached, as detection is degraded
+}
+
+//AlertsCapCfg bounds the rate of the alerts sent to LAPI, so that a runaway scenario can't flood it with alerts (and decisions)
+type AlertsCapCfg struct {
+	Interval       time.Duration  `yaml:"interval,omitempty"`                //the caps are per interval, defaults to 1m
+	MaxAlerts      int            `yaml:"max_alerts,omitempty"`              //max number of alerts per interval, all scenarios included. 0 means no limit
+	MaxPerScenario int            `yaml:"max_alerts_per_scenario,omitempty"` //max number of alerts per interval of each scenario. 0 means no limit
+	Scenarios      map[string]int `yaml:"scenarios,omitempty"`               //max number of alerts per interval of specific scenarios, instead of max_alerts_per_scenario
+}
+
+const defaultAlertsCapInterval = time.Minute
+
+func (a *AlertsCapCfg) Load() error {
+	if a.Interval < 0 {
+		return fmt.Errorf("invalid interval %s", a.Interval)
+	}
+	if a.Interval == 0 {
+		a.Interval = defaultAlertsCapInterval
+	}
+	if a.MaxAlerts < 0 {
+		return fmt.Errorf("max_alerts must be positive, got %d", a.MaxAlerts)
+	}
+	if a.MaxPerScenario < 0 {
+		return fmt.Errorf("max_alerts_per_scenario must be positive, got %d", a.MaxPerScenario)
+	}
+	for scenario, max := range a.Scenarios {
+		if max < 0 {
+			return fmt.Errorf("max number of alerts of %s must be positive, got %d", scenario, max)
+		}
+	}
+	return nil
+}
+
+const defaultAllowedLateness = time.Minute
+
+//EventTimeCfg makes the live buckets leak according to the time of the events, so that delayed logs (ie. batched forwarding) are counted when they happened
+type EventTimeCfg struct {
+	AllowedLateness time.Duration `yaml:"allowed_lateness,omitempty"` //events older than that aren't poured, they are counted as late. Defaults to 1m
+}
+
+func (e *EventTimeCfg) Load() error {
+	if e.AllowedLateness < 0 {
+		return fmt.Errorf("invalid allowed_lateness %s", e.AllowedLateness)
+	}
+	if e.AllowedLateness == 0 {
+		e.AllowedLateness = defaultAllowedLateness
+	}
+	return nil
+}
+
+const (
+	defaultSnapshotInterval = time.Minute
+	defaultSnapshotMaxAge   = 24 * time.Hour
+)
+
+//SnapshotCfg configures the periodic snapshots of the live buckets state, so that they survive a crash or an upgrade
+type SnapshotCfg struct {
+	Interval time.Duration `yaml:"interval,omitempty"` //time between two snapshots, defaults to 1m
+	Path     string        `yaml:"path,omitempty"`     //defaults to <data_dir>/buckets_state.json
+	MaxAge   time.Duration `yaml:"max_age,omitempty"`  //older snapshots aren't restored, defaults to 24h
+}
+
+func (s *SnapshotCfg) Load(dataDir string) error {
+	if s.Interval < 0 {
+		return fmt.Errorf("invalid interval %s", s.Interval)
+	}
+	if s.MaxAge < 0 {
+		return fmt.Errorf("invalid max_age %s", s.MaxAge)
+	}
+	if s.Interval == 0 {
+		s.Interval = defaultSnapshotInterval
+	}
+	if s.MaxAge == 0 {
+		s.MaxAge = defaultSnapshotMaxAge
+	}
+	if s.Path == "" {
+		s.Path = filepath.Join(dataDir, "buckets_state.json")
+	}
+	return nil
 }
@@ -4,26 +4,39 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/antonmedv/expr"
 	"github.com/antonmedv/expr/vm"
 	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
 	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	//IncidentPerSource profiles take their decisions on each source of an incident
+	IncidentPerSource = "per_source"
+	//IncidentAggregate profiles take their decisions once for the whole incident, on its target
+	IncidentAggregate = "aggregate"
+)
+
 //Profile structure(s) are used by the local API to "decide" what kind of decision should be applied when a scenario with an active remediation has been triggered
 type ProfileCfg struct {
-	Name           string                      `yaml:"name,omitempty"`
-	Debug          *bool                       `yaml:"debug,omitempty"`
-	Filters        []string                    `yaml:"filters,omitempty"` //A list of OR'ed expressions. the models.Alert object
-	RuntimeFilters []*vm.Program               `json:"-"`
-	DebugFilters   []*exprhelpers.ExprDebugger `json:"-"`
-	Decisions      []models.Decision           `yaml:"decisions,omitempty"`
-	OnSuccess      string                      `yaml:"on_success,omitempty"` //continue or break
-	OnFailure      string                      `yaml:"on_failure,omitempty"` //continue or break
+	Name                string                      `yaml:"name,omitempty"`
+	Debug               *bool                       `yaml:"debug,omitempty"`
+	Filters             []string                    `yaml:"filters,omitempty"` //A list of OR'ed expressions. the models.Alert object
+	RuntimeFilters      []*vm.Program               `json:"-"`
+	DebugFilters        []*exprhelpers.ExprDebugger `json:"-"`
+	Decisions           []models.Decision           `yaml:"decisions,omitempty"`
+	DurationExpr        string                      `yaml:"duration_expr,omitempty"` //if set, an expression returning the duration of the decisions (ie. '4h')
+	RuntimeDurationExpr *vm.Program                 `json:"-"`
+	OnSuccess           string                      `yaml:"on_success,omitempty"` //continue or break
+	OnFailure           string                      `yaml:"on_failure,omitempty"` //continue or break
+	Incident            string                      `yaml:"incident,omitempty"`   //per_source (default) or aggregate : how the alerts of an incident (a distributed attack) are remediated
 }
 
 func (c *LocalApiServerCfg) LoadProfiles() error {
@@ -62,6 +75,9 @@ func (c *LocalApiServerCfg) LoadProfiles() error {
 			if runtimeFilter, err = expr.Compile(filter, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"Alert": &models.Alert{}}))); err != nil {
 				return errors.Wrapf(err, "Error compiling filter of %s", profile.Name)
 			}
+			if err = exprhelpers.CheckHistoryDurations(filter); err != nil {
+				return errors.Wrapf(err, "Error compiling filter of %s", profile.Name)
+			}
 			c.Profiles[pIdx].RuntimeFilters[fIdx] = runtimeFilter
 			if debugFilter, err = exprhelpers.NewDebugger(filter, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"Alert": &models.Alert{}}))); err != nil {
 				log.Debugf("Error compiling debug filter of %s : %s", profile.Name, err)
@@ -70,6 +86,37 @@ func (c *LocalApiServerCfg) LoadProfiles() error {
 			}
 			c.Profiles[pIdx].DebugFilters[fIdx] = debugFilter
 		}
+
+		switch profile.Incident {
+		case "":
+			c.Profiles[pIdx].Incident = IncidentPerSource
+		case IncidentPerSource:
+		case IncidentAggregate:
+			for _, decision := range profile.Decisions {
+				if decision.Scope == nil || *decision.Scope == "" {
+					return fmt.Errorf("profile %s : the decisions of aggregate profiles must have a scope", profile.Name)
+				}
+				/*the value of the decision is the target of the incident (ie. a username), not an address*/
+				if strings.EqualFold(*decision.Scope, types.Ip) || strings.EqualFold(*decision.Scope, types.Range) {
+					return fmt.Errorf("profile %s : the decisions of aggregate profiles are on the target of the incident, they can't have the scope %s", profile.Name, *decision.Scope)
+				}
+			}
+		default:
+			return fmt.Errorf("profile %s : unknown incident '%s', must be %s or %s", profile.Name, profile.Incident, IncidentPerSource, IncidentAggregate)
+		}
+
+		if profile.DurationExpr != "" {
+			env := exprhelpers.GetExprEnv(map[string]interface{}{"Alert": &models.Alert{}})
+			if c.Profiles[pIdx].RuntimeDurationExpr, err = expr.Compile(profile.DurationExpr, expr.Env(env)); err != nil {
+				return errors.Wrapf(err, "Error compiling duration_expr of %s", profile.Name)
+			}
+			if err = exprhelpers.CheckReturnKind(profile.DurationExpr, env, reflect.String); err != nil {
+				return errors.Wrapf(err, "Error compiling duration_expr of %s", profile.Name)
+			}
+			if err = exprhelpers.CheckHistoryDurations(profile.DurationExpr); err != nil {
+				return errors.Wrapf(err, "Error compiling duration_expr of %s", profile.Name)
+			}
+		}
 	}
 	if len(c.Profiles) == 0 {
 		return fmt.Errorf("zero profiles loaded for LAPI")
@@ -0,0 +1,71 @@
+package csconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAggregateProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crowdsec-profiles-")
+	if err != nil {
+		t.Fatalf("while creating temp dir : %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		profile string
+		err     string
+	}{
+		{
+			name:    "aggregate on the target",
+			profile: "name: aggregate\nincident: aggregate\nfilters:\n - Alert.Remediation == true\ndecisions:\n - type: captcha\n   scope: username\n   duration: 1h\n",
+		},
+		{
+			name:    "aggregate without scope",
+			profile: "name: aggregate\nincident: aggregate\nfilters:\n - Alert.Remediation == true\ndecisions:\n - type: ban\n   duration: 1h\n",
+			err:     "must have a scope",
+		},
+		{
+			name:    "aggregate on ip",
+			profile: "name: aggregate\nincident: aggregate\nfilters:\n - Alert.Remediation == true\ndecisions:\n - type: ban\n   scope: Ip\n   duration: 1h\n",
+			err:     "can't have the scope Ip",
+		},
+		{
+			name:    "aggregate on range",
+			profile: "name: aggregate\nincident: aggregate\nfilters:\n - Alert.Remediation == true\ndecisions:\n - type: ban\n   scope: range\n   duration: 1h\n",
+			err:     "can't have the scope range",
+		},
+		{
+			name:    "invalid history duration in filter",
+			profile: "name: recidive\nfilters:\n - GetAlertsCountSince(Alert.GetValue(), '1 week') > 2\ndecisions:\n - type: ban\n   duration: 1h\n",
+			err:     "invalid duration '1 week'",
+		},
+		{
+			name:    "invalid history duration in duration_expr",
+			profile: "name: recidive\nfilters:\n - Alert.Remediation == true\ndecisions:\n - type: ban\n   duration: 1h\nduration_expr: \"GetAlertsCountSince(Alert.GetValue(), 'forever') > 2 ? '96h' : '4h'\"\n",
+			err:     "invalid duration 'forever'",
+		},
+	}
+
+	for idx, test := range tests {
+		path := filepath.Join(dir, "profiles.yaml")
+		if err := ioutil.WriteFile(path, []byte(test.profile), 0644); err != nil {
+			t.Fatalf("while writing profiles : %s", err)
+		}
+		cfg := LocalApiServerCfg{ProfilesPath: path}
+		err := cfg.LoadProfiles()
+		if test.err == "" {
+			if err != nil {
+				t.Fatalf("%d/%d (%s) : unexpected error %s", idx, len(tests), test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%d/%d (%s) : expected error '%s', got '%v'", idx, len(tests), test.name, test.err, err)
+		}
+	}
+}
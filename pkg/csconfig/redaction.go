@@ -0,0 +1,94 @@
+package csconfig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+const defaultRedactionReplacement = "<redacted>"
+
+/*Redaction policy applied to the events meta-data before they leave the agent (alerts sent to LAPI)*/
+type RedactionCfg struct {
+	AllowMeta   []string            `yaml:"allow_meta,omitempty"`    //if not empty, only those meta keys are kept
+	DenyMeta    []string            `yaml:"deny_meta,omitempty"`     //those meta keys are always dropped
+	Mask        []*RedactionMaskCfg `yaml:"mask,omitempty"`          //regexp masking applied to meta values
+	Hash        []string            `yaml:"hash,omitempty"`          //meta keys which value is replaced by an HMAC
+	HashKeyPath string              `yaml:"hash_key_path,omitempty"` //file holding the local HMAC key
+	hashKey     []byte
+}
+
+type RedactionMaskCfg struct {
+	Key         string         `yaml:"key,omitempty"` //meta key the mask applies to, every key if empty
+	Regexp      string         `yaml:"regexp"`
+	Replacement string         `yaml:"replacement,omitempty"`
+	runtimeRe   *regexp.Regexp `yaml:"-"`
+}
+
+func (r *RedactionCfg) Load(configDir string) error {
+	for idx, mask := range r.Mask {
+		if mask.Regexp == "" {
+			return fmt.Errorf("redaction mask %d : empty regexp", idx)
+		}
+		re, err := regexp.Compile(mask.Regexp)
+		if err != nil {
+			return errors.Wrapf(err, "while compiling redaction mask '%s'", mask.Regexp)
+		}
+		mask.runtimeRe = re
+		if mask.Replacement == "" {
+			mask.Replacement = defaultRedactionReplacement
+		}
+	}
+	if len(r.Hash) == 0 {
+		return nil
+	}
+	if r.HashKeyPath == "" {
+		r.HashKeyPath = filepath.Clean(configDir + "/redaction.key")
+	}
+	key, err := ioutil.ReadFile(r.HashKeyPath)
+	if err != nil {
+		return errors.Wrapf(err, "while reading redaction hash key (create it with 'head -c 32 /dev/urandom > %s')", r.HashKeyPath)
+	}
+	if len(key) < 16 {
+		return fmt.Errorf("redaction hash key '%s' is too short (%d bytes, need at least 16)", r.HashKeyPath, len(key))
+	}
+	r.hashKey = key
+	return nil
+}
+
+func redactionMatch(key string, list []string) bool {
+	for _, k := range list {
+		if k == key || k == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+//RedactMeta returns the value that is allowed to leave the agent for a given meta key. The bool is false if the key must be dropped.
+func (r *RedactionCfg) RedactMeta(key string, value string) (string, bool) {
+	if redactionMatch(key, r.DenyMeta) {
+		return "", false
+	}
+	if len(r.AllowMeta) > 0 && !redactionMatch(key, r.AllowMeta) {
+		return "", false
+	}
+	if redactionMatch(key, r.Hash) {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(value))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)), true
+	}
+	for _, mask := range r.Mask {
+		if mask.Key != "" && mask.Key != key {
+			continue
+		}
+		value = mask.runtimeRe.ReplaceAllString(value, mask.Replacement)
+	}
+	return value, true
+}
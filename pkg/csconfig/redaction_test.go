@@ -0,0 +1,101 @@
+package csconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactionLoad(t *testing.T) {
+	tests := []struct {
+		name  string
+		Input *RedactionCfg
+		err   string
+	}{
+		{
+			name:  "valid masks",
+			Input: &RedactionCfg{Mask: []*RedactionMaskCfg{{Regexp: `[a-z]+@[a-z.]+`}}},
+		},
+		{
+			name:  "bad regexp",
+			Input: &RedactionCfg{Mask: []*RedactionMaskCfg{{Regexp: `(`}}},
+			err:   "while compiling redaction mask '('",
+		},
+		{
+			name:  "hash without key",
+			Input: &RedactionCfg{Hash: []string{"user"}, HashKeyPath: "./tests/xxx.key"},
+			err:   "while reading redaction hash key",
+		},
+		{
+			name:  "hash with default key",
+			Input: &RedactionCfg{Hash: []string{"user"}},
+		},
+	}
+
+	for idx, test := range tests {
+		err := test.Input.Load("./tests")
+		if test.err == "" {
+			if err != nil {
+				t.Fatalf("%d/%d (%s) : unexpected error %s", idx, len(tests), test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Fatalf("%d/%d (%s) : expected error '%s' got '%v'", idx, len(tests), test.name, test.err, err)
+		}
+	}
+}
+
+func TestRedactMeta(t *testing.T) {
+	policy := &RedactionCfg{
+		DenyMeta: []string{"password"},
+		Hash:     []string{"user"},
+		Mask: []*RedactionMaskCfg{
+			{Key: "http_path", Regexp: `\?.*$`, Replacement: "?<redacted>"},
+			{Regexp: `[a-z0-9._-]+@[a-z0-9.-]+`},
+		},
+	}
+	if err := policy.Load("./tests"); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	tests := []struct {
+		key      string
+		value    string
+		expected string
+		kept     bool
+	}{
+		{key: "password", value: "secret", kept: false},
+		{key: "source_ip", value: "1.2.3.4", expected: "1.2.3.4", kept: true},
+		{key: "http_path", value: "/login?user=bob", expected: "/login?<redacted>", kept: true},
+		{key: "log", value: "mail from bob@example.com", expected: "mail from <redacted>", kept: true},
+	}
+	for _, test := range tests {
+		value, kept := policy.RedactMeta(test.key, test.value)
+		assert.Equal(t, test.kept, kept, test.key)
+		if test.kept {
+			assert.Equal(t, test.expected, value, test.key)
+		}
+	}
+
+	//the hash is stable and doesn't leak the value
+	h1, _ := policy.RedactMeta("user", "bob")
+	h2, _ := policy.RedactMeta("user", "bob")
+	h3, _ := policy.RedactMeta("user", "alice")
+	assert.Equal(t, h1, h2)
+	assert.NotEqual(t, h1, h3)
+	assert.True(t, strings.HasPrefix(h1, "hmac:"))
+	assert.NotContains(t, h1, "bob")
+
+	//allow list drops everything else
+	policy = &RedactionCfg{AllowMeta: []string{"source_ip"}}
+	if err := policy.Load("./tests"); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	_, kept := policy.RedactMeta("user", "bob")
+	assert.False(t, kept)
+	value, kept := policy.RedactMeta("source_ip", "1.2.3.4")
+	assert.True(t, kept)
+	assert.Equal(t, "1.2.3.4", value)
+}
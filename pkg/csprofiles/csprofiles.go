@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/antonmedv/expr"
 	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
@@ -14,9 +15,31 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+//durationFromExpr evaluates the duration_expr of the profile, it must return a valid duration string
+func durationFromExpr(Profile *csconfig.ProfileCfg, Alert *models.Alert) (string, error) {
+	output, err := expr.Run(Profile.RuntimeDurationExpr, exprhelpers.GetExprEnv(map[string]interface{}{"Alert": Alert}))
+	if err != nil {
+		return "", errors.Wrapf(err, "while running duration_expr %s", Profile.DurationExpr)
+	}
+	duration, ok := output.(string)
+	if !ok {
+		return "", fmt.Errorf("duration_expr returned non-string : %T", output)
+	}
+	if _, err := time.ParseDuration(duration); err != nil {
+		return "", errors.Wrapf(err, "duration_expr returned invalid duration")
+	}
+	return duration, nil
+}
+
 func GenerateDecisionFromProfile(Profile *csconfig.ProfileCfg, Alert *models.Alert) ([]*models.Decision, error) {
 	var decisions []*models.Decision
 
+	/*aggregate profiles take their decisions once for the incident : they are carried by the alert of its leader*/
+	aggregate := Profile.Incident == csconfig.IncidentAggregate && Alert.GetIncident() != ""
+	if aggregate && Alert.GetValue() != Alert.GetMeta(types.IncidentLeaderMeta) {
+		return nil, nil
+	}
+
 	for _, refDecision := range Profile.Decisions {
 		decision := models.Decision{}
 		/*the reference decision from profile is in sumulated mode */
@@ -39,17 +62,35 @@ func GenerateDecisionFromProfile(Profile *csconfig.ProfileCfg, Alert *models.Ale
 		/*some fields are populated from the reference object : duration, scope, type*/
 		decision.Duration = new(string)
 		*decision.Duration = *refDecision.Duration
+		if Profile.RuntimeDurationExpr != nil {
+			duration, err := durationFromExpr(Profile, Alert)
+			if err != nil {
+				log.Warningf("Profile [%s] failed to compute duration, using '%s' : %s", Profile.Name, *refDecision.Duration, err)
+			} else {
+				*decision.Duration = duration
+			}
+		}
 		decision.Type = new(string)
 		*decision.Type = *refDecision.Type
 
 		/*for the others, let's populate it from the alert and its source*/
 		decision.Value = new(string)
 		*decision.Value = *Alert.Source.Value
+		srcIP, srcRange := Alert.Source.IP, Alert.Source.Range
+		if aggregate {
+			/*the decisions of the incident are on its target*/
+			*decision.Value = Alert.GetMeta(types.IncidentTargetMeta)
+			if *decision.Value == "" {
+				log.Warningf("Profile [%s] requires incident decision, but the target of the incident of %s is missing", Profile.Name, *Alert.Scenario)
+				continue
+			}
+			srcIP, srcRange = *decision.Value, *decision.Value
+		}
 
 		if strings.EqualFold(*decision.Scope, types.Ip) {
-			srcAddr := net.ParseIP(Alert.Source.IP)
+			srcAddr := net.ParseIP(srcIP)
 			if srcAddr == nil {
-				return nil, fmt.Errorf("can't parse ip %s", Alert.Source.IP)
+				return nil, fmt.Errorf("can't parse ip %s", srcIP)
 			}
 			decision.StartIP = int64(types.IP2Int(srcAddr))
 			decision.EndIP = decision.StartIP
@@ -60,17 +101,17 @@ func GenerateDecisionFromProfile(Profile *csconfig.ProfileCfg, Alert *models.Ale
 			- the alert is about an IP, but the geolite enrichment isn't present
 			- the alert is about a range, in this case it should succeed
 			*/
-			if Alert.Source.Range != "" {
-				srcAddr, srcRange, err := net.ParseCIDR(Alert.Source.Range)
+			if srcRange != "" {
+				srcAddr, ipNet, err := net.ParseCIDR(srcRange)
 				if err != nil {
 					log.Warningf("Profile [%s] requires IP decision, but can't parse '%s' from '%s'",
 						Profile.Name, *Alert.Source.Value, *Alert.Scenario)
 					continue
 				}
 				decision.StartIP = int64(types.IP2Int(srcAddr))
-				decision.EndIP = int64(types.IP2Int(types.LastAddress(srcRange)))
+				decision.EndIP = int64(types.IP2Int(types.LastAddress(ipNet)))
 				decision.Value = new(string)
-				*decision.Value = Alert.Source.Range
+				*decision.Value = srcRange
 			} else {
 				log.Warningf("Profile [%s] requires scope decision, but information is missing from %s", Profile.Name, *Alert.Scenario)
 				continue
@@ -0,0 +1,170 @@
+package csprofiles
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func incidentAlert(ip string) *models.Alert {
+	return &models.Alert{
+		Scenario:    strPtr("crowdsecurity/distributed-bf"),
+		Remediation: true,
+		Source:      &models.Source{IP: ip, Scope: strPtr(types.Ip), Value: strPtr(ip)},
+		Meta: models.Meta{
+			&models.MetaItems0{Key: types.IncidentMeta, Value: "abcd-1"},
+			&models.MetaItems0{Key: types.IncidentSourcesMeta, Value: "3"},
+			&models.MetaItems0{Key: types.IncidentTargetMeta, Value: "admin"},
+			&models.MetaItems0{Key: types.IncidentLeaderMeta, Value: "1.2.3.1"},
+		},
+	}
+}
+
+func TestIncidentDecisions(t *testing.T) {
+	perSource := &csconfig.ProfileCfg{Name: "per_source", Incident: csconfig.IncidentPerSource,
+		Decisions: []models.Decision{{Type: strPtr("ban"), Duration: strPtr("4h")}}}
+	aggregate := &csconfig.ProfileCfg{Name: "aggregate", Incident: csconfig.IncidentAggregate,
+		Decisions: []models.Decision{{Type: strPtr("enforce_mfa"), Scope: strPtr("username"), Duration: strPtr("1h")}}}
+
+	aggregated := 0
+	for _, ip := range []string{"1.2.3.1", "1.2.3.2", "1.2.3.3"} {
+		alert := incidentAlert(ip)
+		if alert.GetSourcesCount() != 3 {
+			t.Fatalf("expected 3 sources, got %d", alert.GetSourcesCount())
+		}
+		decisions, err := GenerateDecisionFromProfile(perSource, alert)
+		if err != nil {
+			t.Fatalf("while generating decisions : %s", err)
+		}
+		if len(decisions) != 1 || *decisions[0].Value != ip {
+			t.Fatalf("expected a decision on %s", ip)
+		}
+		decisions, err = GenerateDecisionFromProfile(aggregate, alert)
+		if err != nil {
+			t.Fatalf("while generating decisions : %s", err)
+		}
+		for _, decision := range decisions {
+			if *decision.Scope != "username" || *decision.Value != "admin" {
+				t.Fatalf("unexpected decision on %s:%s", *decision.Scope, *decision.Value)
+			}
+			aggregated++
+		}
+	}
+	if aggregated != 1 {
+		t.Fatalf("expected 1 decision for the incident, got %d", aggregated)
+	}
+
+	/*alerts out of an incident are remediated per source by aggregate profiles*/
+	alert := incidentAlert("1.2.3.4")
+	alert.Meta = nil
+	if alert.GetSourcesCount() != 1 {
+		t.Fatalf("expected 1 source, got %d", alert.GetSourcesCount())
+	}
+	decisions, err := GenerateDecisionFromProfile(aggregate, alert)
+	if err != nil {
+		t.Fatalf("while generating decisions : %s", err)
+	}
+	if len(decisions) != 1 || *decisions[0].Value != "1.2.3.4" {
+		t.Fatalf("expected a decision on 1.2.3.4")
+	}
+}
+
+func TestConfidenceFilter(t *testing.T) {
+	filter := `Alert.GetScope() == "Ip" && Alert.GetConfidence() >= 80`
+	program, err := expr.Compile(filter, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"Alert": &models.Alert{}})))
+	if err != nil {
+		t.Fatalf("while compiling filter : %s", err)
+	}
+	profile := &csconfig.ProfileCfg{Name: "high_confidence", Filters: []string{filter}, RuntimeFilters: []*vm.Program{program},
+		Decisions: []models.Decision{{Type: strPtr("ban"), Duration: strPtr("4h")}}}
+
+	tests := []struct {
+		labels    []string
+		decisions int
+	}{
+		{[]string{"confidence:90", "severity:high"}, 1},
+		{[]string{"confidence:50", "severity:high"}, 0},
+		{[]string{"type:bruteforce"}, 0},
+	}
+	for _, test := range tests {
+		alert := incidentAlert("1.2.3.4")
+		alert.Meta = nil
+		alert.Labels = test.labels
+		decisions, err := EvaluateProfiles([]*csconfig.ProfileCfg{profile}, alert)
+		if err != nil {
+			t.Fatalf("while evaluating profiles : %s", err)
+		}
+		if len(decisions) != test.decisions {
+			t.Fatalf("expected %d decisions for %v, got %d", test.decisions, test.labels, len(decisions))
+		}
+	}
+}
+
+type fakeHistory struct{}
+
+func (f fakeHistory) CountDecisionsByValue(value string) (int, error) {
+	if value == "1.2.3.4" {
+		return 2, nil
+	}
+	return 0, nil
+}
+
+func (f fakeHistory) CountActiveDecisionsByValue(value string) (int, error) {
+	return 0, nil
+}
+
+func (f fakeHistory) CountAlertsByValueSince(value string, since time.Time) (int, error) {
+	return 0, fmt.Errorf("database is down")
+}
+
+func TestDurationExprHistory(t *testing.T) {
+	durationExpr := `GetDecisionsCount(Alert.GetValue()) > 0 ? "96h" : "24h"`
+	program, err := expr.Compile(durationExpr, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"Alert": &models.Alert{}})))
+	if err != nil {
+		t.Fatalf("while compiling duration_expr : %s", err)
+	}
+	profile := &csconfig.ProfileCfg{Name: "recidive", DurationExpr: durationExpr, RuntimeDurationExpr: program,
+		Decisions: []models.Decision{{Type: strPtr("ban"), Duration: strPtr("4h")}}}
+
+	duration := func(ip string) string {
+		alert := incidentAlert(ip)
+		alert.Meta = nil
+		decisions, err := GenerateDecisionFromProfile(profile, alert)
+		if err != nil {
+			t.Fatalf("while generating decisions : %s", err)
+		}
+		if len(decisions) != 1 {
+			t.Fatalf("expected 1 decision on %s, got %d", ip, len(decisions))
+		}
+		return *decisions[0].Duration
+	}
+
+	/*without database, the expression fails and the duration of the profile is used*/
+	exprhelpers.SetDecisionsHistory(nil)
+	if _, err := durationFromExpr(profile, incidentAlert("1.2.3.4")); err == nil {
+		t.Fatalf("expected duration_expr to fail without database")
+	}
+	if d := duration("1.2.3.4"); d != "4h" {
+		t.Fatalf("expected the duration of the profile, got %s", d)
+	}
+
+	exprhelpers.SetDecisionsHistory(fakeHistory{})
+	defer exprhelpers.SetDecisionsHistory(nil)
+	if d := duration("1.2.3.4"); d != "96h" {
+		t.Fatalf("expected 96h for a repeat offender, got %s", d)
+	}
+	if d := duration("5.6.7.8"); d != "24h" {
+		t.Fatalf("expected 24h for a first offense, got %s", d)
+	}
+}
@@ -206,3 +206,29 @@ func DownloadItem(cscli *csconfig.CscliCfg, target Item, overwrite bool) (Item,
 	hubIdx[target.Type][target.Name] = target
 	return target, nil
 }
+
+//GetItemData returns the data files declared by an installed parser, postoverflow or scenario
+func GetItemData(target Item) ([]*types.DataSource, error) {
+	ret := []*types.DataSource{}
+	if target.LocalPath == "" {
+		return ret, nil
+	}
+	f, err := os.Open(target.LocalPath)
+	if err != nil {
+		return ret, errors.Wrapf(err, "while opening %s", target.LocalPath)
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	for {
+		data := &types.DataSet{}
+		err = dec.Decode(data)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return ret, errors.Wrapf(err, "while reading %s", target.LocalPath)
+		}
+		ret = append(ret, data.Data...)
+	}
+	return ret, nil
+}
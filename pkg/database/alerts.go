@@ -11,6 +11,7 @@ import (
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/alert"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/decision"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/event"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/label"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/meta"
 	"github.com/crowdsecurity/crowdsec/pkg/models"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
@@ -124,6 +125,7 @@ func (c *Client) CreateAlertBulk(machineId string, alertList []*models.Alert) ([
 	for i, alertItem := range alertList {
 		var decisions []*ent.Decision
 		var metas []*ent.Meta
+		var labels []*ent.Label
 		var events []*ent.Event
 
 		owner, err := c.QueryMachineByID(machineId)
@@ -183,6 +185,20 @@ func (c *Client) CreateAlertBulk(machineId string, alertList []*models.Alert) ([
 			}
 		}
 
+		if len(alertItem.Labels) > 0 {
+			labelBulk := make([]*ent.LabelCreate, len(alertItem.Labels))
+			for i, labelItem := range alertItem.Labels {
+				key, value := types.SplitLabel(labelItem)
+				labelBulk[i] = c.Ent.Label.Create().
+					SetKey(key).
+					SetValue(value)
+			}
+			labels, err = c.Ent.Label.CreateBulk(labelBulk...).Save(c.CTX)
+			if err != nil {
+				return []string{}, errors.Wrapf(BulkError, "creating alert labels: %s", err)
+			}
+		}
+
 		ts, err := time.Parse(time.RFC3339, *alertItem.StopAt)
 		if err != nil {
 			log.Errorf("While parsing StartAt of item %s : %s", *alertItem.StopAt, err)
@@ -237,7 +253,8 @@ func (c *Client) CreateAlertBulk(machineId string, alertList []*models.Alert) ([
 			SetScenarioHash(*alertItem.ScenarioHash).
 			AddDecisions(decisions...).
 			AddEvents(events...).
-			AddMetas(metas...)
+			AddMetas(metas...).
+			AddLabels(labels...)
 
 		if owner != nil {
 			alertB.SetOwner(owner)
@@ -344,6 +361,11 @@ func BuildAlertRequestFromFilter(alerts *ent.AlertQuery, filter map[string][]str
 				return nil, fmt.Errorf("Empty time now() - %s", until.String())
 			}
 			alerts = alerts.Where(alert.StartedAtLTE(until))
+		case "label":
+			for _, labelItem := range value {
+				key, val := types.SplitLabel(labelItem)
+				alerts = alerts.Where(alert.HasLabelsWith(label.KeyEQ(key), label.ValueEQ(val)))
+			}
 		case "decision_type":
 			alerts = alerts.Where(alert.HasDecisionsWith(decision.TypeEQ(value[0])))
 		case "include_capi": //allows to exclude one or more specific origins
@@ -392,6 +414,16 @@ func (c *Client) TotalAlerts() (int, error) {
 	return c.Ent.Alert.Query().Count(c.CTX)
 }
 
+//CountAlertsByValueSince returns the number of alerts created since the given time about the source value (ie. an IP)
+func (c *Client) CountAlertsByValueSince(value string, since time.Time) (int, error) {
+	count, err := c.Ent.Alert.Query().Where(alert.SourceValueEQ(value), alert.CreatedAtGTE(since)).Count(c.CTX)
+	if err != nil {
+		log.Warningf("CountAlertsByValueSince : %s", err)
+		return 0, errors.Wrap(QueryFail, "count alerts")
+	}
+	return count, nil
+}
+
 func (c *Client) QueryAlertWithFilter(filter map[string][]string) ([]*ent.Alert, error) {
 	sort := "DESC" // we sort by desc by default
 	if val, ok := filter["sort"]; ok {
@@ -422,6 +454,7 @@ func (c *Client) QueryAlertWithFilter(filter map[string][]string) ([]*ent.Alert,
 			WithDecisions().
 			WithEvents().
 			WithMetas().
+			WithLabels().
 			WithOwner()
 		if sort == "ASC" {
 			alerts = alerts.Order(ent.Asc(alert.FieldCreatedAt))
@@ -475,6 +508,14 @@ func (c *Client) DeleteAlertGraph(alertItem *ent.Alert) error {
 		return errors.Wrapf(DeleteFail, "meta with alert ID '%d'", alertItem.ID)
 	}
 
+	// delete the associated labels
+	_, err = c.Ent.Label.Delete().
+		Where(label.HasOwnerWith(alert.IDEQ(alertItem.ID))).Exec(c.CTX)
+	if err != nil {
+		log.Warningf("DeleteAlertGraph : %s", err)
+		return errors.Wrapf(DeleteFail, "labels with alert ID '%d'", alertItem.ID)
+	}
+
 	// delete the associated decisions
 	_, err = c.Ent.Decision.Delete().
 		Where(decision.HasOwnerWith(alert.IDEQ(alertItem.ID))).Exec(c.CTX)
@@ -563,7 +604,7 @@ func (c *Client) FlushAlerts(MaxAge string, MaxItems int) error {
 }
 
 func (c *Client) GetAlertByID(alertID int) (*ent.Alert, error) {
-	alert, err := c.Ent.Alert.Query().Where(alert.IDEQ(alertID)).WithDecisions().WithEvents().WithMetas().WithOwner().First(c.CTX)
+	alert, err := c.Ent.Alert.Query().Where(alert.IDEQ(alertID)).WithDecisions().WithEvents().WithMetas().WithLabels().WithOwner().First(c.CTX)
 	if err != nil {
 		/*record not found, 404*/
 		if ent.IsNotFound(err) {
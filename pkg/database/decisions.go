@@ -111,6 +111,26 @@ func (c *Client) QueryDecisionWithFilter(filter map[string][]string) ([]*ent.Dec
 	return data, nil
 }
 
+//CountDecisionsByValue returns the number of decisions (active or expired) ever taken on the value (ie. an IP)
+func (c *Client) CountDecisionsByValue(value string) (int, error) {
+	count, err := c.Ent.Decision.Query().Where(decision.ValueEQ(value)).Count(c.CTX)
+	if err != nil {
+		log.Warningf("CountDecisionsByValue : %s", err)
+		return 0, errors.Wrap(QueryFail, "count decisions")
+	}
+	return count, nil
+}
+
+//CountActiveDecisionsByValue returns the number of decisions currently active on the value (ie. an IP)
+func (c *Client) CountActiveDecisionsByValue(value string) (int, error) {
+	count, err := c.Ent.Decision.Query().Where(decision.ValueEQ(value), decision.UntilGT(time.Now())).Count(c.CTX)
+	if err != nil {
+		log.Warningf("CountActiveDecisionsByValue : %s", err)
+		return 0, errors.Wrap(QueryFail, "count active decisions")
+	}
+	return count, nil
+}
+
 func (c *Client) QueryAllDecisions() ([]*ent.Decision, error) {
 	data, err := c.Ent.Decision.Query().Where(decision.UntilGT(time.Now())).All(c.CTX)
 	if err != nil {
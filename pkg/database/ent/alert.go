@@ -77,9 +77,11 @@ type AlertEdges struct {
 	Events []*Event
 	// Metas holds the value of the metas edge.
 	Metas []*Meta
+	// Labels holds the value of the labels edge.
+	Labels []*Label
 	// loadedTypes holds the information for reporting if a
 	// type was loaded (or requested) in eager-loading or not.
-	loadedTypes [4]bool
+	loadedTypes [5]bool
 }
 
 // OwnerOrErr returns the Owner value or an error if the edge
@@ -123,6 +125,15 @@ func (e AlertEdges) MetasOrErr() ([]*Meta, error) {
 	return nil, &NotLoadedError{edge: "metas"}
 }
 
+// LabelsOrErr returns the Labels value or an error if the edge
+// was not loaded in eager-loading.
+func (e AlertEdges) LabelsOrErr() ([]*Label, error) {
+	if e.loadedTypes[4] {
+		return e.Labels, nil
+	}
+	return nil, &NotLoadedError{edge: "labels"}
+}
+
 // scanValues returns the types for scanning values from sql.Rows.
 func (*Alert) scanValues() []interface{} {
 	return []interface{}{
@@ -313,6 +324,11 @@ func (a *Alert) QueryMetas() *MetaQuery {
 	return (&AlertClient{config: a.config}).QueryMetas(a)
 }
 
+// QueryLabels queries the labels edge of the Alert.
+func (a *Alert) QueryLabels() *LabelQuery {
+	return (&AlertClient{config: a.config}).QueryLabels(a)
+}
+
 // Update returns a builder for updating this Alert.
 // Note that, you need to call Alert.Unwrap() before calling this method, if this Alert
 // was returned from a transaction, and the transaction was committed or rolled back.
@@ -64,6 +64,8 @@ const (
 	EdgeEvents = "events"
 	// EdgeMetas holds the string denoting the metas edge name in mutations.
 	EdgeMetas = "metas"
+	// EdgeLabels holds the string denoting the labels edge name in mutations.
+	EdgeLabels = "labels"
 
 	// Table holds the table name of the alert in the database.
 	Table = "alerts"
@@ -95,6 +97,13 @@ const (
 	MetasInverseTable = "meta"
 	// MetasColumn is the table column denoting the metas relation/edge.
 	MetasColumn = "alert_metas"
+	// LabelsTable is the table the holds the labels relation/edge.
+	LabelsTable = "labels"
+	// LabelsInverseTable is the table name for the Label entity.
+	// It exists in this package in order to avoid circular dependency with the "label" package.
+	LabelsInverseTable = "labels"
+	// LabelsColumn is the table column denoting the labels relation/edge.
+	LabelsColumn = "alert_labels"
 )
 
 // Columns holds all SQL columns for alert fields.
@@ -11,6 +11,7 @@ import (
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/alert"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/decision"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/event"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/label"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/machine"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/meta"
 	"github.com/facebook/ent/dialect/sql/sqlgraph"
@@ -388,6 +389,21 @@ func (ac *AlertCreate) AddMetas(m ...*Meta) *AlertCreate {
 	return ac.AddMetaIDs(ids...)
 }
 
+// AddLabelIDs adds the labels edge to Label by ids.
+func (ac *AlertCreate) AddLabelIDs(ids ...int) *AlertCreate {
+	ac.mutation.AddLabelIDs(ids...)
+	return ac
+}
+
+// AddLabels adds the labels edges to Label.
+func (ac *AlertCreate) AddLabels(l ...*Label) *AlertCreate {
+	ids := make([]int, len(l))
+	for i := range l {
+		ids[i] = l[i].ID
+	}
+	return ac.AddLabelIDs(ids...)
+}
+
 // Mutation returns the AlertMutation object of the builder.
 func (ac *AlertCreate) Mutation() *AlertMutation {
 	return ac.mutation
@@ -767,6 +783,25 @@ func (ac *AlertCreate) createSpec() (*Alert, *sqlgraph.CreateSpec) {
 		}
 		_spec.Edges = append(_spec.Edges, edge)
 	}
+	if nodes := ac.mutation.LabelsIDs(); len(nodes) > 0 {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.O2M,
+			Inverse: false,
+			Table:   alert.LabelsTable,
+			Columns: []string{alert.LabelsColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: label.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_spec.Edges = append(_spec.Edges, edge)
+	}
 	return _node, _spec
 }
 
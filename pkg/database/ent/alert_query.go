@@ -12,6 +12,7 @@ import (
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/alert"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/decision"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/event"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/label"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/machine"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/meta"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/predicate"
@@ -33,6 +34,7 @@ type AlertQuery struct {
 	withDecisions *DecisionQuery
 	withEvents    *EventQuery
 	withMetas     *MetaQuery
+	withLabels    *LabelQuery
 	withFKs       bool
 	// intermediate query (i.e. traversal path).
 	sql  *sql.Selector
@@ -151,6 +153,28 @@ func (aq *AlertQuery) QueryMetas() *MetaQuery {
 	return query
 }
 
+// QueryLabels chains the current query on the labels edge.
+func (aq *AlertQuery) QueryLabels() *LabelQuery {
+	query := &LabelQuery{config: aq.config}
+	query.path = func(ctx context.Context) (fromU *sql.Selector, err error) {
+		if err := aq.prepareQuery(ctx); err != nil {
+			return nil, err
+		}
+		selector := aq.sqlQuery()
+		if err := selector.Err(); err != nil {
+			return nil, err
+		}
+		step := sqlgraph.NewStep(
+			sqlgraph.From(alert.Table, alert.FieldID, selector),
+			sqlgraph.To(label.Table, label.FieldID),
+			sqlgraph.Edge(sqlgraph.O2M, false, alert.LabelsTable, alert.LabelsColumn),
+		)
+		fromU = sqlgraph.SetNeighbors(aq.driver.Dialect(), step)
+		return fromU, nil
+	}
+	return query
+}
+
 // First returns the first Alert entity in the query. Returns *NotFoundError when no alert was found.
 func (aq *AlertQuery) First(ctx context.Context) (*Alert, error) {
 	nodes, err := aq.Limit(1).All(ctx)
@@ -374,6 +398,18 @@ func (aq *AlertQuery) WithMetas(opts ...func(*MetaQuery)) *AlertQuery {
 	return aq
 }
 
+//	WithLabels tells the query-builder to eager-loads the nodes that are connected to
+//
+// the "labels" edge. The optional arguments used to configure the query builder of the edge.
+func (aq *AlertQuery) WithLabels(opts ...func(*LabelQuery)) *AlertQuery {
+	query := &LabelQuery{config: aq.config}
+	for _, opt := range opts {
+		opt(query)
+	}
+	aq.withLabels = query
+	return aq
+}
+
 // GroupBy used to group vertices by one or more fields/columns.
 // It is often used with aggregate functions, like: count, max, mean, min, sum.
 //
@@ -441,11 +477,12 @@ func (aq *AlertQuery) sqlAll(ctx context.Context) ([]*Alert, error) {
 		nodes       = []*Alert{}
 		withFKs     = aq.withFKs
 		_spec       = aq.querySpec()
-		loadedTypes = [4]bool{
+		loadedTypes = [5]bool{
 			aq.withOwner != nil,
 			aq.withDecisions != nil,
 			aq.withEvents != nil,
 			aq.withMetas != nil,
+			aq.withLabels != nil,
 		}
 	)
 	if aq.withOwner != nil {
@@ -587,6 +624,35 @@ func (aq *AlertQuery) sqlAll(ctx context.Context) ([]*Alert, error) {
 		}
 	}
 
+	if query := aq.withLabels; query != nil {
+		fks := make([]driver.Value, 0, len(nodes))
+		nodeids := make(map[int]*Alert)
+		for i := range nodes {
+			fks = append(fks, nodes[i].ID)
+			nodeids[nodes[i].ID] = nodes[i]
+			nodes[i].Edges.Labels = []*Label{}
+		}
+		query.withFKs = true
+		query.Where(predicate.Label(func(s *sql.Selector) {
+			s.Where(sql.InValues(alert.LabelsColumn, fks...))
+		}))
+		neighbors, err := query.All(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range neighbors {
+			fk := n.alert_labels
+			if fk == nil {
+				return nil, fmt.Errorf(`foreign-key "alert_labels" is nil for node %v`, n.ID)
+			}
+			node, ok := nodeids[*fk]
+			if !ok {
+				return nil, fmt.Errorf(`unexpected foreign-key "alert_labels" returned %v for node %v`, *fk, n.ID)
+			}
+			node.Edges.Labels = append(node.Edges.Labels, n)
+		}
+	}
+
 	return nodes, nil
 }
 
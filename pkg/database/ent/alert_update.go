@@ -10,6 +10,7 @@ import (
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/alert"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/decision"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/event"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/label"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/machine"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/meta"
 	"github.com/crowdsecurity/crowdsec/pkg/database/ent/predicate"
@@ -532,6 +533,21 @@ func (au *AlertUpdate) AddMetas(m ...*Meta) *AlertUpdate {
 	return au.AddMetaIDs(ids...)
 }
 
+// AddLabelIDs adds the labels edge to Label by ids.
+func (au *AlertUpdate) AddLabelIDs(ids ...int) *AlertUpdate {
+	au.mutation.AddLabelIDs(ids...)
+	return au
+}
+
+// AddLabels adds the labels edges to Label.
+func (au *AlertUpdate) AddLabels(l ...*Label) *AlertUpdate {
+	ids := make([]int, len(l))
+	for i := range l {
+		ids[i] = l[i].ID
+	}
+	return au.AddLabelIDs(ids...)
+}
+
 // Mutation returns the AlertMutation object of the builder.
 func (au *AlertUpdate) Mutation() *AlertMutation {
 	return au.mutation
@@ -606,6 +622,27 @@ func (au *AlertUpdate) RemoveMetas(m ...*Meta) *AlertUpdate {
 	return au.RemoveMetaIDs(ids...)
 }
 
+// ClearLabels clears all "labels" edges to type Label.
+func (au *AlertUpdate) ClearLabels() *AlertUpdate {
+	au.mutation.ClearLabels()
+	return au
+}
+
+// RemoveLabelIDs removes the labels edge to Label by ids.
+func (au *AlertUpdate) RemoveLabelIDs(ids ...int) *AlertUpdate {
+	au.mutation.RemoveLabelIDs(ids...)
+	return au
+}
+
+// RemoveLabels removes labels edges to Label.
+func (au *AlertUpdate) RemoveLabels(l ...*Label) *AlertUpdate {
+	ids := make([]int, len(l))
+	for i := range l {
+		ids[i] = l[i].ID
+	}
+	return au.RemoveLabelIDs(ids...)
+}
+
 // Save executes the query and returns the number of rows/vertices matched by this operation.
 func (au *AlertUpdate) Save(ctx context.Context) (int, error) {
 	var (
@@ -1162,6 +1199,60 @@ func (au *AlertUpdate) sqlSave(ctx context.Context) (n int, err error) {
 		}
 		_spec.Edges.Add = append(_spec.Edges.Add, edge)
 	}
+	if au.mutation.LabelsCleared() {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.O2M,
+			Inverse: false,
+			Table:   alert.LabelsTable,
+			Columns: []string{alert.LabelsColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: label.FieldID,
+				},
+			},
+		}
+		_spec.Edges.Clear = append(_spec.Edges.Clear, edge)
+	}
+	if nodes := au.mutation.RemovedLabelsIDs(); len(nodes) > 0 && !au.mutation.LabelsCleared() {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.O2M,
+			Inverse: false,
+			Table:   alert.LabelsTable,
+			Columns: []string{alert.LabelsColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: label.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_spec.Edges.Clear = append(_spec.Edges.Clear, edge)
+	}
+	if nodes := au.mutation.LabelsIDs(); len(nodes) > 0 {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.O2M,
+			Inverse: false,
+			Table:   alert.LabelsTable,
+			Columns: []string{alert.LabelsColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: label.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_spec.Edges.Add = append(_spec.Edges.Add, edge)
+	}
 	if n, err = sqlgraph.UpdateNodes(ctx, au.driver, _spec); err != nil {
 		if _, ok := err.(*sqlgraph.NotFoundError); ok {
 			err = &NotFoundError{alert.Label}
@@ -1680,6 +1771,21 @@ func (auo *AlertUpdateOne) AddMetas(m ...*Meta) *AlertUpdateOne {
 	return auo.AddMetaIDs(ids...)
 }
 
+// AddLabelIDs adds the labels edge to Label by ids.
+func (auo *AlertUpdateOne) AddLabelIDs(ids ...int) *AlertUpdateOne {
+	auo.mutation.AddLabelIDs(ids...)
+	return auo
+}
+
+// AddLabels adds the labels edges to Label.
+func (auo *AlertUpdateOne) AddLabels(l ...*Label) *AlertUpdateOne {
+	ids := make([]int, len(l))
+	for i := range l {
+		ids[i] = l[i].ID
+	}
+	return auo.AddLabelIDs(ids...)
+}
+
 // Mutation returns the AlertMutation object of the builder.
 func (auo *AlertUpdateOne) Mutation() *AlertMutation {
 	return auo.mutation
@@ -1754,6 +1860,27 @@ func (auo *AlertUpdateOne) RemoveMetas(m ...*Meta) *AlertUpdateOne {
 	return auo.RemoveMetaIDs(ids...)
 }
 
+// ClearLabels clears all "labels" edges to type Label.
+func (auo *AlertUpdateOne) ClearLabels() *AlertUpdateOne {
+	auo.mutation.ClearLabels()
+	return auo
+}
+
+// RemoveLabelIDs removes the labels edge to Label by ids.
+func (auo *AlertUpdateOne) RemoveLabelIDs(ids ...int) *AlertUpdateOne {
+	auo.mutation.RemoveLabelIDs(ids...)
+	return auo
+}
+
+// RemoveLabels removes labels edges to Label.
+func (auo *AlertUpdateOne) RemoveLabels(l ...*Label) *AlertUpdateOne {
+	ids := make([]int, len(l))
+	for i := range l {
+		ids[i] = l[i].ID
+	}
+	return auo.RemoveLabelIDs(ids...)
+}
+
 // Save executes the query and returns the updated entity.
 func (auo *AlertUpdateOne) Save(ctx context.Context) (*Alert, error) {
 	var (
@@ -2308,6 +2435,60 @@ func (auo *AlertUpdateOne) sqlSave(ctx context.Context) (_node *Alert, err error
 		}
 		_spec.Edges.Add = append(_spec.Edges.Add, edge)
 	}
+	if auo.mutation.LabelsCleared() {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.O2M,
+			Inverse: false,
+			Table:   alert.LabelsTable,
+			Columns: []string{alert.LabelsColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: label.FieldID,
+				},
+			},
+		}
+		_spec.Edges.Clear = append(_spec.Edges.Clear, edge)
+	}
+	if nodes := auo.mutation.RemovedLabelsIDs(); len(nodes) > 0 && !auo.mutation.LabelsCleared() {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.O2M,
+			Inverse: false,
+			Table:   alert.LabelsTable,
+			Columns: []string{alert.LabelsColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: label.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_spec.Edges.Clear = append(_spec.Edges.Clear, edge)
+	}
+	if nodes := auo.mutation.LabelsIDs(); len(nodes) > 0 {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.O2M,
+			Inverse: false,
+			Table:   alert.LabelsTable,
+			Columns: []string{alert.LabelsColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: label.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_spec.Edges.Add = append(_spec.Edges.Add, edge)
+	}
 	_node = &Alert{config: auo.config}
 	_spec.Assign = _node.assignValues
 	_spec.ScanValues = _node.scanValues()
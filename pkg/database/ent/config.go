@@ -30,6 +30,7 @@ type hooks struct {
 	Event    []ent.Hook
 	Machine  []ent.Hook
 	Meta     []ent.Hook
+	Label    []ent.Hook
 }
 
 // Options applies the options on the config object.
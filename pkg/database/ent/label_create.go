@@ -0,0 +1,310 @@
+// Code generated by entc, DO NOT EDIT.
+
+package ent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/alert"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/label"
+	"github.com/facebook/ent/dialect/sql/sqlgraph"
+	"github.com/facebook/ent/schema/field"
+)
+
+// LabelCreate is the builder for creating a Label entity.
+type LabelCreate struct {
+	config
+	mutation *LabelMutation
+	hooks    []Hook
+}
+
+// SetCreatedAt sets the created_at field.
+func (mc *LabelCreate) SetCreatedAt(t time.Time) *LabelCreate {
+	mc.mutation.SetCreatedAt(t)
+	return mc
+}
+
+// SetNillableCreatedAt sets the created_at field if the given value is not nil.
+func (mc *LabelCreate) SetNillableCreatedAt(t *time.Time) *LabelCreate {
+	if t != nil {
+		mc.SetCreatedAt(*t)
+	}
+	return mc
+}
+
+// SetUpdatedAt sets the updated_at field.
+func (mc *LabelCreate) SetUpdatedAt(t time.Time) *LabelCreate {
+	mc.mutation.SetUpdatedAt(t)
+	return mc
+}
+
+// SetNillableUpdatedAt sets the updated_at field if the given value is not nil.
+func (mc *LabelCreate) SetNillableUpdatedAt(t *time.Time) *LabelCreate {
+	if t != nil {
+		mc.SetUpdatedAt(*t)
+	}
+	return mc
+}
+
+// SetKey sets the key field.
+func (mc *LabelCreate) SetKey(s string) *LabelCreate {
+	mc.mutation.SetKey(s)
+	return mc
+}
+
+// SetValue sets the value field.
+func (mc *LabelCreate) SetValue(s string) *LabelCreate {
+	mc.mutation.SetValue(s)
+	return mc
+}
+
+// SetOwnerID sets the owner edge to Alert by id.
+func (mc *LabelCreate) SetOwnerID(id int) *LabelCreate {
+	mc.mutation.SetOwnerID(id)
+	return mc
+}
+
+// SetNillableOwnerID sets the owner edge to Alert by id if the given value is not nil.
+func (mc *LabelCreate) SetNillableOwnerID(id *int) *LabelCreate {
+	if id != nil {
+		mc = mc.SetOwnerID(*id)
+	}
+	return mc
+}
+
+// SetOwner sets the owner edge to Alert.
+func (mc *LabelCreate) SetOwner(a *Alert) *LabelCreate {
+	return mc.SetOwnerID(a.ID)
+}
+
+// Mutation returns the LabelMutation object of the builder.
+func (mc *LabelCreate) Mutation() *LabelMutation {
+	return mc.mutation
+}
+
+// Save creates the Label in the database.
+func (mc *LabelCreate) Save(ctx context.Context) (*Label, error) {
+	var (
+		err  error
+		node *Label
+	)
+	mc.defaults()
+	if len(mc.hooks) == 0 {
+		if err = mc.check(); err != nil {
+			return nil, err
+		}
+		node, err = mc.sqlSave(ctx)
+	} else {
+		var mut Mutator = MutateFunc(func(ctx context.Context, m Mutation) (Value, error) {
+			mutation, ok := m.(*LabelMutation)
+			if !ok {
+				return nil, fmt.Errorf("unexpected mutation type %T", m)
+			}
+			if err = mc.check(); err != nil {
+				return nil, err
+			}
+			mc.mutation = mutation
+			node, err = mc.sqlSave(ctx)
+			mutation.done = true
+			return node, err
+		})
+		for i := len(mc.hooks) - 1; i >= 0; i-- {
+			mut = mc.hooks[i](mut)
+		}
+		if _, err := mut.Mutate(ctx, mc.mutation); err != nil {
+			return nil, err
+		}
+	}
+	return node, err
+}
+
+// SaveX calls Save and panics if Save returns an error.
+func (mc *LabelCreate) SaveX(ctx context.Context) *Label {
+	v, err := mc.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// defaults sets the default values of the builder before save.
+func (mc *LabelCreate) defaults() {
+	if _, ok := mc.mutation.CreatedAt(); !ok {
+		v := label.DefaultCreatedAt()
+		mc.mutation.SetCreatedAt(v)
+	}
+	if _, ok := mc.mutation.UpdatedAt(); !ok {
+		v := label.DefaultUpdatedAt()
+		mc.mutation.SetUpdatedAt(v)
+	}
+}
+
+// check runs all checks and user-defined validators on the builder.
+func (mc *LabelCreate) check() error {
+	if _, ok := mc.mutation.CreatedAt(); !ok {
+		return &ValidationError{Name: "created_at", err: errors.New("ent: missing required field \"created_at\"")}
+	}
+	if _, ok := mc.mutation.UpdatedAt(); !ok {
+		return &ValidationError{Name: "updated_at", err: errors.New("ent: missing required field \"updated_at\"")}
+	}
+	if _, ok := mc.mutation.Key(); !ok {
+		return &ValidationError{Name: "key", err: errors.New("ent: missing required field \"key\"")}
+	}
+	if _, ok := mc.mutation.Value(); !ok {
+		return &ValidationError{Name: "value", err: errors.New("ent: missing required field \"value\"")}
+	}
+	if v, ok := mc.mutation.Value(); ok {
+		if err := label.ValueValidator(v); err != nil {
+			return &ValidationError{Name: "value", err: fmt.Errorf("ent: validator failed for field \"value\": %w", err)}
+		}
+	}
+	return nil
+}
+
+func (mc *LabelCreate) sqlSave(ctx context.Context) (*Label, error) {
+	_node, _spec := mc.createSpec()
+	if err := sqlgraph.CreateNode(ctx, mc.driver, _spec); err != nil {
+		if cerr, ok := isSQLConstraintError(err); ok {
+			err = cerr
+		}
+		return nil, err
+	}
+	id := _spec.ID.Value.(int64)
+	_node.ID = int(id)
+	return _node, nil
+}
+
+func (mc *LabelCreate) createSpec() (*Label, *sqlgraph.CreateSpec) {
+	var (
+		_node = &Label{config: mc.config}
+		_spec = &sqlgraph.CreateSpec{
+			Table: label.Table,
+			ID: &sqlgraph.FieldSpec{
+				Type:   field.TypeInt,
+				Column: label.FieldID,
+			},
+		}
+	)
+	if value, ok := mc.mutation.CreatedAt(); ok {
+		_spec.Fields = append(_spec.Fields, &sqlgraph.FieldSpec{
+			Type:   field.TypeTime,
+			Value:  value,
+			Column: label.FieldCreatedAt,
+		})
+		_node.CreatedAt = value
+	}
+	if value, ok := mc.mutation.UpdatedAt(); ok {
+		_spec.Fields = append(_spec.Fields, &sqlgraph.FieldSpec{
+			Type:   field.TypeTime,
+			Value:  value,
+			Column: label.FieldUpdatedAt,
+		})
+		_node.UpdatedAt = value
+	}
+	if value, ok := mc.mutation.Key(); ok {
+		_spec.Fields = append(_spec.Fields, &sqlgraph.FieldSpec{
+			Type:   field.TypeString,
+			Value:  value,
+			Column: label.FieldKey,
+		})
+		_node.Key = value
+	}
+	if value, ok := mc.mutation.Value(); ok {
+		_spec.Fields = append(_spec.Fields, &sqlgraph.FieldSpec{
+			Type:   field.TypeString,
+			Value:  value,
+			Column: label.FieldValue,
+		})
+		_node.Value = value
+	}
+	if nodes := mc.mutation.OwnerIDs(); len(nodes) > 0 {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.M2O,
+			Inverse: true,
+			Table:   label.OwnerTable,
+			Columns: []string{label.OwnerColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: alert.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_spec.Edges = append(_spec.Edges, edge)
+	}
+	return _node, _spec
+}
+
+// LabelCreateBulk is the builder for creating a bulk of Label entities.
+type LabelCreateBulk struct {
+	config
+	builders []*LabelCreate
+}
+
+// Save creates the Label entities in the database.
+func (mcb *LabelCreateBulk) Save(ctx context.Context) ([]*Label, error) {
+	specs := make([]*sqlgraph.CreateSpec, len(mcb.builders))
+	nodes := make([]*Label, len(mcb.builders))
+	mutators := make([]Mutator, len(mcb.builders))
+	for i := range mcb.builders {
+		func(i int, root context.Context) {
+			builder := mcb.builders[i]
+			builder.defaults()
+			var mut Mutator = MutateFunc(func(ctx context.Context, m Mutation) (Value, error) {
+				mutation, ok := m.(*LabelMutation)
+				if !ok {
+					return nil, fmt.Errorf("unexpected mutation type %T", m)
+				}
+				if err := builder.check(); err != nil {
+					return nil, err
+				}
+				builder.mutation = mutation
+				nodes[i], specs[i] = builder.createSpec()
+				var err error
+				if i < len(mutators)-1 {
+					_, err = mutators[i+1].Mutate(root, mcb.builders[i+1].mutation)
+				} else {
+					// Invoke the actual operation on the latest mutation in the chain.
+					if err = sqlgraph.BatchCreate(ctx, mcb.driver, &sqlgraph.BatchCreateSpec{Nodes: specs}); err != nil {
+						if cerr, ok := isSQLConstraintError(err); ok {
+							err = cerr
+						}
+					}
+				}
+				mutation.done = true
+				if err != nil {
+					return nil, err
+				}
+				id := specs[i].ID.Value.(int64)
+				nodes[i].ID = int(id)
+				return nodes[i], nil
+			})
+			for i := len(builder.hooks) - 1; i >= 0; i-- {
+				mut = builder.hooks[i](mut)
+			}
+			mutators[i] = mut
+		}(i, ctx)
+	}
+	if len(mutators) > 0 {
+		if _, err := mutators[0].Mutate(ctx, mcb.builders[0].mutation); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// SaveX calls Save and panics if Save returns an error.
+func (mcb *LabelCreateBulk) SaveX(ctx context.Context) []*Label {
+	v, err := mcb.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
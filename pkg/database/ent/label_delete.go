@@ -0,0 +1,109 @@
+// Code generated by entc, DO NOT EDIT.
+
+package ent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/label"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/predicate"
+	"github.com/facebook/ent/dialect/sql"
+	"github.com/facebook/ent/dialect/sql/sqlgraph"
+	"github.com/facebook/ent/schema/field"
+)
+
+// LabelDelete is the builder for deleting a Label entity.
+type LabelDelete struct {
+	config
+	hooks      []Hook
+	mutation   *LabelMutation
+	predicates []predicate.Label
+}
+
+// Where adds a new predicate to the delete builder.
+func (md *LabelDelete) Where(ps ...predicate.Label) *LabelDelete {
+	md.predicates = append(md.predicates, ps...)
+	return md
+}
+
+// Exec executes the deletion query and returns how many vertices were deleted.
+func (md *LabelDelete) Exec(ctx context.Context) (int, error) {
+	var (
+		err      error
+		affected int
+	)
+	if len(md.hooks) == 0 {
+		affected, err = md.sqlExec(ctx)
+	} else {
+		var mut Mutator = MutateFunc(func(ctx context.Context, m Mutation) (Value, error) {
+			mutation, ok := m.(*LabelMutation)
+			if !ok {
+				return nil, fmt.Errorf("unexpected mutation type %T", m)
+			}
+			md.mutation = mutation
+			affected, err = md.sqlExec(ctx)
+			mutation.done = true
+			return affected, err
+		})
+		for i := len(md.hooks) - 1; i >= 0; i-- {
+			mut = md.hooks[i](mut)
+		}
+		if _, err := mut.Mutate(ctx, md.mutation); err != nil {
+			return 0, err
+		}
+	}
+	return affected, err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (md *LabelDelete) ExecX(ctx context.Context) int {
+	n, err := md.Exec(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func (md *LabelDelete) sqlExec(ctx context.Context) (int, error) {
+	_spec := &sqlgraph.DeleteSpec{
+		Node: &sqlgraph.NodeSpec{
+			Table: label.Table,
+			ID: &sqlgraph.FieldSpec{
+				Type:   field.TypeInt,
+				Column: label.FieldID,
+			},
+		},
+	}
+	if ps := md.predicates; len(ps) > 0 {
+		_spec.Predicate = func(selector *sql.Selector) {
+			for i := range ps {
+				ps[i](selector)
+			}
+		}
+	}
+	return sqlgraph.DeleteNodes(ctx, md.driver, _spec)
+}
+
+// LabelDeleteOne is the builder for deleting a single Label entity.
+type LabelDeleteOne struct {
+	md *LabelDelete
+}
+
+// Exec executes the deletion query.
+func (mdo *LabelDeleteOne) Exec(ctx context.Context) error {
+	n, err := mdo.md.Exec(ctx)
+	switch {
+	case err != nil:
+		return err
+	case n == 0:
+		return &NotFoundError{label.Label}
+	default:
+		return nil
+	}
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (mdo *LabelDeleteOne) ExecX(ctx context.Context) {
+	mdo.md.ExecX(ctx)
+}
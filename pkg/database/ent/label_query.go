@@ -0,0 +1,957 @@
+// Code generated by entc, DO NOT EDIT.
+
+package ent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/alert"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/label"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/predicate"
+	"github.com/facebook/ent/dialect/sql"
+	"github.com/facebook/ent/dialect/sql/sqlgraph"
+	"github.com/facebook/ent/schema/field"
+)
+
+// LabelQuery is the builder for querying Label entities.
+type LabelQuery struct {
+	config
+	limit      *int
+	offset     *int
+	order      []OrderFunc
+	unique     []string
+	predicates []predicate.Label
+	// eager-loading edges.
+	withOwner *AlertQuery
+	withFKs   bool
+	// intermediate query (i.e. traversal path).
+	sql  *sql.Selector
+	path func(context.Context) (*sql.Selector, error)
+}
+
+// Where adds a new predicate for the builder.
+func (mq *LabelQuery) Where(ps ...predicate.Label) *LabelQuery {
+	mq.predicates = append(mq.predicates, ps...)
+	return mq
+}
+
+// Limit adds a limit step to the query.
+func (mq *LabelQuery) Limit(limit int) *LabelQuery {
+	mq.limit = &limit
+	return mq
+}
+
+// Offset adds an offset step to the query.
+func (mq *LabelQuery) Offset(offset int) *LabelQuery {
+	mq.offset = &offset
+	return mq
+}
+
+// Order adds an order step to the query.
+func (mq *LabelQuery) Order(o ...OrderFunc) *LabelQuery {
+	mq.order = append(mq.order, o...)
+	return mq
+}
+
+// QueryOwner chains the current query on the owner edge.
+func (mq *LabelQuery) QueryOwner() *AlertQuery {
+	query := &AlertQuery{config: mq.config}
+	query.path = func(ctx context.Context) (fromU *sql.Selector, err error) {
+		if err := mq.prepareQuery(ctx); err != nil {
+			return nil, err
+		}
+		selector := mq.sqlQuery()
+		if err := selector.Err(); err != nil {
+			return nil, err
+		}
+		step := sqlgraph.NewStep(
+			sqlgraph.From(label.Table, label.FieldID, selector),
+			sqlgraph.To(alert.Table, alert.FieldID),
+			sqlgraph.Edge(sqlgraph.M2O, true, label.OwnerTable, label.OwnerColumn),
+		)
+		fromU = sqlgraph.SetNeighbors(mq.driver.Dialect(), step)
+		return fromU, nil
+	}
+	return query
+}
+
+// First returns the first Label entity in the query. Returns *NotFoundError when no label was found.
+func (mq *LabelQuery) First(ctx context.Context) (*Label, error) {
+	nodes, err := mq.Limit(1).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, &NotFoundError{label.Label}
+	}
+	return nodes[0], nil
+}
+
+// FirstX is like First, but panics if an error occurs.
+func (mq *LabelQuery) FirstX(ctx context.Context) *Label {
+	node, err := mq.First(ctx)
+	if err != nil && !IsNotFound(err) {
+		panic(err)
+	}
+	return node
+}
+
+// FirstID returns the first Label id in the query. Returns *NotFoundError when no id was found.
+func (mq *LabelQuery) FirstID(ctx context.Context) (id int, err error) {
+	var ids []int
+	if ids, err = mq.Limit(1).IDs(ctx); err != nil {
+		return
+	}
+	if len(ids) == 0 {
+		err = &NotFoundError{label.Label}
+		return
+	}
+	return ids[0], nil
+}
+
+// FirstXID is like FirstID, but panics if an error occurs.
+func (mq *LabelQuery) FirstXID(ctx context.Context) int {
+	id, err := mq.FirstID(ctx)
+	if err != nil && !IsNotFound(err) {
+		panic(err)
+	}
+	return id
+}
+
+// Only returns the only Label entity in the query, returns an error if not exactly one entity was returned.
+func (mq *LabelQuery) Only(ctx context.Context) (*Label, error) {
+	nodes, err := mq.Limit(2).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch len(nodes) {
+	case 1:
+		return nodes[0], nil
+	case 0:
+		return nil, &NotFoundError{label.Label}
+	default:
+		return nil, &NotSingularError{label.Label}
+	}
+}
+
+// OnlyX is like Only, but panics if an error occurs.
+func (mq *LabelQuery) OnlyX(ctx context.Context) *Label {
+	node, err := mq.Only(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return node
+}
+
+// OnlyID returns the only Label id in the query, returns an error if not exactly one id was returned.
+func (mq *LabelQuery) OnlyID(ctx context.Context) (id int, err error) {
+	var ids []int
+	if ids, err = mq.Limit(2).IDs(ctx); err != nil {
+		return
+	}
+	switch len(ids) {
+	case 1:
+		id = ids[0]
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = &NotSingularError{label.Label}
+	}
+	return
+}
+
+// OnlyIDX is like OnlyID, but panics if an error occurs.
+func (mq *LabelQuery) OnlyIDX(ctx context.Context) int {
+	id, err := mq.OnlyID(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// All executes the query and returns a list of LabelSlice.
+func (mq *LabelQuery) All(ctx context.Context) ([]*Label, error) {
+	if err := mq.prepareQuery(ctx); err != nil {
+		return nil, err
+	}
+	return mq.sqlAll(ctx)
+}
+
+// AllX is like All, but panics if an error occurs.
+func (mq *LabelQuery) AllX(ctx context.Context) []*Label {
+	nodes, err := mq.All(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return nodes
+}
+
+// IDs executes the query and returns a list of Label ids.
+func (mq *LabelQuery) IDs(ctx context.Context) ([]int, error) {
+	var ids []int
+	if err := mq.Select(label.FieldID).Scan(ctx, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// IDsX is like IDs, but panics if an error occurs.
+func (mq *LabelQuery) IDsX(ctx context.Context) []int {
+	ids, err := mq.IDs(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return ids
+}
+
+// Count returns the count of the given query.
+func (mq *LabelQuery) Count(ctx context.Context) (int, error) {
+	if err := mq.prepareQuery(ctx); err != nil {
+		return 0, err
+	}
+	return mq.sqlCount(ctx)
+}
+
+// CountX is like Count, but panics if an error occurs.
+func (mq *LabelQuery) CountX(ctx context.Context) int {
+	count, err := mq.Count(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// Exist returns true if the query has elements in the graph.
+func (mq *LabelQuery) Exist(ctx context.Context) (bool, error) {
+	if err := mq.prepareQuery(ctx); err != nil {
+		return false, err
+	}
+	return mq.sqlExist(ctx)
+}
+
+// ExistX is like Exist, but panics if an error occurs.
+func (mq *LabelQuery) ExistX(ctx context.Context) bool {
+	exist, err := mq.Exist(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return exist
+}
+
+// Clone returns a duplicate of the query builder, including all associated steps. It can be
+// used to prepare common query builders and use them differently after the clone is made.
+func (mq *LabelQuery) Clone() *LabelQuery {
+	return &LabelQuery{
+		config:     mq.config,
+		limit:      mq.limit,
+		offset:     mq.offset,
+		order:      append([]OrderFunc{}, mq.order...),
+		unique:     append([]string{}, mq.unique...),
+		predicates: append([]predicate.Label{}, mq.predicates...),
+		// clone intermediate query.
+		sql:  mq.sql.Clone(),
+		path: mq.path,
+	}
+}
+
+//  WithOwner tells the query-builder to eager-loads the nodes that are connected to
+// the "owner" edge. The optional arguments used to configure the query builder of the edge.
+func (mq *LabelQuery) WithOwner(opts ...func(*AlertQuery)) *LabelQuery {
+	query := &AlertQuery{config: mq.config}
+	for _, opt := range opts {
+		opt(query)
+	}
+	mq.withOwner = query
+	return mq
+}
+
+// GroupBy used to group vertices by one or more fields/columns.
+// It is often used with aggregate functions, like: count, max, mean, min, sum.
+//
+// Example:
+//
+//	var v []struct {
+//		CreatedAt time.Time `json:"created_at,omitempty"`
+//		Count int `json:"count,omitempty"`
+//	}
+//
+//	client.Label.Query().
+//		GroupBy(label.FieldCreatedAt).
+//		Aggregate(ent.Count()).
+//		Scan(ctx, &v)
+//
+func (mq *LabelQuery) GroupBy(field string, fields ...string) *LabelGroupBy {
+	group := &LabelGroupBy{config: mq.config}
+	group.fields = append([]string{field}, fields...)
+	group.path = func(ctx context.Context) (prev *sql.Selector, err error) {
+		if err := mq.prepareQuery(ctx); err != nil {
+			return nil, err
+		}
+		return mq.sqlQuery(), nil
+	}
+	return group
+}
+
+// Select one or more fields from the given query.
+//
+// Example:
+//
+//	var v []struct {
+//		CreatedAt time.Time `json:"created_at,omitempty"`
+//	}
+//
+//	client.Label.Query().
+//		Select(label.FieldCreatedAt).
+//		Scan(ctx, &v)
+//
+func (mq *LabelQuery) Select(field string, fields ...string) *LabelSelect {
+	selector := &LabelSelect{config: mq.config}
+	selector.fields = append([]string{field}, fields...)
+	selector.path = func(ctx context.Context) (prev *sql.Selector, err error) {
+		if err := mq.prepareQuery(ctx); err != nil {
+			return nil, err
+		}
+		return mq.sqlQuery(), nil
+	}
+	return selector
+}
+
+func (mq *LabelQuery) prepareQuery(ctx context.Context) error {
+	if mq.path != nil {
+		prev, err := mq.path(ctx)
+		if err != nil {
+			return err
+		}
+		mq.sql = prev
+	}
+	return nil
+}
+
+func (mq *LabelQuery) sqlAll(ctx context.Context) ([]*Label, error) {
+	var (
+		nodes       = []*Label{}
+		withFKs     = mq.withFKs
+		_spec       = mq.querySpec()
+		loadedTypes = [1]bool{
+			mq.withOwner != nil,
+		}
+	)
+	if mq.withOwner != nil {
+		withFKs = true
+	}
+	if withFKs {
+		_spec.Node.Columns = append(_spec.Node.Columns, label.ForeignKeys...)
+	}
+	_spec.ScanValues = func() []interface{} {
+		node := &Label{config: mq.config}
+		nodes = append(nodes, node)
+		values := node.scanValues()
+		if withFKs {
+			values = append(values, node.fkValues()...)
+		}
+		return values
+	}
+	_spec.Assign = func(values ...interface{}) error {
+		if len(nodes) == 0 {
+			return fmt.Errorf("ent: Assign called without calling ScanValues")
+		}
+		node := nodes[len(nodes)-1]
+		node.Edges.loadedTypes = loadedTypes
+		return node.assignValues(values...)
+	}
+	if err := sqlgraph.QueryNodes(ctx, mq.driver, _spec); err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nodes, nil
+	}
+
+	if query := mq.withOwner; query != nil {
+		ids := make([]int, 0, len(nodes))
+		nodeids := make(map[int][]*Label)
+		for i := range nodes {
+			if fk := nodes[i].alert_labels; fk != nil {
+				ids = append(ids, *fk)
+				nodeids[*fk] = append(nodeids[*fk], nodes[i])
+			}
+		}
+		query.Where(alert.IDIn(ids...))
+		neighbors, err := query.All(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range neighbors {
+			nodes, ok := nodeids[n.ID]
+			if !ok {
+				return nil, fmt.Errorf(`unexpected foreign-key "alert_labels" returned %v`, n.ID)
+			}
+			for i := range nodes {
+				nodes[i].Edges.Owner = n
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+func (mq *LabelQuery) sqlCount(ctx context.Context) (int, error) {
+	_spec := mq.querySpec()
+	return sqlgraph.CountNodes(ctx, mq.driver, _spec)
+}
+
+func (mq *LabelQuery) sqlExist(ctx context.Context) (bool, error) {
+	n, err := mq.sqlCount(ctx)
+	if err != nil {
+		return false, fmt.Errorf("ent: check existence: %v", err)
+	}
+	return n > 0, nil
+}
+
+func (mq *LabelQuery) querySpec() *sqlgraph.QuerySpec {
+	_spec := &sqlgraph.QuerySpec{
+		Node: &sqlgraph.NodeSpec{
+			Table:   label.Table,
+			Columns: label.Columns,
+			ID: &sqlgraph.FieldSpec{
+				Type:   field.TypeInt,
+				Column: label.FieldID,
+			},
+		},
+		From:   mq.sql,
+		Unique: true,
+	}
+	if ps := mq.predicates; len(ps) > 0 {
+		_spec.Predicate = func(selector *sql.Selector) {
+			for i := range ps {
+				ps[i](selector)
+			}
+		}
+	}
+	if limit := mq.limit; limit != nil {
+		_spec.Limit = *limit
+	}
+	if offset := mq.offset; offset != nil {
+		_spec.Offset = *offset
+	}
+	if ps := mq.order; len(ps) > 0 {
+		_spec.Order = func(selector *sql.Selector) {
+			for i := range ps {
+				ps[i](selector, label.ValidColumn)
+			}
+		}
+	}
+	return _spec
+}
+
+func (mq *LabelQuery) sqlQuery() *sql.Selector {
+	builder := sql.Dialect(mq.driver.Dialect())
+	t1 := builder.Table(label.Table)
+	selector := builder.Select(t1.Columns(label.Columns...)...).From(t1)
+	if mq.sql != nil {
+		selector = mq.sql
+		selector.Select(selector.Columns(label.Columns...)...)
+	}
+	for _, p := range mq.predicates {
+		p(selector)
+	}
+	for _, p := range mq.order {
+		p(selector, label.ValidColumn)
+	}
+	if offset := mq.offset; offset != nil {
+		// limit is mandatory for offset clause. We start
+		// with default value, and override it below if needed.
+		selector.Offset(*offset).Limit(math.MaxInt32)
+	}
+	if limit := mq.limit; limit != nil {
+		selector.Limit(*limit)
+	}
+	return selector
+}
+
+// LabelGroupBy is the builder for group-by Label entities.
+type LabelGroupBy struct {
+	config
+	fields []string
+	fns    []AggregateFunc
+	// intermediate query (i.e. traversal path).
+	sql  *sql.Selector
+	path func(context.Context) (*sql.Selector, error)
+}
+
+// Aggregate adds the given aggregation functions to the group-by query.
+func (mgb *LabelGroupBy) Aggregate(fns ...AggregateFunc) *LabelGroupBy {
+	mgb.fns = append(mgb.fns, fns...)
+	return mgb
+}
+
+// Scan applies the group-by query and scan the result into the given value.
+func (mgb *LabelGroupBy) Scan(ctx context.Context, v interface{}) error {
+	query, err := mgb.path(ctx)
+	if err != nil {
+		return err
+	}
+	mgb.sql = query
+	return mgb.sqlScan(ctx, v)
+}
+
+// ScanX is like Scan, but panics if an error occurs.
+func (mgb *LabelGroupBy) ScanX(ctx context.Context, v interface{}) {
+	if err := mgb.Scan(ctx, v); err != nil {
+		panic(err)
+	}
+}
+
+// Strings returns list of strings from group-by. It is only allowed when querying group-by with one field.
+func (mgb *LabelGroupBy) Strings(ctx context.Context) ([]string, error) {
+	if len(mgb.fields) > 1 {
+		return nil, errors.New("ent: LabelGroupBy.Strings is not achievable when grouping more than 1 field")
+	}
+	var v []string
+	if err := mgb.Scan(ctx, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// StringsX is like Strings, but panics if an error occurs.
+func (mgb *LabelGroupBy) StringsX(ctx context.Context) []string {
+	v, err := mgb.Strings(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String returns a single string from group-by. It is only allowed when querying group-by with one field.
+func (mgb *LabelGroupBy) String(ctx context.Context) (_ string, err error) {
+	var v []string
+	if v, err = mgb.Strings(ctx); err != nil {
+		return
+	}
+	switch len(v) {
+	case 1:
+		return v[0], nil
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = fmt.Errorf("ent: LabelGroupBy.Strings returned %d results when one was expected", len(v))
+	}
+	return
+}
+
+// StringX is like String, but panics if an error occurs.
+func (mgb *LabelGroupBy) StringX(ctx context.Context) string {
+	v, err := mgb.String(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Ints returns list of ints from group-by. It is only allowed when querying group-by with one field.
+func (mgb *LabelGroupBy) Ints(ctx context.Context) ([]int, error) {
+	if len(mgb.fields) > 1 {
+		return nil, errors.New("ent: LabelGroupBy.Ints is not achievable when grouping more than 1 field")
+	}
+	var v []int
+	if err := mgb.Scan(ctx, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// IntsX is like Ints, but panics if an error occurs.
+func (mgb *LabelGroupBy) IntsX(ctx context.Context) []int {
+	v, err := mgb.Ints(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Int returns a single int from group-by. It is only allowed when querying group-by with one field.
+func (mgb *LabelGroupBy) Int(ctx context.Context) (_ int, err error) {
+	var v []int
+	if v, err = mgb.Ints(ctx); err != nil {
+		return
+	}
+	switch len(v) {
+	case 1:
+		return v[0], nil
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = fmt.Errorf("ent: LabelGroupBy.Ints returned %d results when one was expected", len(v))
+	}
+	return
+}
+
+// IntX is like Int, but panics if an error occurs.
+func (mgb *LabelGroupBy) IntX(ctx context.Context) int {
+	v, err := mgb.Int(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Float64s returns list of float64s from group-by. It is only allowed when querying group-by with one field.
+func (mgb *LabelGroupBy) Float64s(ctx context.Context) ([]float64, error) {
+	if len(mgb.fields) > 1 {
+		return nil, errors.New("ent: LabelGroupBy.Float64s is not achievable when grouping more than 1 field")
+	}
+	var v []float64
+	if err := mgb.Scan(ctx, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Float64sX is like Float64s, but panics if an error occurs.
+func (mgb *LabelGroupBy) Float64sX(ctx context.Context) []float64 {
+	v, err := mgb.Float64s(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Float64 returns a single float64 from group-by. It is only allowed when querying group-by with one field.
+func (mgb *LabelGroupBy) Float64(ctx context.Context) (_ float64, err error) {
+	var v []float64
+	if v, err = mgb.Float64s(ctx); err != nil {
+		return
+	}
+	switch len(v) {
+	case 1:
+		return v[0], nil
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = fmt.Errorf("ent: LabelGroupBy.Float64s returned %d results when one was expected", len(v))
+	}
+	return
+}
+
+// Float64X is like Float64, but panics if an error occurs.
+func (mgb *LabelGroupBy) Float64X(ctx context.Context) float64 {
+	v, err := mgb.Float64(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Bools returns list of bools from group-by. It is only allowed when querying group-by with one field.
+func (mgb *LabelGroupBy) Bools(ctx context.Context) ([]bool, error) {
+	if len(mgb.fields) > 1 {
+		return nil, errors.New("ent: LabelGroupBy.Bools is not achievable when grouping more than 1 field")
+	}
+	var v []bool
+	if err := mgb.Scan(ctx, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// BoolsX is like Bools, but panics if an error occurs.
+func (mgb *LabelGroupBy) BoolsX(ctx context.Context) []bool {
+	v, err := mgb.Bools(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Bool returns a single bool from group-by. It is only allowed when querying group-by with one field.
+func (mgb *LabelGroupBy) Bool(ctx context.Context) (_ bool, err error) {
+	var v []bool
+	if v, err = mgb.Bools(ctx); err != nil {
+		return
+	}
+	switch len(v) {
+	case 1:
+		return v[0], nil
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = fmt.Errorf("ent: LabelGroupBy.Bools returned %d results when one was expected", len(v))
+	}
+	return
+}
+
+// BoolX is like Bool, but panics if an error occurs.
+func (mgb *LabelGroupBy) BoolX(ctx context.Context) bool {
+	v, err := mgb.Bool(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (mgb *LabelGroupBy) sqlScan(ctx context.Context, v interface{}) error {
+	for _, f := range mgb.fields {
+		if !label.ValidColumn(f) {
+			return &ValidationError{Name: f, err: fmt.Errorf("invalid field %q for group-by", f)}
+		}
+	}
+	selector := mgb.sqlQuery()
+	if err := selector.Err(); err != nil {
+		return err
+	}
+	rows := &sql.Rows{}
+	query, args := selector.Query()
+	if err := mgb.driver.Query(ctx, query, args, rows); err != nil {
+		return err
+	}
+	defer rows.Close()
+	return sql.ScanSlice(rows, v)
+}
+
+func (mgb *LabelGroupBy) sqlQuery() *sql.Selector {
+	selector := mgb.sql
+	columns := make([]string, 0, len(mgb.fields)+len(mgb.fns))
+	columns = append(columns, mgb.fields...)
+	for _, fn := range mgb.fns {
+		columns = append(columns, fn(selector, label.ValidColumn))
+	}
+	return selector.Select(columns...).GroupBy(mgb.fields...)
+}
+
+// LabelSelect is the builder for select fields of Label entities.
+type LabelSelect struct {
+	config
+	fields []string
+	// intermediate query (i.e. traversal path).
+	sql  *sql.Selector
+	path func(context.Context) (*sql.Selector, error)
+}
+
+// Scan applies the selector query and scan the result into the given value.
+func (ms *LabelSelect) Scan(ctx context.Context, v interface{}) error {
+	query, err := ms.path(ctx)
+	if err != nil {
+		return err
+	}
+	ms.sql = query
+	return ms.sqlScan(ctx, v)
+}
+
+// ScanX is like Scan, but panics if an error occurs.
+func (ms *LabelSelect) ScanX(ctx context.Context, v interface{}) {
+	if err := ms.Scan(ctx, v); err != nil {
+		panic(err)
+	}
+}
+
+// Strings returns list of strings from selector. It is only allowed when selecting one field.
+func (ms *LabelSelect) Strings(ctx context.Context) ([]string, error) {
+	if len(ms.fields) > 1 {
+		return nil, errors.New("ent: LabelSelect.Strings is not achievable when selecting more than 1 field")
+	}
+	var v []string
+	if err := ms.Scan(ctx, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// StringsX is like Strings, but panics if an error occurs.
+func (ms *LabelSelect) StringsX(ctx context.Context) []string {
+	v, err := ms.Strings(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String returns a single string from selector. It is only allowed when selecting one field.
+func (ms *LabelSelect) String(ctx context.Context) (_ string, err error) {
+	var v []string
+	if v, err = ms.Strings(ctx); err != nil {
+		return
+	}
+	switch len(v) {
+	case 1:
+		return v[0], nil
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = fmt.Errorf("ent: LabelSelect.Strings returned %d results when one was expected", len(v))
+	}
+	return
+}
+
+// StringX is like String, but panics if an error occurs.
+func (ms *LabelSelect) StringX(ctx context.Context) string {
+	v, err := ms.String(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Ints returns list of ints from selector. It is only allowed when selecting one field.
+func (ms *LabelSelect) Ints(ctx context.Context) ([]int, error) {
+	if len(ms.fields) > 1 {
+		return nil, errors.New("ent: LabelSelect.Ints is not achievable when selecting more than 1 field")
+	}
+	var v []int
+	if err := ms.Scan(ctx, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// IntsX is like Ints, but panics if an error occurs.
+func (ms *LabelSelect) IntsX(ctx context.Context) []int {
+	v, err := ms.Ints(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Int returns a single int from selector. It is only allowed when selecting one field.
+func (ms *LabelSelect) Int(ctx context.Context) (_ int, err error) {
+	var v []int
+	if v, err = ms.Ints(ctx); err != nil {
+		return
+	}
+	switch len(v) {
+	case 1:
+		return v[0], nil
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = fmt.Errorf("ent: LabelSelect.Ints returned %d results when one was expected", len(v))
+	}
+	return
+}
+
+// IntX is like Int, but panics if an error occurs.
+func (ms *LabelSelect) IntX(ctx context.Context) int {
+	v, err := ms.Int(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Float64s returns list of float64s from selector. It is only allowed when selecting one field.
+func (ms *LabelSelect) Float64s(ctx context.Context) ([]float64, error) {
+	if len(ms.fields) > 1 {
+		return nil, errors.New("ent: LabelSelect.Float64s is not achievable when selecting more than 1 field")
+	}
+	var v []float64
+	if err := ms.Scan(ctx, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Float64sX is like Float64s, but panics if an error occurs.
+func (ms *LabelSelect) Float64sX(ctx context.Context) []float64 {
+	v, err := ms.Float64s(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Float64 returns a single float64 from selector. It is only allowed when selecting one field.
+func (ms *LabelSelect) Float64(ctx context.Context) (_ float64, err error) {
+	var v []float64
+	if v, err = ms.Float64s(ctx); err != nil {
+		return
+	}
+	switch len(v) {
+	case 1:
+		return v[0], nil
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = fmt.Errorf("ent: LabelSelect.Float64s returned %d results when one was expected", len(v))
+	}
+	return
+}
+
+// Float64X is like Float64, but panics if an error occurs.
+func (ms *LabelSelect) Float64X(ctx context.Context) float64 {
+	v, err := ms.Float64(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Bools returns list of bools from selector. It is only allowed when selecting one field.
+func (ms *LabelSelect) Bools(ctx context.Context) ([]bool, error) {
+	if len(ms.fields) > 1 {
+		return nil, errors.New("ent: LabelSelect.Bools is not achievable when selecting more than 1 field")
+	}
+	var v []bool
+	if err := ms.Scan(ctx, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// BoolsX is like Bools, but panics if an error occurs.
+func (ms *LabelSelect) BoolsX(ctx context.Context) []bool {
+	v, err := ms.Bools(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Bool returns a single bool from selector. It is only allowed when selecting one field.
+func (ms *LabelSelect) Bool(ctx context.Context) (_ bool, err error) {
+	var v []bool
+	if v, err = ms.Bools(ctx); err != nil {
+		return
+	}
+	switch len(v) {
+	case 1:
+		return v[0], nil
+	case 0:
+		err = &NotFoundError{label.Label}
+	default:
+		err = fmt.Errorf("ent: LabelSelect.Bools returned %d results when one was expected", len(v))
+	}
+	return
+}
+
+// BoolX is like Bool, but panics if an error occurs.
+func (ms *LabelSelect) BoolX(ctx context.Context) bool {
+	v, err := ms.Bool(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (ms *LabelSelect) sqlScan(ctx context.Context, v interface{}) error {
+	for _, f := range ms.fields {
+		if !label.ValidColumn(f) {
+			return &ValidationError{Name: f, err: fmt.Errorf("invalid field %q for selection", f)}
+		}
+	}
+	rows := &sql.Rows{}
+	query, args := ms.sqlQuery().Query()
+	if err := ms.driver.Query(ctx, query, args, rows); err != nil {
+		return err
+	}
+	defer rows.Close()
+	return sql.ScanSlice(rows, v)
+}
+
+func (ms *LabelSelect) sqlQuery() sql.Querier {
+	selector := ms.sql
+	selector.Select(selector.Columns(ms.fields...)...)
+	return selector
+}
@@ -0,0 +1,496 @@
+// Code generated by entc, DO NOT EDIT.
+
+package ent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/alert"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/label"
+	"github.com/crowdsecurity/crowdsec/pkg/database/ent/predicate"
+	"github.com/facebook/ent/dialect/sql"
+	"github.com/facebook/ent/dialect/sql/sqlgraph"
+	"github.com/facebook/ent/schema/field"
+)
+
+// LabelUpdate is the builder for updating Label entities.
+type LabelUpdate struct {
+	config
+	hooks      []Hook
+	mutation   *LabelMutation
+	predicates []predicate.Label
+}
+
+// Where adds a new predicate for the builder.
+func (mu *LabelUpdate) Where(ps ...predicate.Label) *LabelUpdate {
+	mu.predicates = append(mu.predicates, ps...)
+	return mu
+}
+
+// SetCreatedAt sets the created_at field.
+func (mu *LabelUpdate) SetCreatedAt(t time.Time) *LabelUpdate {
+	mu.mutation.SetCreatedAt(t)
+	return mu
+}
+
+// SetNillableCreatedAt sets the created_at field if the given value is not nil.
+func (mu *LabelUpdate) SetNillableCreatedAt(t *time.Time) *LabelUpdate {
+	if t != nil {
+		mu.SetCreatedAt(*t)
+	}
+	return mu
+}
+
+// SetUpdatedAt sets the updated_at field.
+func (mu *LabelUpdate) SetUpdatedAt(t time.Time) *LabelUpdate {
+	mu.mutation.SetUpdatedAt(t)
+	return mu
+}
+
+// SetNillableUpdatedAt sets the updated_at field if the given value is not nil.
+func (mu *LabelUpdate) SetNillableUpdatedAt(t *time.Time) *LabelUpdate {
+	if t != nil {
+		mu.SetUpdatedAt(*t)
+	}
+	return mu
+}
+
+// SetKey sets the key field.
+func (mu *LabelUpdate) SetKey(s string) *LabelUpdate {
+	mu.mutation.SetKey(s)
+	return mu
+}
+
+// SetValue sets the value field.
+func (mu *LabelUpdate) SetValue(s string) *LabelUpdate {
+	mu.mutation.SetValue(s)
+	return mu
+}
+
+// SetOwnerID sets the owner edge to Alert by id.
+func (mu *LabelUpdate) SetOwnerID(id int) *LabelUpdate {
+	mu.mutation.SetOwnerID(id)
+	return mu
+}
+
+// SetNillableOwnerID sets the owner edge to Alert by id if the given value is not nil.
+func (mu *LabelUpdate) SetNillableOwnerID(id *int) *LabelUpdate {
+	if id != nil {
+		mu = mu.SetOwnerID(*id)
+	}
+	return mu
+}
+
+// SetOwner sets the owner edge to Alert.
+func (mu *LabelUpdate) SetOwner(a *Alert) *LabelUpdate {
+	return mu.SetOwnerID(a.ID)
+}
+
+// Mutation returns the LabelMutation object of the builder.
+func (mu *LabelUpdate) Mutation() *LabelMutation {
+	return mu.mutation
+}
+
+// ClearOwner clears the "owner" edge to type Alert.
+func (mu *LabelUpdate) ClearOwner() *LabelUpdate {
+	mu.mutation.ClearOwner()
+	return mu
+}
+
+// Save executes the query and returns the number of rows/vertices matched by this operation.
+func (mu *LabelUpdate) Save(ctx context.Context) (int, error) {
+	var (
+		err      error
+		affected int
+	)
+	if len(mu.hooks) == 0 {
+		if err = mu.check(); err != nil {
+			return 0, err
+		}
+		affected, err = mu.sqlSave(ctx)
+	} else {
+		var mut Mutator = MutateFunc(func(ctx context.Context, m Mutation) (Value, error) {
+			mutation, ok := m.(*LabelMutation)
+			if !ok {
+				return nil, fmt.Errorf("unexpected mutation type %T", m)
+			}
+			if err = mu.check(); err != nil {
+				return 0, err
+			}
+			mu.mutation = mutation
+			affected, err = mu.sqlSave(ctx)
+			mutation.done = true
+			return affected, err
+		})
+		for i := len(mu.hooks) - 1; i >= 0; i-- {
+			mut = mu.hooks[i](mut)
+		}
+		if _, err := mut.Mutate(ctx, mu.mutation); err != nil {
+			return 0, err
+		}
+	}
+	return affected, err
+}
+
+// SaveX is like Save, but panics if an error occurs.
+func (mu *LabelUpdate) SaveX(ctx context.Context) int {
+	affected, err := mu.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return affected
+}
+
+// Exec executes the query.
+func (mu *LabelUpdate) Exec(ctx context.Context) error {
+	_, err := mu.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (mu *LabelUpdate) ExecX(ctx context.Context) {
+	if err := mu.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// check runs all checks and user-defined validators on the builder.
+func (mu *LabelUpdate) check() error {
+	if v, ok := mu.mutation.Value(); ok {
+		if err := label.ValueValidator(v); err != nil {
+			return &ValidationError{Name: "value", err: fmt.Errorf("ent: validator failed for field \"value\": %w", err)}
+		}
+	}
+	return nil
+}
+
+func (mu *LabelUpdate) sqlSave(ctx context.Context) (n int, err error) {
+	_spec := &sqlgraph.UpdateSpec{
+		Node: &sqlgraph.NodeSpec{
+			Table:   label.Table,
+			Columns: label.Columns,
+			ID: &sqlgraph.FieldSpec{
+				Type:   field.TypeInt,
+				Column: label.FieldID,
+			},
+		},
+	}
+	if ps := mu.predicates; len(ps) > 0 {
+		_spec.Predicate = func(selector *sql.Selector) {
+			for i := range ps {
+				ps[i](selector)
+			}
+		}
+	}
+	if value, ok := mu.mutation.CreatedAt(); ok {
+		_spec.Fields.Set = append(_spec.Fields.Set, &sqlgraph.FieldSpec{
+			Type:   field.TypeTime,
+			Value:  value,
+			Column: label.FieldCreatedAt,
+		})
+	}
+	if value, ok := mu.mutation.UpdatedAt(); ok {
+		_spec.Fields.Set = append(_spec.Fields.Set, &sqlgraph.FieldSpec{
+			Type:   field.TypeTime,
+			Value:  value,
+			Column: label.FieldUpdatedAt,
+		})
+	}
+	if value, ok := mu.mutation.Key(); ok {
+		_spec.Fields.Set = append(_spec.Fields.Set, &sqlgraph.FieldSpec{
+			Type:   field.TypeString,
+			Value:  value,
+			Column: label.FieldKey,
+		})
+	}
+	if value, ok := mu.mutation.Value(); ok {
+		_spec.Fields.Set = append(_spec.Fields.Set, &sqlgraph.FieldSpec{
+			Type:   field.TypeString,
+			Value:  value,
+			Column: label.FieldValue,
+		})
+	}
+	if mu.mutation.OwnerCleared() {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.M2O,
+			Inverse: true,
+			Table:   label.OwnerTable,
+			Columns: []string{label.OwnerColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: alert.FieldID,
+				},
+			},
+		}
+		_spec.Edges.Clear = append(_spec.Edges.Clear, edge)
+	}
+	if nodes := mu.mutation.OwnerIDs(); len(nodes) > 0 {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.M2O,
+			Inverse: true,
+			Table:   label.OwnerTable,
+			Columns: []string{label.OwnerColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: alert.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_spec.Edges.Add = append(_spec.Edges.Add, edge)
+	}
+	if n, err = sqlgraph.UpdateNodes(ctx, mu.driver, _spec); err != nil {
+		if _, ok := err.(*sqlgraph.NotFoundError); ok {
+			err = &NotFoundError{label.Label}
+		} else if cerr, ok := isSQLConstraintError(err); ok {
+			err = cerr
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// LabelUpdateOne is the builder for updating a single Label entity.
+type LabelUpdateOne struct {
+	config
+	hooks    []Hook
+	mutation *LabelMutation
+}
+
+// SetCreatedAt sets the created_at field.
+func (muo *LabelUpdateOne) SetCreatedAt(t time.Time) *LabelUpdateOne {
+	muo.mutation.SetCreatedAt(t)
+	return muo
+}
+
+// SetNillableCreatedAt sets the created_at field if the given value is not nil.
+func (muo *LabelUpdateOne) SetNillableCreatedAt(t *time.Time) *LabelUpdateOne {
+	if t != nil {
+		muo.SetCreatedAt(*t)
+	}
+	return muo
+}
+
+// SetUpdatedAt sets the updated_at field.
+func (muo *LabelUpdateOne) SetUpdatedAt(t time.Time) *LabelUpdateOne {
+	muo.mutation.SetUpdatedAt(t)
+	return muo
+}
+
+// SetNillableUpdatedAt sets the updated_at field if the given value is not nil.
+func (muo *LabelUpdateOne) SetNillableUpdatedAt(t *time.Time) *LabelUpdateOne {
+	if t != nil {
+		muo.SetUpdatedAt(*t)
+	}
+	return muo
+}
+
+// SetKey sets the key field.
+func (muo *LabelUpdateOne) SetKey(s string) *LabelUpdateOne {
+	muo.mutation.SetKey(s)
+	return muo
+}
+
+// SetValue sets the value field.
+func (muo *LabelUpdateOne) SetValue(s string) *LabelUpdateOne {
+	muo.mutation.SetValue(s)
+	return muo
+}
+
+// SetOwnerID sets the owner edge to Alert by id.
+func (muo *LabelUpdateOne) SetOwnerID(id int) *LabelUpdateOne {
+	muo.mutation.SetOwnerID(id)
+	return muo
+}
+
+// SetNillableOwnerID sets the owner edge to Alert by id if the given value is not nil.
+func (muo *LabelUpdateOne) SetNillableOwnerID(id *int) *LabelUpdateOne {
+	if id != nil {
+		muo = muo.SetOwnerID(*id)
+	}
+	return muo
+}
+
+// SetOwner sets the owner edge to Alert.
+func (muo *LabelUpdateOne) SetOwner(a *Alert) *LabelUpdateOne {
+	return muo.SetOwnerID(a.ID)
+}
+
+// Mutation returns the LabelMutation object of the builder.
+func (muo *LabelUpdateOne) Mutation() *LabelMutation {
+	return muo.mutation
+}
+
+// ClearOwner clears the "owner" edge to type Alert.
+func (muo *LabelUpdateOne) ClearOwner() *LabelUpdateOne {
+	muo.mutation.ClearOwner()
+	return muo
+}
+
+// Save executes the query and returns the updated entity.
+func (muo *LabelUpdateOne) Save(ctx context.Context) (*Label, error) {
+	var (
+		err  error
+		node *Label
+	)
+	if len(muo.hooks) == 0 {
+		if err = muo.check(); err != nil {
+			return nil, err
+		}
+		node, err = muo.sqlSave(ctx)
+	} else {
+		var mut Mutator = MutateFunc(func(ctx context.Context, m Mutation) (Value, error) {
+			mutation, ok := m.(*LabelMutation)
+			if !ok {
+				return nil, fmt.Errorf("unexpected mutation type %T", m)
+			}
+			if err = muo.check(); err != nil {
+				return nil, err
+			}
+			muo.mutation = mutation
+			node, err = muo.sqlSave(ctx)
+			mutation.done = true
+			return node, err
+		})
+		for i := len(muo.hooks) - 1; i >= 0; i-- {
+			mut = muo.hooks[i](mut)
+		}
+		if _, err := mut.Mutate(ctx, muo.mutation); err != nil {
+			return nil, err
+		}
+	}
+	return node, err
+}
+
+// SaveX is like Save, but panics if an error occurs.
+func (muo *LabelUpdateOne) SaveX(ctx context.Context) *Label {
+	node, err := muo.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return node
+}
+
+// Exec executes the query on the entity.
+func (muo *LabelUpdateOne) Exec(ctx context.Context) error {
+	_, err := muo.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (muo *LabelUpdateOne) ExecX(ctx context.Context) {
+	if err := muo.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// check runs all checks and user-defined validators on the builder.
+func (muo *LabelUpdateOne) check() error {
+	if v, ok := muo.mutation.Value(); ok {
+		if err := label.ValueValidator(v); err != nil {
+			return &ValidationError{Name: "value", err: fmt.Errorf("ent: validator failed for field \"value\": %w", err)}
+		}
+	}
+	return nil
+}
+
+func (muo *LabelUpdateOne) sqlSave(ctx context.Context) (_node *Label, err error) {
+	_spec := &sqlgraph.UpdateSpec{
+		Node: &sqlgraph.NodeSpec{
+			Table:   label.Table,
+			Columns: label.Columns,
+			ID: &sqlgraph.FieldSpec{
+				Type:   field.TypeInt,
+				Column: label.FieldID,
+			},
+		},
+	}
+	id, ok := muo.mutation.ID()
+	if !ok {
+		return nil, &ValidationError{Name: "ID", err: fmt.Errorf("missing Label.ID for update")}
+	}
+	_spec.Node.ID.Value = id
+	if value, ok := muo.mutation.CreatedAt(); ok {
+		_spec.Fields.Set = append(_spec.Fields.Set, &sqlgraph.FieldSpec{
+			Type:   field.TypeTime,
+			Value:  value,
+			Column: label.FieldCreatedAt,
+		})
+	}
+	if value, ok := muo.mutation.UpdatedAt(); ok {
+		_spec.Fields.Set = append(_spec.Fields.Set, &sqlgraph.FieldSpec{
+			Type:   field.TypeTime,
+			Value:  value,
+			Column: label.FieldUpdatedAt,
+		})
+	}
+	if value, ok := muo.mutation.Key(); ok {
+		_spec.Fields.Set = append(_spec.Fields.Set, &sqlgraph.FieldSpec{
+			Type:   field.TypeString,
+			Value:  value,
+			Column: label.FieldKey,
+		})
+	}
+	if value, ok := muo.mutation.Value(); ok {
+		_spec.Fields.Set = append(_spec.Fields.Set, &sqlgraph.FieldSpec{
+			Type:   field.TypeString,
+			Value:  value,
+			Column: label.FieldValue,
+		})
+	}
+	if muo.mutation.OwnerCleared() {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.M2O,
+			Inverse: true,
+			Table:   label.OwnerTable,
+			Columns: []string{label.OwnerColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: alert.FieldID,
+				},
+			},
+		}
+		_spec.Edges.Clear = append(_spec.Edges.Clear, edge)
+	}
+	if nodes := muo.mutation.OwnerIDs(); len(nodes) > 0 {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.M2O,
+			Inverse: true,
+			Table:   label.OwnerTable,
+			Columns: []string{label.OwnerColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeInt,
+					Column: alert.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_spec.Edges.Add = append(_spec.Edges.Add, edge)
+	}
+	_node = &Label{config: muo.config}
+	_spec.Assign = _node.assignValues
+	_spec.ScanValues = _node.scanValues()
+	if err = sqlgraph.UpdateNode(ctx, muo.driver, _spec); err != nil {
+		if _, ok := err.(*sqlgraph.NotFoundError); ok {
+			err = &NotFoundError{label.Label}
+		} else if cerr, ok := isSQLConstraintError(err); ok {
+			err = cerr
+		}
+		return nil, err
+	}
+	return _node, nil
+}
@@ -170,6 +170,30 @@ var (
 			},
 		},
 	}
+	// LabelsColumns holds the columns for the "labels" table.
+	LabelsColumns = []*schema.Column{
+		{Name: "id", Type: field.TypeInt, Increment: true},
+		{Name: "created_at", Type: field.TypeTime},
+		{Name: "updated_at", Type: field.TypeTime},
+		{Name: "key", Type: field.TypeString},
+		{Name: "value", Type: field.TypeString, Size: 4095},
+		{Name: "alert_labels", Type: field.TypeInt, Nullable: true},
+	}
+	// LabelsTable holds the schema information for the "labels" table.
+	LabelsTable = &schema.Table{
+		Name:       "labels",
+		Columns:    LabelsColumns,
+		PrimaryKey: []*schema.Column{LabelsColumns[0]},
+		ForeignKeys: []*schema.ForeignKey{
+			{
+				Symbol:  "labels_alerts_labels",
+				Columns: []*schema.Column{LabelsColumns[5]},
+
+				RefColumns: []*schema.Column{AlertsColumns[0]},
+				OnDelete:   schema.SetNull,
+			},
+		},
+	}
 	// Tables holds all the tables in the schema.
 	Tables = []*schema.Table{
 		AlertsTable,
@@ -178,6 +202,7 @@ var (
 		EventsTable,
 		MachinesTable,
 		MetaTable,
+		LabelsTable,
 	}
 )
 
@@ -186,4 +211,5 @@ func init() {
 	DecisionsTable.ForeignKeys[0].RefTable = AlertsTable
 	EventsTable.ForeignKeys[0].RefTable = AlertsTable
 	MetaTable.ForeignKeys[0].RefTable = AlertsTable
+	LabelsTable.ForeignKeys[0].RefTable = AlertsTable
 }
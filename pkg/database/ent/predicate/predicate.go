@@ -23,3 +23,6 @@ type Machine func(*sql.Selector)
 
 // Meta is the predicate function for meta builders.
 type Meta func(*sql.Selector)
+
+// Label is the predicate function for label builders.
+type Label func(*sql.Selector)
@@ -59,5 +59,6 @@ func (Alert) Edges() []ent.Edge {
 		edge.To("decisions", Decision.Type),
 		edge.To("events", Event.Type),
 		edge.To("metas", Meta.Type),
+		edge.To("labels", Label.Type),
 	}
 }
@@ -0,0 +1,35 @@
+package schema
+
+import (
+	"time"
+
+	"github.com/facebook/ent"
+	"github.com/facebook/ent/schema/edge"
+	"github.com/facebook/ent/schema/field"
+)
+
+// Label holds the schema definition for the Label entity.
+type Label struct {
+	ent.Schema
+}
+
+// Fields of the Label.
+func (Label) Fields() []ent.Field {
+	return []ent.Field{
+		field.Time("created_at").
+			Default(time.Now),
+		field.Time("updated_at").
+			Default(time.Now),
+		field.String("key"),
+		field.String("value").MaxLen(4095),
+	}
+}
+
+// Edges of the Label.
+func (Label) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("owner", Alert.Type).
+			Ref("labels").
+			Unique(),
+	}
+}
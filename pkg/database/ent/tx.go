@@ -24,6 +24,8 @@ type Tx struct {
 	Machine *MachineClient
 	// Meta is the client for interacting with the Meta builders.
 	Meta *MetaClient
+	// Label is the client for interacting with the Label builders.
+	Label *LabelClient
 
 	// lazily loaded.
 	client     *Client
@@ -165,6 +167,7 @@ func (tx *Tx) init() {
 	tx.Event = NewEventClient(tx.config)
 	tx.Machine = NewMachineClient(tx.config)
 	tx.Meta = NewMetaClient(tx.config)
+	tx.Label = NewLabelClient(tx.config)
 }
 
 // txDriver wraps the given dialect.Tx with a nop dialect.Driver implementation.
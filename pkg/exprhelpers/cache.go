@@ -0,0 +1,87 @@
+package exprhelpers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+The helpers are called for each event, with arguments that are most of the time static (patterns, time zones) but can come
+from the events themselves. boundedCache keeps what they compiled, failures included, without growing with the events :
+once full, it starts over.
+*/
+type boundedCache struct {
+	lock    sync.RWMutex
+	max     int
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value interface{}
+	err   error
+}
+
+func newBoundedCache(max int) *boundedCache {
+	return &boundedCache{max: max, entries: make(map[string]cacheEntry)}
+}
+
+//get returns the cached value of key, computing it (and caching it, even if it failed) with build if needed
+func (c *boundedCache) get(key string, build func() (interface{}, error)) (interface{}, error) {
+	c.lock.RLock()
+	entry, ok := c.entries[key]
+	c.lock.RUnlock()
+	if ok {
+		return entry.value, entry.err
+	}
+	value, err := build()
+	c.lock.Lock()
+	if len(c.entries) >= c.max {
+		c.entries = make(map[string]cacheEntry)
+	}
+	c.entries[key] = cacheEntry{value: value, err: err}
+	c.lock.Unlock()
+	return value, err
+}
+
+func (c *boundedCache) len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return len(c.entries)
+}
+
+//warningInterval is the minimum time between two warnings of the same helper
+var warningInterval = time.Minute
+
+//limitedLog logs the warnings of a helper at most once per warningInterval, with the number of warnings left out meanwhile
+type limitedLog struct {
+	lock       sync.Mutex
+	last       time.Time
+	suppressed int
+	logger     *log.Entry //the standard logger if nil
+}
+
+func (l *limitedLog) Warningf(format string, args ...interface{}) {
+	l.lock.Lock()
+	if time.Since(l.last) < warningInterval {
+		l.suppressed++
+		l.lock.Unlock()
+		return
+	}
+	suppressed := l.suppressed
+	l.last = time.Now()
+	l.suppressed = 0
+	l.lock.Unlock()
+
+	message := fmt.Sprintf(format, args...)
+	if suppressed > 0 {
+		message = fmt.Sprintf("%s (%d similar warnings left out)", message, suppressed)
+	}
+	if l.logger != nil {
+		l.logger.Warning(message)
+		return
+	}
+	log.Warning(message)
+}
@@ -0,0 +1,80 @@
+package exprhelpers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundedCache(t *testing.T) {
+	cache := newBoundedCache(3)
+	builds := 0
+	build := func(key string) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			builds++
+			if key == "bad" {
+				return nil, fmt.Errorf("bad key")
+			}
+			return strings.ToUpper(key), nil
+		}
+	}
+
+	value, err := cache.get("a", build("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "A", value)
+	_, err = cache.get("a", build("a"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, builds)
+
+	//failures are cached too
+	_, err = cache.get("bad", build("bad"))
+	assert.Error(t, err)
+	_, err = cache.get("bad", build("bad"))
+	assert.Error(t, err)
+	assert.Equal(t, 2, builds)
+
+	//the cache starts over once full
+	for _, key := range []string{"b", "c", "d"} {
+		_, err = cache.get(key, build(key))
+		require.NoError(t, err)
+	}
+	assert.LessOrEqual(t, cache.len(), 3)
+	_, err = cache.get("a", build("a"))
+	require.NoError(t, err)
+	assert.Equal(t, 6, builds)
+}
+
+func TestLimitedLog(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	defer func(interval time.Duration) { warningInterval = interval }(warningInterval)
+	warningInterval = time.Hour
+
+	var limited limitedLog
+	for i := 0; i < 5; i++ {
+		limited.Warningf("warning %d", i)
+	}
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, log.WarnLevel, hook.LastEntry().Level)
+	assert.Equal(t, "warning 0", hook.LastEntry().Message)
+
+	//the warnings left out are counted in the next one
+	limited.last = time.Now().Add(-2 * time.Hour)
+	limited.Warningf("warning %d", 5)
+	require.Len(t, hook.AllEntries(), 2)
+	assert.Equal(t, "warning 5 (4 similar warnings left out)", hook.LastEntry().Message)
+
+	//invalid patterns are compiled, and reported, once
+	hook.Reset()
+	regexpLog = limitedLog{}
+	for i := 0; i < 3; i++ {
+		assert.False(t, RegexpMatch("abc", "(unclosed"))
+	}
+	assert.Len(t, hook.AllEntries(), 1)
+}
@@ -0,0 +1,31 @@
+package exprhelpers
+
+import (
+	"sync"
+)
+
+/*
+GetExprEnv builds a new map holding the whole helpers library on each call, which is too expensive to do for each
+expression evaluated on each event. The hot path (parser nodes, bucket filters and groupby, distinct...) uses
+pooled environments instead : the helpers are set once, and only "evt" changes between evaluations.
+The VM itself can't be pooled : expr's vm doesn't reset its memory budget between runs.
+*/
+var evtEnvPool = sync.Pool{
+	New: func() interface{} {
+		return GetExprEnv(map[string]interface{}{"evt": nil})
+	},
+}
+
+//AcquireEvtEnv returns an environment holding the helpers and evt, it must be given back with ReleaseEvtEnv once the evaluations are done and not be kept after
+func AcquireEvtEnv(evt interface{}) map[string]interface{} {
+	env := evtEnvPool.Get().(map[string]interface{})
+	env["evt"] = evt
+	return env
+}
+
+//ReleaseEvtEnv gives back an environment acquired with AcquireEvtEnv
+func ReleaseEvtEnv(env map[string]interface{}) {
+	/*don't keep the event alive while the env sits in the pool*/
+	env["evt"] = nil
+	evtEnvPool.Put(env)
+}
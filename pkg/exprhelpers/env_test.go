@@ -0,0 +1,63 @@
+package exprhelpers
+
+import (
+	"testing"
+
+	"github.com/antonmedv/expr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type benchEvent struct {
+	Meta map[string]string
+}
+
+func TestAcquireEvtEnv(t *testing.T) {
+	evt := &benchEvent{Meta: map[string]string{"source_ip": "1.2.3.4"}}
+	program, err := expr.Compile("IpInRange(evt.Meta.source_ip, '1.2.3.0/24')", expr.Env(GetExprEnv(map[string]interface{}{"evt": &benchEvent{}})))
+	require.NoError(t, err)
+
+	env := AcquireEvtEnv(evt)
+	assert.Len(t, env, len(exprHelpers)+1)
+	out, err := expr.Run(program, env)
+	require.NoError(t, err)
+	assert.Equal(t, true, out)
+	ReleaseEvtEnv(env)
+	assert.Nil(t, env["evt"])
+
+	/*whatever env the pool gives back, it must hold the new event*/
+	evt = &benchEvent{Meta: map[string]string{"source_ip": "4.3.2.1"}}
+	env = AcquireEvtEnv(evt)
+	defer ReleaseEvtEnv(env)
+	out, err = expr.Run(program, env)
+	require.NoError(t, err)
+	assert.Equal(t, false, out)
+}
+
+func benchmarkEnv(b *testing.B, getEnv func(interface{}) map[string]interface{}, putEnv func(map[string]interface{})) {
+	evt := &benchEvent{Meta: map[string]string{"log_type": "ssh_failed-auth", "source_ip": "1.2.3.4"}}
+	program, err := expr.Compile("evt.Meta.log_type == 'http_access-log' && Upper(evt.Meta.source_ip) != ''",
+		expr.Env(GetExprEnv(map[string]interface{}{"evt": &benchEvent{}})))
+	if err != nil {
+		b.Fatalf("compile : %s", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		env := getEnv(evt)
+		if _, err := expr.Run(program, env); err != nil {
+			b.Fatalf("run : %s", err)
+		}
+		putEnv(env)
+	}
+}
+
+func BenchmarkGetExprEnv(b *testing.B) {
+	benchmarkEnv(b, func(evt interface{}) map[string]interface{} {
+		return GetExprEnv(map[string]interface{}{"evt": evt})
+	}, func(map[string]interface{}) {})
+}
+
+func BenchmarkAcquireEvtEnv(b *testing.B) {
+	benchmarkEnv(b, AcquireEvtEnv, ReleaseEvtEnv)
+}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/davecgh/go-spew/spew"
 	log "github.com/sirupsen/logrus"
@@ -16,6 +17,10 @@ import (
 
 var dataFile map[string][]string
 var dataFileRegex map[string][]*regexp.Regexp
+var dataFileMatcher map[string]*regexpMatcher
+
+/*data files can be reloaded while expressions are running*/
+var dataFileLock sync.RWMutex
 
 func Atof(x string) float64 {
 	log.Debugf("debug atof %s", x)
@@ -30,15 +35,56 @@ func Upper(s string) string {
 	return strings.ToUpper(s)
 }
 
+//ExprHelper describes a function exposed to the expressions (filters, groupby, statics, profiles etc.)
+type ExprHelper struct {
+	Name        string      `json:"name" yaml:"name"`
+	Signature   string      `json:"signature" yaml:"signature"`
+	Description string      `json:"description" yaml:"description"`
+	Func        interface{} `json:"-" yaml:"-"`
+}
+
+var exprHelpers = []ExprHelper{
+	{"Atof", "Atof(string) float64", "parses a string representation of a float number", Atof},
+	{"JsonExtract", "JsonExtract(JsonBlob, FieldName) string", "extracts FieldName (ie. 'foo.bar[0]') from JsonBlob", JsonExtract},
+	{"JsonExtractLib", "JsonExtractLib(JsonBlob, Path...) string", "extracts the element at Path from JsonBlob", JsonExtractLib},
+	{"File", "File(FileName) []string", "returns the content of the data file FileName", File},
+	{"RegexpInFile", "RegexpInFile(StringToMatch, FileName) bool", "true if StringToMatch is matched by one of the regexps of the data file FileName", RegexpInFile},
+	{"Upper", "Upper(string) string", "returns the uppercase version of the string", Upper},
+	{"Lower", "Lower(string) string", "returns the lowercase version of the string", Lower},
+	{"Trim", "Trim(string) string", "removes leading and trailing white spaces", Trim},
+	{"Split", "Split(string, Sep) []string", "splits the string around each occurrence of Sep", Split},
+	{"Contains", "Contains(string, Substr) bool", "true if Substr is within the string", Contains},
+	{"RegexpMatch", "RegexpMatch(string, Pattern) bool", "true if the string is matched by the RE2 Pattern", RegexpMatch},
+	{"RegexpCaptures", "RegexpCaptures(string, Pattern) []string", "returns the capture groups of the first match of Pattern", RegexpCaptures},
+	{"UrlDecode", "UrlDecode(string) string", "returns the url-decoded version of the string", UrlDecode},
+	{"PathNormalize", "PathNormalize(string) string", "url-decodes and cleans a path ('//a/./b/../c' -> '/a/c')", PathNormalize},
+	{"B64Decode", "B64Decode(string) string", "decodes a base64 string, empty string on failure", B64Decode},
+	{"HexDecode", "HexDecode(string) string", "decodes an hex string, empty string on failure", HexDecode},
+	{"Distance", "Distance(string, string) int", "returns the levenshtein distance between the two strings", Distance},
+	{"Now", "Now() time.Time", "returns the current time", Now},
+	{"ParseDuration", "ParseDuration(string) time.Duration", "parses a duration string ('1h30m'), 0 on failure", ParseDuration},
+	{"HourOfDay", "HourOfDay(Time, Zone) int", "returns the hour (0-23) of Time (a time or an RFC3339 timestamp) in Zone ('UTC', 'Europe/Paris'), fails on invalid input", HourOfDay},
+	{"IpInRange", "IpInRange(IPStr, RangeStr) bool", "true if the IP is contained in the range", IpInRange},
+	{"IpToRange", "IpToRange(IPStr, CIDR) string", "returns the network of size CIDR ('/24') the IP belongs to", IpToRange},
+	{"RangeSize", "RangeSize(RangeStr) int64", "returns the number of addresses in the range", RangeSize},
+	{"IpEqual", "IpEqual(IPStr, IPStr) bool", "compares two IPs whatever their representation (IPv4-mapped IPv6, zero compression)", IpEqual},
+	{"IsIP", "IsIP(IPStr) bool", "true if the string is a valid IPv4 or IPv6", IsIP},
+	{"IsIPV4", "IsIPV4(IPStr) bool", "true if the string is a valid IPv4", IsIPV4},
+	{"IsIPV6", "IsIPV6(IPStr) bool", "true if the string is a valid IPv6", IsIPV6},
+	{"GetDecisionsCount", "GetDecisionsCount(Value) int", "returns the number of decisions (active or expired) ever taken on Value (local API only)", GetDecisionsCount},
+	{"GetActiveDecisions", "GetActiveDecisions(Value) int", "returns the number of decisions currently active on Value (local API only)", GetActiveDecisions},
+	{"GetAlertsCountSince", "GetAlertsCountSince(Value, Duration) int", "returns the number of alerts about Value in the last Duration ('24h') (local API only)", GetAlertsCountSince},
+}
+
+//GetExprHelpers returns the documented list of helpers available in expressions
+func GetExprHelpers() []ExprHelper {
+	return exprHelpers
+}
+
 func GetExprEnv(ctx map[string]interface{}) map[string]interface{} {
-	var ExprLib = map[string]interface{}{
-		"Atof":           Atof,
-		"JsonExtract":    JsonExtract,
-		"JsonExtractLib": JsonExtractLib,
-		"File":           File,
-		"RegexpInFile":   RegexpInFile,
-		"Upper":          Upper,
-		"IpInRange":      IpInRange,
+	var ExprLib = make(map[string]interface{}, len(exprHelpers)+len(ctx))
+	for _, helper := range exprHelpers {
+		ExprLib[helper.Name] = helper.Func
 	}
 	for k, v := range ctx {
 		ExprLib[k] = v
@@ -47,11 +93,15 @@ func GetExprEnv(ctx map[string]interface{}) map[string]interface{} {
 }
 
 func Init() error {
+	dataFileLock.Lock()
+	defer dataFileLock.Unlock()
 	dataFile = make(map[string][]string)
 	dataFileRegex = make(map[string][]*regexp.Regexp)
+	dataFileMatcher = make(map[string]*regexpMatcher)
 	return nil
 }
 
+//FileInit (re)loads the data file, the previous content is atomically replaced so it can be called while expressions are running
 func FileInit(fileFolder string, filename string, fileType string) error {
 	log.Debugf("init (folder:%s) (file:%s) (type:%s)", fileFolder, filename, fileType)
 	filepath := path.Join(fileFolder, filename)
@@ -65,9 +115,8 @@ func FileInit(fileFolder string, filename string, fileType string) error {
 		log.Debugf("ignored file %s%s because no type specified", fileFolder, filename)
 		return nil
 	}
-	if _, ok := dataFile[filename]; !ok {
-		dataFile[filename] = []string{}
-	}
+	strs := []string{}
+	regexps := []*regexp.Regexp{}
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		if strings.HasPrefix(scanner.Text(), "#") { // allow comments
@@ -75,9 +124,13 @@ func FileInit(fileFolder string, filename string, fileType string) error {
 		}
 		switch fileType {
 		case "regex", "regexp":
-			dataFileRegex[filename] = append(dataFileRegex[filename], regexp.MustCompile(scanner.Text()))
+			re, err := regexp.Compile(scanner.Text())
+			if err != nil {
+				return fmt.Errorf("invalid regexp '%s' in '%s' : %s", scanner.Text(), filename, err)
+			}
+			regexps = append(regexps, re)
 		case "string":
-			dataFile[filename] = append(dataFile[filename], scanner.Text())
+			strs = append(strs, scanner.Text())
 		default:
 			return fmt.Errorf("unknown data type '%s' for : '%s'", fileType, filename)
 		}
@@ -86,10 +139,22 @@ func FileInit(fileFolder string, filename string, fileType string) error {
 	if err := scanner.Err(); err != nil {
 		return err
 	}
+
+	dataFileLock.Lock()
+	defer dataFileLock.Unlock()
+	switch fileType {
+	case "regex", "regexp":
+		dataFileRegex[filename] = regexps
+		dataFileMatcher[filename] = newRegexpMatcher(regexps)
+	case "string":
+		dataFile[filename] = strs
+	}
 	return nil
 }
 
 func File(filename string) []string {
+	dataFileLock.RLock()
+	defer dataFileLock.RUnlock()
 	if _, ok := dataFile[filename]; ok {
 		return dataFile[filename]
 	}
@@ -99,16 +164,14 @@ func File(filename string) []string {
 }
 
 func RegexpInFile(data string, filename string) bool {
-	if _, ok := dataFileRegex[filename]; ok {
-		for _, re := range dataFileRegex[filename] {
-			if re.Match([]byte(data)) {
-				return true
-			}
-		}
-	} else {
-		log.Errorf("file '%s' (type:regexp) not found in expr library", filename)
-		log.Errorf("expr library : %s", spew.Sdump(dataFileRegex))
+	dataFileLock.RLock()
+	matcher, ok := dataFileMatcher[filename]
+	dataFileLock.RUnlock()
+	if ok {
+		return matcher.Match(data)
 	}
+	log.Errorf("file '%s' (type:regexp) not found in expr library", filename)
+	log.Errorf("expr library : %s", spew.Sdump(dataFileRegex))
 	return false
 }
 
@@ -2,6 +2,9 @@ package exprhelpers
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
 
 	log "github.com/sirupsen/logrus"
 
@@ -366,3 +369,46 @@ func TestUpper(t *testing.T) {
 
 	log.Printf("test 'Upper()' : OK")
 }
+
+func TestExprHelpersAreExposed(t *testing.T) {
+	env := GetExprEnv(map[string]interface{}{})
+	for _, helper := range GetExprHelpers() {
+		if helper.Signature == "" || helper.Description == "" {
+			t.Fatalf("helper '%s' is not documented", helper.Name)
+		}
+		if _, ok := env[helper.Name]; !ok {
+			t.Fatalf("helper '%s' is not in expr env", helper.Name)
+		}
+	}
+}
+
+func TestFileReload(t *testing.T) {
+	if err := Init(); err != nil {
+		log.Fatalf(err.Error())
+	}
+	dir, err := ioutil.TempDir("", "crowdsec-expr")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "data.txt"), []byte("foo\nbar\n"), 0644))
+	require.NoError(t, FileInit(dir, "data.txt", "string"))
+	//loading twice doesn't duplicate entries
+	require.NoError(t, FileInit(dir, "data.txt", "string"))
+	assert.Equal(t, []string{"foo", "bar"}, File("data.txt"))
+
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "data.txt"), []byte("baz\n"), 0644))
+	require.NoError(t, FileInit(dir, "data.txt", "string"))
+	assert.Equal(t, []string{"baz"}, File("data.txt"))
+
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "re.txt"), []byte("^foo"), 0644))
+	require.NoError(t, FileInit(dir, "re.txt", "regex"))
+	assert.True(t, RegexpInFile("foobar", "re.txt"))
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "re.txt"), []byte("^bar"), 0644))
+	require.NoError(t, FileInit(dir, "re.txt", "regex"))
+	assert.False(t, RegexpInFile("foobar", "re.txt"))
+
+	//an invalid file keeps the previous content
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "re.txt"), []byte("(bar"), 0644))
+	assert.Error(t, FileInit(dir, "re.txt", "regex"))
+	assert.True(t, RegexpInFile("barfoo", "re.txt"))
+}
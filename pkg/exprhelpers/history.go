@@ -0,0 +1,141 @@
+package exprhelpers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/antonmedv/expr/ast"
+	"github.com/antonmedv/expr/parser"
+)
+
+//DecisionsHistory gives access to past alerts and decisions, it is implemented by database.Client
+type DecisionsHistory interface {
+	CountDecisionsByValue(value string) (int, error)
+	CountActiveDecisionsByValue(value string) (int, error)
+	CountAlertsByValueSince(value string, since time.Time) (int, error)
+}
+
+//DefaultHistoryCacheTTL is the time the results of history lookups are kept, so that a burst of alerts doesn't hammer the database
+const DefaultHistoryCacheTTL = 10 * time.Second
+
+//purge the expired entries when the cache grows beyond this size, and start over if they are all still valid
+const historyCacheSweepSize = 10000
+
+type historyCacheEntry struct {
+	count   int
+	expires time.Time
+}
+
+/*
+ErrNoDecisionsHistory is raised by the history helpers when there is no database to look into (ie. in the parsers or postoverflows
+of crowdsec) : they fail the expression instead of silently returning 0
+*/
+var ErrNoDecisionsHistory = errors.New("decisions history is only available in the profiles of the local API")
+
+var history DecisionsHistory
+var historyCacheTTL = DefaultHistoryCacheTTL
+var historyCache = make(map[string]historyCacheEntry)
+var historyCacheLock sync.Mutex
+
+//SetDecisionsHistory sets the backend used by GetDecisionsCount, GetActiveDecisions and GetAlertsCountSince, and flushes the cache
+func SetDecisionsHistory(h DecisionsHistory) {
+	historyCacheLock.Lock()
+	defer historyCacheLock.Unlock()
+	history = h
+	historyCache = make(map[string]historyCacheEntry)
+}
+
+func cachedCount(key string, lookup func(DecisionsHistory) (int, error)) int {
+	now := time.Now()
+
+	historyCacheLock.Lock()
+	h := history
+	if h == nil {
+		historyCacheLock.Unlock()
+		/*expr turns the panic into an error of the expression*/
+		panic(fmt.Errorf("%s : %w", key, ErrNoDecisionsHistory))
+	}
+	if entry, ok := historyCache[key]; ok && now.Before(entry.expires) {
+		historyCacheLock.Unlock()
+		return entry.count
+	}
+	historyCacheLock.Unlock()
+
+	count, err := lookup(h)
+	if err != nil {
+		/*a failing database must not look like a clean history*/
+		panic(fmt.Errorf("history lookup '%s' failed : %w", key, err))
+	}
+
+	historyCacheLock.Lock()
+	defer historyCacheLock.Unlock()
+	if len(historyCache) >= historyCacheSweepSize {
+		for k, entry := range historyCache {
+			if now.After(entry.expires) {
+				delete(historyCache, k)
+			}
+		}
+		if len(historyCache) >= historyCacheSweepSize {
+			historyCache = make(map[string]historyCacheEntry)
+		}
+	}
+	historyCache[key] = historyCacheEntry{count: count, expires: now.Add(historyCacheTTL)}
+	return count
+}
+
+//GetDecisionsCount returns the number of decisions (active or expired) ever taken on value
+func GetDecisionsCount(value string) int {
+	return cachedCount(fmt.Sprintf("decisions/%s", value), func(h DecisionsHistory) (int, error) {
+		return h.CountDecisionsByValue(value)
+	})
+}
+
+//GetActiveDecisions returns the number of decisions currently active on value
+func GetActiveDecisions(value string) int {
+	return cachedCount(fmt.Sprintf("active/%s", value), func(h DecisionsHistory) (int, error) {
+		return h.CountActiveDecisionsByValue(value)
+	})
+}
+
+//GetAlertsCountSince returns the number of alerts about value in the last duration (ie. '24h'), the expression fails on invalid duration
+func GetAlertsCountSince(value string, duration string) int {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		panic(fmt.Errorf("GetAlertsCountSince : invalid duration '%s' : %w", duration, err))
+	}
+	return cachedCount(fmt.Sprintf("alerts/%s/%s", value, duration), func(h DecisionsHistory) (int, error) {
+		return h.CountAlertsByValueSince(value, time.Now().Add(-d))
+	})
+}
+
+type historyDurations struct {
+	err error
+}
+
+func (v *historyDurations) Enter(node *ast.Node) {}
+
+func (v *historyDurations) Exit(node *ast.Node) {
+	n, ok := (*node).(*ast.FunctionNode)
+	if !ok || n.Name != "GetAlertsCountSince" || len(n.Arguments) != 2 || v.err != nil {
+		return
+	}
+	/*only the constant durations are known before runtime*/
+	if duration, ok := n.Arguments[1].(*ast.StringNode); ok {
+		if _, err := time.ParseDuration(duration.Value); err != nil {
+			v.err = fmt.Errorf("GetAlertsCountSince : invalid duration '%s' : %w", duration.Value, err)
+		}
+	}
+}
+
+//CheckHistoryDurations fails if the expression calls GetAlertsCountSince with a constant duration that is invalid
+func CheckHistoryDurations(input string) error {
+	tree, err := parser.Parse(input)
+	if err != nil {
+		return err
+	}
+	v := &historyDurations{}
+	ast.Walk(&tree.Node, v)
+	return v.err
+}
@@ -0,0 +1,95 @@
+package exprhelpers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/antonmedv/expr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHistory struct {
+	lookups int
+	since   time.Time
+}
+
+func (f *fakeHistory) CountDecisionsByValue(value string) (int, error) {
+	f.lookups++
+	if value == "1.2.3.4" {
+		return 3, nil
+	}
+	return 0, nil
+}
+
+func (f *fakeHistory) CountActiveDecisionsByValue(value string) (int, error) {
+	f.lookups++
+	if value == "1.2.3.4" {
+		return 1, nil
+	}
+	return 0, fmt.Errorf("database is down")
+}
+
+func (f *fakeHistory) CountAlertsByValueSince(value string, since time.Time) (int, error) {
+	f.lookups++
+	f.since = since
+	return 5, nil
+}
+
+func TestDecisionsHistory(t *testing.T) {
+	//without history, the expressions fail
+	SetDecisionsHistory(nil)
+	assert.Panics(t, func() { GetDecisionsCount("1.2.3.4") })
+	program, err := expr.Compile("GetActiveDecisions('1.2.3.4') == 0")
+	require.NoError(t, err)
+	_, err = expr.Run(program, GetExprEnv(map[string]interface{}{}))
+	assert.Error(t, err)
+
+	fake := &fakeHistory{}
+	SetDecisionsHistory(fake)
+	defer SetDecisionsHistory(nil)
+
+	env := GetExprEnv(map[string]interface{}{"ip": "1.2.3.4"})
+	program, err = expr.Compile("GetDecisionsCount(ip) > 2 && GetActiveDecisions(ip) == 1 && GetAlertsCountSince(ip, '24h') == 5", expr.Env(env))
+	require.NoError(t, err)
+	output, err := expr.Run(program, env)
+	require.NoError(t, err)
+	assert.Equal(t, true, output)
+	assert.Equal(t, 3, fake.lookups)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), fake.since, time.Minute)
+
+	//results are cached
+	output, err = expr.Run(program, env)
+	require.NoError(t, err)
+	assert.Equal(t, true, output)
+	assert.Equal(t, 3, fake.lookups)
+
+	//database errors and invalid durations fail the expression
+	assert.Panics(t, func() { GetActiveDecisions("5.6.7.8") })
+	assert.Panics(t, func() { GetAlertsCountSince("1.2.3.4", "forever") })
+	program, err = expr.Compile("GetActiveDecisions('5.6.7.8') == 0", expr.Env(env))
+	require.NoError(t, err)
+	_, err = expr.Run(program, env)
+	assert.Error(t, err)
+}
+
+func TestHistoryCacheCap(t *testing.T) {
+	SetDecisionsHistory(&fakeHistory{})
+	defer SetDecisionsHistory(nil)
+
+	for i := 0; i < 2*historyCacheSweepSize; i++ {
+		GetDecisionsCount(fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+	historyCacheLock.Lock()
+	size := len(historyCache)
+	historyCacheLock.Unlock()
+	assert.LessOrEqual(t, size, historyCacheSweepSize)
+}
+
+func TestCheckHistoryDurations(t *testing.T) {
+	assert.NoError(t, CheckHistoryDurations("GetAlertsCountSince(Alert.GetValue(), '24h') > 2"))
+	assert.NoError(t, CheckHistoryDurations("GetAlertsCountSince(Alert.GetValue(), Alert.GetScenario()) > 2"))
+	assert.Error(t, CheckHistoryDurations("GetAlertsCountSince(Alert.GetValue(), 'forever') > 2"))
+	assert.Error(t, CheckHistoryDurations("true && GetAlertsCountSince(Alert.GetValue(), '1 week') > 2"))
+}
@@ -0,0 +1,73 @@
+package exprhelpers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//IpEqual compares two IPs whatever their representation (ie. '::ffff:1.2.3.4' == '1.2.3.4', '2001:db8::1' == '2001:0db8:0:0:0:0:0:1')
+func IpEqual(a string, b string) bool {
+	ipA := net.ParseIP(a)
+	ipB := net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return false
+	}
+	return ipA.Equal(ipB)
+}
+
+func IsIPV4(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() != nil
+}
+
+func IsIPV6(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() == nil
+}
+
+func IsIP(ip string) bool {
+	return net.ParseIP(ip) != nil
+}
+
+//IpToRange returns the network of the given size the ip belongs to (ie. IpToRange('1.2.3.4', '/24') == '1.2.3.0/24')
+func IpToRange(ip string, cidr string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		log.Debugf("'%s' is not a valid IP", ip)
+		return ""
+	}
+	var size int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(cidr, "/"), "%d", &size); err != nil {
+		log.Debugf("'%s' is not a valid CIDR size", cidr)
+		return ""
+	}
+	bits := 128
+	if parsed.To4() != nil {
+		parsed = parsed.To4()
+		bits = 32
+	}
+	if size < 0 || size > bits {
+		log.Debugf("'%s' is not a valid CIDR size for %s", cidr, ip)
+		return ""
+	}
+	ipNet := net.IPNet{IP: parsed.Mask(net.CIDRMask(size, bits)), Mask: net.CIDRMask(size, bits)}
+	return ipNet.String()
+}
+
+//RangeSize returns the number of addresses in the range, capped to math.MaxInt64 for large IPv6 ranges
+func RangeSize(ipRange string) int64 {
+	_, ipNet, err := net.ParseCIDR(ipRange)
+	if err != nil {
+		log.Debugf("'%s' is not a valid IP Range", ipRange)
+		return 0
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones >= 63 {
+		return math.MaxInt64
+	}
+	return int64(1) << uint(bits-ones)
+}
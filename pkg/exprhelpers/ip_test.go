@@ -0,0 +1,95 @@
+package exprhelpers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/antonmedv/expr"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIpHelpers(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]interface{}
+		code   string
+		result interface{}
+	}{
+		{
+			name:   "IpEqual() test: ipv4-mapped ipv6",
+			env:    map[string]interface{}{"a": "::ffff:1.2.3.4", "b": "1.2.3.4"},
+			code:   "IpEqual(a, b)",
+			result: true,
+		},
+		{
+			name:   "IpEqual() test: zero compression",
+			env:    map[string]interface{}{"a": "2001:db8::1", "b": "2001:0db8:0:0:0:0:0:1"},
+			code:   "IpEqual(a, b)",
+			result: true,
+		},
+		{
+			name:   "IpEqual() test: different",
+			env:    map[string]interface{}{"a": "1.2.3.4", "b": "1.2.3.5"},
+			code:   "IpEqual(a, b)",
+			result: false,
+		},
+		{
+			name:   "IsIPV4() test: ipv4",
+			env:    map[string]interface{}{"ip": "1.2.3.4"},
+			code:   "IsIPV4(ip) && !IsIPV6(ip) && IsIP(ip)",
+			result: true,
+		},
+		{
+			name:   "IsIPV6() test: ipv6",
+			env:    map[string]interface{}{"ip": "2001:db8::1"},
+			code:   "IsIPV6(ip) && !IsIPV4(ip) && IsIP(ip)",
+			result: true,
+		},
+		{
+			name:   "IsIP() test: garbage",
+			env:    map[string]interface{}{"ip": "1.2.3"},
+			code:   "IsIP(ip)",
+			result: false,
+		},
+		{
+			name:   "IpToRange() test: ipv4",
+			env:    map[string]interface{}{"ip": "1.2.3.4"},
+			code:   "IpToRange(ip, '/24')",
+			result: "1.2.3.0/24",
+		},
+		{
+			name:   "IpToRange() test: ipv6",
+			env:    map[string]interface{}{"ip": "2001:db8:1:2::1"},
+			code:   "IpToRange(ip, '48')",
+			result: "2001:db8:1::/48",
+		},
+		{
+			name:   "IpToRange() test: size too big",
+			env:    map[string]interface{}{"ip": "1.2.3.4"},
+			code:   "IpToRange(ip, '/64')",
+			result: "",
+		},
+		{
+			name:   "RangeSize() test: /24",
+			env:    map[string]interface{}{"ipRange": "1.2.3.0/24"},
+			code:   "RangeSize(ipRange)",
+			result: int64(256),
+		},
+		{
+			name:   "RangeSize() test: huge ipv6",
+			env:    map[string]interface{}{"ipRange": "2001:db8::/32"},
+			code:   "RangeSize(ipRange)",
+			result: int64(math.MaxInt64),
+		},
+	}
+
+	for _, test := range tests {
+		program, err := expr.Compile(test.code, expr.Env(GetExprEnv(test.env)))
+		require.NoError(t, err)
+		output, err := expr.Run(program, GetExprEnv(test.env))
+		require.NoError(t, err)
+		require.Equal(t, test.result, output, test.name)
+		log.Printf("test '%s' : OK", test.name)
+	}
+}
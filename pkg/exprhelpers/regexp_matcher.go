@@ -0,0 +1,109 @@
+package exprhelpers
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+)
+
+//literals shorter than this are not worth a prefilter
+const minPrefilterLength = 3
+
+/*
+regexpMatcher matches a string against all the regexps of a data file.
+Go's regexp engine doesn't get faster with a big alternation, so instead each regexp gets (when possible)
+a literal that any match must contain : the cheap strings.Contains rules out most of the regexps before running them.
+*/
+type regexpMatcher struct {
+	entries []regexpEntry
+}
+
+type regexpEntry struct {
+	re       *regexp.Regexp
+	literal  string //a substring required by any match, empty if none could be found
+	foldCase bool   //literal is lowercase and must be searched in the lowercased input
+}
+
+func newRegexpMatcher(regexps []*regexp.Regexp) *regexpMatcher {
+	m := &regexpMatcher{entries: make([]regexpEntry, 0, len(regexps))}
+	for _, re := range regexps {
+		entry := regexpEntry{re: re}
+		if parsed, err := syntax.Parse(re.String(), syntax.Perl); err == nil {
+			literal, foldCase := requiredLiteral(parsed.Simplify())
+			if len(literal) >= minPrefilterLength && (!foldCase || isASCII(literal)) {
+				entry.literal = literal
+				entry.foldCase = foldCase
+				if foldCase {
+					entry.literal = strings.ToLower(literal)
+				}
+			}
+		}
+		m.entries = append(m.entries, entry)
+	}
+	return m
+}
+
+func (m *regexpMatcher) Match(data string) bool {
+	var lower string
+	/*simple case folding of non-ASCII runes (ie. 'ſ' and 's') can't be checked with a lowercased input*/
+	asciiInput := isASCII(data)
+	lowerDone := false
+
+	for _, entry := range m.entries {
+		if entry.literal != "" {
+			if !entry.foldCase {
+				if !strings.Contains(data, entry.literal) {
+					continue
+				}
+			} else if asciiInput {
+				if !lowerDone {
+					lower = strings.ToLower(data)
+					lowerDone = true
+				}
+				if !strings.Contains(lower, entry.literal) {
+					continue
+				}
+			}
+		}
+		if entry.re.MatchString(data) {
+			return true
+		}
+	}
+	return false
+}
+
+//requiredLiteral returns the longest literal that must be present in any string matched by re
+func requiredLiteral(re *syntax.Regexp) (string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune), re.Flags&syntax.FoldCase != 0
+	case syntax.OpCapture:
+		return requiredLiteral(re.Sub[0])
+	case syntax.OpPlus:
+		return requiredLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return requiredLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		best, bestFold := "", false
+		for _, sub := range re.Sub {
+			literal, foldCase := requiredLiteral(sub)
+			if len(literal) > len(best) {
+				best, bestFold = literal, foldCase
+			}
+		}
+		return best, bestFold
+	}
+	return "", false
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+package exprhelpers
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func naiveMatch(regexps []*regexp.Regexp, data string) bool {
+	for _, re := range regexps {
+		if re.MatchString(data) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequiredLiteral(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		literal  string
+		foldCase bool
+	}{
+		{pattern: ".*Crowdsec.*", literal: "Crowdsec"},
+		{pattern: ".*Crowd[sS]ec.*", literal: "Crowd"},
+		{pattern: "^/wp-admin/(plugin|theme)s/[a-z]+\\.php$", literal: "/wp-admin/"},
+		{pattern: "(?i)masscan", literal: "MASSCAN", foldCase: true},
+		{pattern: "(nikto|sqlmap)", literal: ""},
+		{pattern: "(?:foobar)?baz", literal: "baz"},
+		{pattern: "(abcd)+", literal: "abcd"},
+		{pattern: "(abcd)*", literal: ""},
+	}
+	for _, test := range tests {
+		parsed, err := syntax.Parse(test.pattern, syntax.Perl)
+		require.NoError(t, err)
+		literal, foldCase := requiredLiteral(parsed.Simplify())
+		if test.foldCase {
+			assert.True(t, foldCase, test.pattern)
+			assert.Equal(t, test.literal, upperASCII(literal), test.pattern)
+		} else {
+			assert.Equal(t, test.literal, literal, test.pattern)
+		}
+	}
+}
+
+func upperASCII(s string) string {
+	b := []byte(s)
+	for i := range b {
+		if b[i] >= 'a' && b[i] <= 'z' {
+			b[i] -= 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func TestRegexpMatcherSameResults(t *testing.T) {
+	patterns := []string{
+		".*Crowdsec.*",
+		".*Crowd[sS]ec.*",
+		"(?i)masscan",
+		"(?i)^sqlmap/[0-9.]+",
+		"^/wp-admin/(plugin|theme)s/[a-z]+\\.php$",
+		"(nikto|zgrab)",
+		"\\.(git|svn)/",
+		"(?i)straße",
+		"(?i)ks",
+	}
+	inputs := []string{
+		"",
+		"crowdsec",
+		"Crowdsec",
+		"test CrowdSec",
+		"Mozilla/5.0 (compatible; MASSCAN/1.0)",
+		"sqlmap/1.4.11#stable (http://sqlmap.org)",
+		"SQLMAP/1.4",
+		"/wp-admin/plugins/hello.php",
+		"/wp-admin/plugins/hello.php?x=1",
+		"Mozilla/5.00 (Nikto/2.1.6)",
+		"/repo/.git/config",
+		"STRASSE",
+		"Straße",
+		"STRAẞE",
+		"\u212As",
+		"ſcan \u212A\u017F",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/87.0.4280.88 Safari/537.36",
+	}
+	regexps := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		regexps = append(regexps, regexp.MustCompile(pattern))
+	}
+	matcher := newRegexpMatcher(regexps)
+	for _, input := range inputs {
+		assert.Equal(t, naiveMatch(regexps, input), matcher.Match(input), "input '%s'", input)
+		for _, re := range regexps {
+			single := newRegexpMatcher([]*regexp.Regexp{re})
+			assert.Equal(t, re.MatchString(input), single.Match(input), "pattern '%s' input '%s'", re, input)
+		}
+	}
+	log.Printf("test 'regexp matcher' : OK")
+}
+
+/*
+the patterns extending tests/test_data_re.txt to the size of real bad user-agents lists : patterns with a plain literal are the best case
+of the prefilter, the real lists also have case insensitive patterns, alternations and patterns without any literal to look for
+*/
+var (
+	literalPattern     = func(i int) string { return fmt.Sprintf(".*Crowd[sS]ec-bot%d.*", i) }
+	foldCasePattern    = func(i int) string { return fmt.Sprintf("(?i)crowdsec-bot%d", i) }
+	alternationPattern = func(i int) string { return fmt.Sprintf("(?:nikto|sqlmap|masscan|zgrab)[-/]%d", i) }
+	noLiteralPattern   = func(i int) string { return fmt.Sprintf("^[a-z]+/[0-9]{%d}\\.[0-9]+ \\(.*\\)$", i%50+1) }
+)
+
+/*the list is extended with the patterns in turn*/
+var benchPatterns = []struct {
+	name     string
+	patterns []func(i int) string
+}{
+	{name: "literal", patterns: []func(i int) string{literalPattern}},
+	{name: "fold-case", patterns: []func(i int) string{foldCasePattern}},
+	{name: "alternation", patterns: []func(i int) string{alternationPattern}},
+	{name: "no-literal", patterns: []func(i int) string{noLiteralPattern}},
+	{name: "mixed", patterns: []func(i int) string{literalPattern, foldCasePattern, alternationPattern, noLiteralPattern}},
+}
+
+func benchmarkRegexps(b *testing.B, count int, patterns []func(i int) string) []*regexp.Regexp {
+	if err := Init(); err != nil {
+		b.Fatal(err)
+	}
+	if err := FileInit(TestFolder, "test_data_re.txt", "regex"); err != nil {
+		b.Fatal(err)
+	}
+	regexps := append([]*regexp.Regexp{}, dataFileRegex["test_data_re.txt"]...)
+	for i := len(regexps); i < count; i++ {
+		regexps = append(regexps, regexp.MustCompile(patterns[i%len(patterns)](i)))
+	}
+	return regexps
+}
+
+var benchInputs = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36",
+	"curl/7.68.0",
+	"sqlmap/1.4.11#stable (http://sqlmap.org)",
+	"test CrowdSec",
+}
+
+func BenchmarkRegexpInFile(b *testing.B) {
+	for _, patterns := range benchPatterns {
+		for _, count := range []int{2, 1000} {
+			regexps := benchmarkRegexps(b, count, patterns.patterns)
+			matcher := newRegexpMatcher(regexps)
+			b.Run(fmt.Sprintf("%s/naive-%d", patterns.name, count), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					for _, input := range benchInputs {
+						naiveMatch(regexps, input)
+					}
+				}
+			})
+			b.Run(fmt.Sprintf("%s/matcher-%d", patterns.name, count), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					for _, input := range benchInputs {
+						matcher.Match(input)
+					}
+				}
+			})
+		}
+	}
+}
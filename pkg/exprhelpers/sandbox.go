@@ -0,0 +1,287 @@
+package exprhelpers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/checker"
+	"github.com/antonmedv/expr/conf"
+	"github.com/antonmedv/expr/parser"
+	"github.com/antonmedv/expr/vm"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//DefaultExprSlowAfter is the time an evaluation can take before being counted as a fault, once it returned
+	DefaultExprSlowAfter = 100 * time.Millisecond
+	//DefaultExprMaxErrors is the number of consecutive faults before the owner of an expression is quarantined
+	DefaultExprMaxErrors = 100
+)
+
+var exprSlowAfter = DefaultExprSlowAfter
+var exprMaxErrors int32 = DefaultExprMaxErrors
+
+/*guards are registered by "kind/name" so that they can be listed and released at runtime*/
+var guards sync.Map
+
+var ExprErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cs_expr_errors_total",
+		Help: "Total expression evaluations that failed, returned a wrong type or were too slow.",
+	},
+	[]string{"kind", "name"},
+)
+
+var ExprQuarantined = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cs_expr_quarantined",
+		Help: "Set to 1 when a parser node or scenario is quarantined because of faulty expressions.",
+	},
+	[]string{"kind", "name"},
+)
+
+//SetExprLimits sets the slow evaluation threshold and the max consecutive errors of guards, zero values keep the defaults
+func SetExprLimits(slowAfter time.Duration, maxErrors int) {
+	exprSlowAfter = DefaultExprSlowAfter
+	if slowAfter > 0 {
+		exprSlowAfter = slowAfter
+	}
+	atomic.StoreInt32(&exprMaxErrors, DefaultExprMaxErrors)
+	if maxErrors > 0 {
+		atomic.StoreInt32(&exprMaxErrors, int32(maxErrors))
+	}
+}
+
+//CheckReturnKind compiles the expression with the type checker and fails if its static return type is known and not one of kinds
+func CheckReturnKind(input string, env map[string]interface{}, kinds ...reflect.Kind) error {
+	tree, err := parser.Parse(input)
+	if err != nil {
+		return err
+	}
+	ret, err := checker.Check(tree, conf.New(env))
+	if err != nil {
+		return err
+	}
+	/*the type of map items, json extraction etc. is only known at runtime*/
+	if ret == nil || ret.Kind() == reflect.Interface {
+		return nil
+	}
+	for _, kind := range kinds {
+		if ret.Kind() == kind {
+			return nil
+		}
+	}
+	return fmt.Errorf("expression '%s' returns %s, expected %v", input, ret, kinds)
+}
+
+//ExprGuard tracks the faults of the expressions of a parser node or a scenario, and quarantines it when it keeps failing
+type ExprGuard struct {
+	Kind        string
+	Name        string
+	errors      int32
+	quarantined int32
+	logger      *log.Entry
+	warnings    *limitedLog //a faulty expression fails for each event, its errors are logged at most once per warningInterval
+}
+
+//QuarantineStatus is the public view of a guard
+type QuarantineStatus struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	Errors      int    `json:"consecutive_errors"`
+	Quarantined bool   `json:"quarantined"`
+}
+
+/*
+NewExprGuard creates and registers the guard of kind (ie. 'parser', 'scenario') and name. Each guard has its own entry : if the name is
+already taken by another guard, a suffix is added to it. The guards of the previous configuration are dropped by ResetExprGuards.
+*/
+func NewExprGuard(kind string, name string, logger *log.Entry) *ExprGuard {
+	if logger == nil {
+		logger = log.WithFields(log.Fields{kind: name})
+	}
+	g := &ExprGuard{Kind: kind, Name: name, logger: logger, warnings: &limitedLog{logger: logger}}
+	for i := 2; ; i++ {
+		if _, taken := guards.LoadOrStore(g.key(), g); !taken {
+			break
+		}
+		g.Name = fmt.Sprintf("%s#%d", name, i)
+	}
+	if g.Name != name {
+		logger.Debugf("%s '%s' is already tracked for expression errors, tracked as '%s'", kind, name, g.Name)
+	}
+	ExprQuarantined.With(prometheus.Labels{"kind": kind, "name": g.Name}).Set(0)
+	return g
+}
+
+//ResetExprGuards drops the registered guards, before the parser nodes and scenarios are (re)loaded
+func ResetExprGuards() {
+	guards.Range(func(key, _ interface{}) bool {
+		guards.Delete(key)
+		return true
+	})
+	ExprQuarantined.Reset()
+}
+
+func (g *ExprGuard) key() string {
+	return g.Kind + "/" + g.Name
+}
+
+//Quarantined returns true if the owner of the guard must be skipped
+func (g *ExprGuard) Quarantined() bool {
+	if g == nil {
+		return false
+	}
+	return atomic.LoadInt32(&g.quarantined) == 1
+}
+
+//Fault records a failed evaluation and quarantines the owner once the max number of consecutive errors is reached
+func (g *ExprGuard) Fault(err error) {
+	if g == nil {
+		return
+	}
+	ExprErrors.With(prometheus.Labels{"kind": g.Kind, "name": g.Name}).Inc()
+	count := atomic.AddInt32(&g.errors, 1)
+	g.warnings.Warningf("expression error (%d/%d) : %s", count, atomic.LoadInt32(&exprMaxErrors), err)
+	if count < atomic.LoadInt32(&exprMaxErrors) {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&g.quarantined, 0, 1) {
+		ExprQuarantined.With(prometheus.Labels{"kind": g.Kind, "name": g.Name}).Set(1)
+		g.logger.Errorf("!!! %s '%s' is QUARANTINED after %d consecutive expression errors, it won't process events until released or reloaded (last error : %s)",
+			g.Kind, g.Name, count, err)
+	}
+}
+
+//Success resets the consecutive errors counter
+func (g *ExprGuard) Success() {
+	if g == nil {
+		return
+	}
+	if atomic.LoadInt32(&g.errors) != 0 {
+		atomic.StoreInt32(&g.errors, 0)
+	}
+}
+
+//Release lifts the quarantine
+func (g *ExprGuard) Release() {
+	atomic.StoreInt32(&g.errors, 0)
+	if atomic.CompareAndSwapInt32(&g.quarantined, 1, 0) {
+		ExprQuarantined.With(prometheus.Labels{"kind": g.Kind, "name": g.Name}).Set(0)
+		g.logger.Warningf("%s '%s' released from quarantine", g.Kind, g.Name)
+	}
+}
+
+/*
+eval runs the program and records errors and slow evaluations as faults. expr's vm can't be interrupted : a slow evaluation
+isn't stopped, it is only accounted once it returned, so that an expression that is always slow gets its owner quarantined.
+check, if not nil, returns the errors that the helpers called by the program couldn't return themselves.
+*/
+func (g *ExprGuard) eval(program *vm.Program, env map[string]interface{}, check func() error) (interface{}, bool, error) {
+	start := time.Now()
+	output, err := expr.Run(program, env)
+	if err == nil && check != nil {
+		err = check()
+	}
+	if err != nil {
+		g.Fault(err)
+		return nil, true, err
+	}
+	if elapsed := time.Since(start); elapsed > exprSlowAfter {
+		g.Fault(fmt.Errorf("evaluation took %s (slow threshold: %s)", elapsed, exprSlowAfter))
+		return output, true, nil
+	}
+	return output, false, nil
+}
+
+//Run evaluates the program, errors and slow evaluations are recorded as faults
+func (g *ExprGuard) Run(program *vm.Program, env map[string]interface{}) (interface{}, error) {
+	output, faulted, err := g.eval(program, env, nil)
+	if !faulted {
+		g.Success()
+	}
+	return output, err
+}
+
+//RunBool evaluates a program that must return a boolean
+func (g *ExprGuard) RunBool(program *vm.Program, env map[string]interface{}) (bool, error) {
+	return g.RunBoolCheck(program, env, nil)
+}
+
+//RunBoolCheck evaluates a program that must return a boolean, the error returned by check after the evaluation is a fault as well
+func (g *ExprGuard) RunBoolCheck(program *vm.Program, env map[string]interface{}, check func() error) (bool, error) {
+	output, faulted, err := g.eval(program, env, check)
+	if err != nil {
+		return false, err
+	}
+	ret, ok := output.(bool)
+	if !ok {
+		err = fmt.Errorf("unexpected non-bool return : %T", output)
+		g.Fault(err)
+		return false, err
+	}
+	if !faulted {
+		g.Success()
+	}
+	return ret, nil
+}
+
+//RunString evaluates a program that must return a string
+func (g *ExprGuard) RunString(program *vm.Program, env map[string]interface{}) (string, error) {
+	output, faulted, err := g.eval(program, env, nil)
+	if err != nil {
+		return "", err
+	}
+	ret, ok := output.(string)
+	if !ok {
+		err = fmt.Errorf("unexpected non-string return : %T", output)
+		g.Fault(err)
+		return "", err
+	}
+	if !faulted {
+		g.Success()
+	}
+	return ret, nil
+}
+
+//GetQuarantine returns the status of the registered guards, sorted by kind and name
+func GetQuarantine() []QuarantineStatus {
+	ret := []QuarantineStatus{}
+	guards.Range(func(_, value interface{}) bool {
+		g := value.(*ExprGuard)
+		ret = append(ret, QuarantineStatus{
+			Kind:        g.Kind,
+			Name:        g.Name,
+			Errors:      int(atomic.LoadInt32(&g.errors)),
+			Quarantined: g.Quarantined(),
+		})
+		return true
+	})
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Kind != ret[j].Kind {
+			return ret[i].Kind < ret[j].Kind
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+//Unquarantine releases the parser node or scenario of kind and name from quarantine
+func Unquarantine(kind string, name string) error {
+	value, ok := guards.Load(kind + "/" + name)
+	if !ok {
+		return fmt.Errorf("no %s named '%s'", kind, name)
+	}
+	g := value.(*ExprGuard)
+	if !g.Quarantined() {
+		return fmt.Errorf("%s '%s' is not quarantined", kind, name)
+	}
+	g.Release()
+	return nil
+}
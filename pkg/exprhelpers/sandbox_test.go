@@ -0,0 +1,93 @@
+package exprhelpers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/antonmedv/expr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReturnKind(t *testing.T) {
+	env := GetExprEnv(map[string]interface{}{"str": "foo", "num": 42, "m": map[string]string{}, "any": map[string]interface{}{}})
+	tests := []struct {
+		name  string
+		code  string
+		kind  reflect.Kind
+		valid bool
+	}{
+		{name: "bool expr is bool", code: "str == 'foo'", kind: reflect.Bool, valid: true},
+		{name: "string expr is not bool", code: "str", kind: reflect.Bool, valid: false},
+		{name: "int expr is not string", code: "num + 1", kind: reflect.String, valid: false},
+		{name: "map item is string", code: "m['x']", kind: reflect.String, valid: true},
+		{name: "interface is checked at runtime", code: "any['x']", kind: reflect.Bool, valid: true},
+		{name: "syntax error", code: "str ==", kind: reflect.Bool, valid: false},
+	}
+	for _, test := range tests {
+		err := CheckReturnKind(test.code, env, test.kind)
+		if test.valid {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+}
+
+func TestExprGuardQuarantine(t *testing.T) {
+	SetExprLimits(0, 3)
+	defer SetExprLimits(0, 0)
+
+	env := GetExprEnv(map[string]interface{}{"value": map[string]interface{}{"str": "foo", "ok": true}})
+	program, err := expr.Compile("value.str", expr.Env(env))
+	require.NoError(t, err)
+	valid, err := expr.Compile("value.ok", expr.Env(env))
+	require.NoError(t, err)
+
+	guard := NewExprGuard("scenario", "test/guard", nil)
+
+	//a success resets the consecutive errors
+	for i := 0; i < 2; i++ {
+		_, err = guard.RunBool(program, env)
+		assert.Error(t, err)
+	}
+	ret, err := guard.RunBool(valid, env)
+	require.NoError(t, err)
+	assert.True(t, ret)
+	assert.False(t, guard.Quarantined())
+
+	for i := 0; i < 3; i++ {
+		_, err = guard.RunBool(program, env)
+		assert.Error(t, err)
+	}
+	assert.True(t, guard.Quarantined())
+
+	found := false
+	for _, status := range GetQuarantine() {
+		if status.Kind == "scenario" && status.Name == "test/guard" {
+			found = true
+			assert.True(t, status.Quarantined)
+		}
+	}
+	assert.True(t, found)
+
+	assert.Error(t, Unquarantine("scenario", "test/unknown"))
+	require.NoError(t, Unquarantine("scenario", "test/guard"))
+	assert.False(t, guard.Quarantined())
+	assert.Error(t, Unquarantine("scenario", "test/guard"))
+}
+
+func TestExprGuardRegistry(t *testing.T) {
+	ResetExprGuards()
+	defer ResetExprGuards()
+
+	first := NewExprGuard("parser", "test/node", nil)
+	second := NewExprGuard("parser", "test/node", nil)
+	assert.Equal(t, "test/node", first.Name)
+	assert.Equal(t, "test/node#2", second.Name)
+	assert.Len(t, GetQuarantine(), 2)
+
+	ResetExprGuards()
+	assert.Len(t, GetQuarantine(), 0)
+	assert.Equal(t, "test/node", NewExprGuard("parser", "test/node", nil).Name)
+}
@@ -0,0 +1,144 @@
+package exprhelpers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//maxCachedRegexps is the number of patterns of RegexpMatch/RegexpCaptures kept compiled
+const maxCachedRegexps = 1000
+
+/*patterns given to RegexpMatch/RegexpCaptures are most of the time static, keep them compiled (invalid ones too, to not retry them)*/
+var regexpCache = newBoundedCache(maxCachedRegexps)
+var regexpLog limitedLog
+
+func getRegexp(pattern string) *regexp.Regexp {
+	re, err := regexpCache.get(pattern, func() (interface{}, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			regexpLog.Warningf("invalid regexp '%s' : %s", pattern, err)
+		}
+		return re, err
+	})
+	if err != nil {
+		return nil
+	}
+	return re.(*regexp.Regexp)
+}
+
+func Lower(s string) string {
+	return strings.ToLower(s)
+}
+
+func Trim(s string) string {
+	return strings.TrimSpace(s)
+}
+
+func Split(s string, sep string) []string {
+	return strings.Split(s, sep)
+}
+
+func Contains(s string, substr string) bool {
+	return strings.Contains(s, substr)
+}
+
+//RegexpMatch returns true if data is matched by pattern (RE2 syntax)
+func RegexpMatch(data string, pattern string) bool {
+	re := getRegexp(pattern)
+	if re == nil {
+		return false
+	}
+	return re.MatchString(data)
+}
+
+//RegexpCaptures returns the capture groups of the first match of pattern in data, or an empty slice
+func RegexpCaptures(data string, pattern string) []string {
+	re := getRegexp(pattern)
+	if re == nil {
+		return []string{}
+	}
+	match := re.FindStringSubmatch(data)
+	if len(match) < 2 {
+		return []string{}
+	}
+	return match[1:]
+}
+
+//UrlDecode returns the url-decoded version of s, or s itself if it can't be decoded
+func UrlDecode(s string) string {
+	ret, err := url.QueryUnescape(s)
+	if err != nil {
+		log.Debugf("unable to url decode '%s' : %s", s, err)
+		return s
+	}
+	return ret
+}
+
+//PathNormalize url-decodes p and cleans it (duplicated slashes, '.' and '..' elements)
+func PathNormalize(p string) string {
+	decoded, err := url.PathUnescape(p)
+	if err != nil {
+		log.Debugf("unable to url decode path '%s' : %s", p, err)
+		decoded = p
+	}
+	decoded = strings.Replace(decoded, "\\", "/", -1)
+	return path.Clean(decoded)
+}
+
+//B64Decode returns the decoded version of a standard base64 string, or an empty string on failure
+func B64Decode(s string) string {
+	ret, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		log.Debugf("unable to base64 decode '%s' : %s", s, err)
+		return ""
+	}
+	return string(ret)
+}
+
+//HexDecode returns the decoded version of an hex string, or an empty string on failure
+func HexDecode(s string) string {
+	ret, err := hex.DecodeString(s)
+	if err != nil {
+		log.Debugf("unable to hex decode '%s' : %s", s, err)
+		return ""
+	}
+	return string(ret)
+}
+
+//Distance returns the levenshtein distance between a and b
+func Distance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,124 @@
+package exprhelpers
+
+import (
+	"testing"
+
+	"github.com/antonmedv/expr"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringHelpers(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]interface{}
+		code   string
+		result interface{}
+	}{
+		{
+			name:   "Lower() test: basic",
+			env:    map[string]interface{}{"value": "CrowdSec"},
+			code:   "Lower(value)",
+			result: "crowdsec",
+		},
+		{
+			name:   "Trim() test: spaces and tabs",
+			env:    map[string]interface{}{"value": " \tcrowdsec \n"},
+			code:   "Trim(value)",
+			result: "crowdsec",
+		},
+		{
+			name:   "Split() test: comma separated",
+			env:    map[string]interface{}{"value": "a,b,c"},
+			code:   "Split(value, ',')[1]",
+			result: "b",
+		},
+		{
+			name:   "Contains() test: substring",
+			env:    map[string]interface{}{"value": "/wp-login.php"},
+			code:   "Contains(value, 'wp-')",
+			result: true,
+		},
+		{
+			name:   "RegexpMatch() test: match",
+			env:    map[string]interface{}{"value": "user=admin"},
+			code:   "RegexpMatch(value, '^user=[a-z]+$')",
+			result: true,
+		},
+		{
+			name:   "RegexpMatch() test: invalid pattern",
+			env:    map[string]interface{}{"value": "user=admin"},
+			code:   "RegexpMatch(value, '(')",
+			result: false,
+		},
+		{
+			name:   "RegexpCaptures() test: capture groups",
+			env:    map[string]interface{}{"value": "user=admin uid=1000"},
+			code:   "RegexpCaptures(value, 'user=([a-z]+) uid=([0-9]+)')[1]",
+			result: "1000",
+		},
+		{
+			name:   "RegexpCaptures() test: no match",
+			env:    map[string]interface{}{"value": "nothing"},
+			code:   "len(RegexpCaptures(value, 'user=([a-z]+)'))",
+			result: 0,
+		},
+		{
+			name:   "UrlDecode() test: encoded query",
+			env:    map[string]interface{}{"value": "id%3D1%27%20OR%201%3D1"},
+			code:   "UrlDecode(value)",
+			result: "id=1' OR 1=1",
+		},
+		{
+			name:   "UrlDecode() test: invalid encoding",
+			env:    map[string]interface{}{"value": "%zz"},
+			code:   "UrlDecode(value)",
+			result: "%zz",
+		},
+		{
+			name:   "PathNormalize() test: traversal",
+			env:    map[string]interface{}{"value": "//static/./img/..%2F..%2Fetc/passwd"},
+			code:   "PathNormalize(value)",
+			result: "/etc/passwd",
+		},
+		{
+			name:   "B64Decode() test: valid",
+			env:    map[string]interface{}{"value": "Y3Jvd2RzZWM="},
+			code:   "B64Decode(value)",
+			result: "crowdsec",
+		},
+		{
+			name:   "B64Decode() test: invalid",
+			env:    map[string]interface{}{"value": "!!"},
+			code:   "B64Decode(value)",
+			result: "",
+		},
+		{
+			name:   "HexDecode() test: valid",
+			env:    map[string]interface{}{"value": "63726f7764736563"},
+			code:   "HexDecode(value)",
+			result: "crowdsec",
+		},
+		{
+			name:   "Distance() test: kitten/sitting",
+			env:    map[string]interface{}{"a": "kitten", "b": "sitting"},
+			code:   "Distance(a, b)",
+			result: 3,
+		},
+		{
+			name:   "Distance() test: empty string",
+			env:    map[string]interface{}{"a": "", "b": "root"},
+			code:   "Distance(a, b)",
+			result: 4,
+		},
+	}
+
+	for _, test := range tests {
+		program, err := expr.Compile(test.code, expr.Env(GetExprEnv(test.env)))
+		require.NoError(t, err)
+		output, err := expr.Run(program, GetExprEnv(test.env))
+		require.NoError(t, err)
+		require.Equal(t, test.result, output, test.name)
+		log.Printf("test '%s' : OK", test.name)
+	}
+}
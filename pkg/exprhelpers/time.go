@@ -0,0 +1,71 @@
+package exprhelpers
+
+import (
+	"fmt"
+	"time"
+)
+
+//maxCachedLocations is the number of time zones of HourOfDay kept loaded
+const maxCachedLocations = 100
+
+var locationCache = newBoundedCache(maxCachedLocations)
+
+var parseDurationLog limitedLog
+
+func getLocation(zone string) (*time.Location, error) {
+	loc, err := locationCache.get(zone, func() (interface{}, error) {
+		return time.LoadLocation(zone)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loc.(*time.Location), nil
+}
+
+func parseTime(ts string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Parse(time.RFC3339, ts)
+	}
+	return t, nil
+}
+
+func Now() time.Time {
+	return time.Now()
+}
+
+//ParseDuration is a binding on time.ParseDuration that returns 0 on failure
+func ParseDuration(s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		parseDurationLog.Warningf("ParseDuration : can't parse duration '%s' : %s", s, err)
+		return 0
+	}
+	return d
+}
+
+/*
+HourOfDay returns the hour (0-23) of ts, a time.Time or an RFC3339 timestamp, in the given zone (ie. 'Europe/Paris', 'UTC' or 'Local').
+An invalid time or zone fails the expression (expr turns the panic into an error) : an hour of -1 would match comparisons.
+*/
+func HourOfDay(ts interface{}, zone string) int {
+	var t time.Time
+
+	switch value := ts.(type) {
+	case time.Time:
+		t = value
+	case string:
+		parsed, err := parseTime(value)
+		if err != nil {
+			panic(fmt.Errorf("HourOfDay : can't parse time '%s' : %s", value, err))
+		}
+		t = parsed
+	default:
+		panic(fmt.Errorf("HourOfDay : expected a time or an RFC3339 timestamp, got %T", ts))
+	}
+	loc, err := getLocation(zone)
+	if err != nil {
+		panic(fmt.Errorf("HourOfDay : unknown time zone '%s' : %s", zone, err))
+	}
+	return t.In(loc).Hour()
+}
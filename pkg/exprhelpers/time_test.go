@@ -0,0 +1,83 @@
+package exprhelpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antonmedv/expr"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeHelpers(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]interface{}
+		code   string
+		result interface{}
+	}{
+		{
+			name:   "ParseDuration() test: valid",
+			env:    map[string]interface{}{"value": "1h30m"},
+			code:   "ParseDuration(value)",
+			result: 90 * time.Minute,
+		},
+		{
+			name:   "ParseDuration() test: invalid",
+			env:    map[string]interface{}{"value": "forever"},
+			code:   "ParseDuration(value)",
+			result: time.Duration(0),
+		},
+		{
+			name:   "HourOfDay() test: UTC",
+			env:    map[string]interface{}{"value": "2020-01-01T10:00:00+00:00"},
+			code:   "HourOfDay(value, 'UTC')",
+			result: 10,
+		},
+		{
+			name:   "HourOfDay() test: other zone",
+			env:    map[string]interface{}{"value": "2020-01-01T23:30:00Z"},
+			code:   "HourOfDay(value, 'Asia/Tokyo')",
+			result: 8,
+		},
+		{
+			name:   "HourOfDay() test: time",
+			env:    map[string]interface{}{"value": time.Date(2020, 1, 1, 23, 30, 0, 0, time.UTC)},
+			code:   "HourOfDay(value, 'Asia/Tokyo')",
+			result: 8,
+		},
+		{
+			name:   "Now() test: is recent",
+			env:    map[string]interface{}{},
+			code:   "Now().Year() >= 2020",
+			result: true,
+		},
+	}
+
+	for _, test := range tests {
+		program, err := expr.Compile(test.code, expr.Env(GetExprEnv(test.env)))
+		require.NoError(t, err)
+		output, err := expr.Run(program, GetExprEnv(test.env))
+		require.NoError(t, err)
+		require.Equal(t, test.result, output, test.name)
+		log.Printf("test '%s' : OK", test.name)
+	}
+
+	//invalid times and zones fail the expression instead of returning an hour
+	failures := []struct {
+		name  string
+		value interface{}
+		code  string
+	}{
+		{name: "syslog timestamp", value: "Jan  1 10:00:00", code: "HourOfDay(value, 'UTC') < 6"},
+		{name: "bad zone", value: "2020-01-01T10:00:00Z", code: "HourOfDay(value, 'Nowhere/Town') < 6"},
+		{name: "not a time", value: 42, code: "HourOfDay(value, 'UTC') < 6"},
+	}
+	for _, test := range failures {
+		env := GetExprEnv(map[string]interface{}{"value": test.value})
+		program, err := expr.Compile(test.code, expr.Env(env))
+		require.NoError(t, err)
+		_, err = expr.Run(program, env)
+		require.Error(t, err, test.name)
+	}
+}
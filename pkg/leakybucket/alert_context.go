@@ -0,0 +1,145 @@
+package leakybucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+//maxContextValueSize is the max size of a meta value in the database of LAPI (cf. the Meta schema)
+const maxContextValueSize = 4095
+
+/*
+By default, the alerts carry all the events of the bucket with their whole meta. The alert context selects what they carry instead :
+the distinct values of expressions on the events (ie. the targeted URIs, that only exist in evt.Parsed) are extracted into the meta
+of the alert, within limits, and only some of the events are kept.
+*/
+type alertContext struct {
+	cfg      *csconfig.AlertContextCfg
+	keys     []string
+	programs []*vm.Program
+}
+
+func newAlertContext(cfg *csconfig.AlertContextCfg) (*alertContext, error) {
+	if err := cfg.Load(); err != nil {
+		return nil, err
+	}
+	ctx := &alertContext{cfg: cfg}
+	for key := range cfg.Context {
+		ctx.keys = append(ctx.keys, key)
+	}
+	sort.Strings(ctx.keys)
+	for _, key := range ctx.keys {
+		program, err := expr.Compile(cfg.Context[key], expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
+		if err != nil {
+			return nil, fmt.Errorf("invalid context '%s' : %v", key, err)
+		}
+		ctx.programs = append(ctx.programs, program)
+	}
+	return ctx, nil
+}
+
+//contextValues returns the distinct values of each key of the context on the events of the queue, in the order they were seen
+func contextValues(leaky *Leaky, ctx *alertContext, queue *Queue) [][]string {
+	redaction := leaky.BucketConfig.redaction
+	values := make([][]string, len(ctx.keys))
+	seen := make([]map[string]bool, len(ctx.keys))
+	for idx := range seen {
+		seen[idx] = make(map[string]bool)
+	}
+	for evtIdx := range queue.Queue {
+		env := exprhelpers.AcquireEvtEnv(&queue.Queue[evtIdx])
+		for idx, program := range ctx.programs {
+			if len(values[idx]) >= ctx.cfg.MaxValues {
+				continue
+			}
+			output, err := leaky.BucketConfig.guard.Run(program, env)
+			if err != nil {
+				leaky.logger.Debugf("failed context '%s' : %v", ctx.keys[idx], err)
+				continue
+			}
+			if output == nil {
+				continue
+			}
+			value := fmt.Sprintf("%v", output)
+			if value == "" {
+				continue
+			}
+			if redaction != nil {
+				var keep bool
+				if value, keep = redaction.RedactMeta(ctx.keys[idx], value); !keep {
+					continue
+				}
+			}
+			if seen[idx][value] {
+				continue
+			}
+			seen[idx][value] = true
+			values[idx] = append(values[idx], value)
+		}
+		exprhelpers.ReleaseEvtEnv(env)
+	}
+	return values
+}
+
+/*
+contextMeta returns the context of the alert : a meta per key, holding the json list of its values. The values beyond the max size
+of the context, or beyond the size of a meta value in the database, are dropped
+*/
+func contextMeta(leaky *Leaky, ctx *alertContext, queue *Queue) models.Meta {
+	meta := models.Meta{}
+	size := 0
+	for idx, values := range contextValues(leaky, ctx, queue) {
+		for len(values) > 0 {
+			serialized, err := json.Marshal(values)
+			if err != nil {
+				leaky.logger.Warningf("while serializing context '%s' : %s", ctx.keys[idx], err)
+				break
+			}
+			if size+len(serialized) <= ctx.cfg.MaxSize && len(serialized) <= maxContextValueSize {
+				meta = append(meta, &models.MetaItems0{Key: ctx.keys[idx], Value: string(serialized)})
+				size += len(serialized)
+				break
+			}
+			values = values[:len(values)-1]
+		}
+		if len(values) == 0 {
+			leaky.logger.Debugf("context '%s' has no value, or is beyond the max size (%d)", ctx.keys[idx], ctx.cfg.MaxSize)
+		}
+	}
+	return meta
+}
+
+//keptEvents returns the events of the queue to keep in the alert
+func keptEvents(queue *Queue, cfg *csconfig.AlertEventsCfg) *Queue {
+	if cfg == nil || cfg.Keep == csconfig.EventsKeepAll {
+		return queue
+	}
+	if cfg.Keep == csconfig.EventsKeepNone {
+		return &Queue{Queue: []types.Event{}, L: queue.L}
+	}
+	count := len(queue.Queue)
+	if count <= cfg.Count {
+		return queue
+	}
+	kept := &Queue{Queue: make([]types.Event, 0, cfg.Count), L: queue.L}
+	switch cfg.Keep {
+	case csconfig.EventsKeepFirst:
+		kept.Queue = append(kept.Queue, queue.Queue[:cfg.Count]...)
+	case csconfig.EventsKeepLast:
+		kept.Queue = append(kept.Queue, queue.Queue[count-cfg.Count:]...)
+	case csconfig.EventsKeepSample:
+		/*evenly spaced events, from the first one*/
+		for i := 0; i < cfg.Count; i++ {
+			kept.Queue = append(kept.Queue, queue.Queue[i*count/cfg.Count])
+		}
+	}
+	return kept
+}
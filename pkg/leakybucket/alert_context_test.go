@@ -0,0 +1,126 @@
+package leakybucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+func contextBucket(t *testing.T, cfg *csconfig.AlertContextCfg, count int) *Leaky {
+	holder := BucketFactory{Name: "test_context", Description: "test_context", Type: "counter", Capacity: -1, Duration: "1m",
+		Filter: "true", AlertContext: cfg}
+	if err := LoadBucket(&holder); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	bucket := NewTimeMachine(holder)
+	bucket.logger = holder.logger
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < count; i++ {
+		bucket.Queue.Add(types.Event{Time: start.Add(time.Duration(i) * time.Second),
+			Meta:   map[string]string{"source_ip": "1.2.3.4", "seq": fmt.Sprintf("%d", i)},
+			Parsed: map[string]string{"request": fmt.Sprintf("/page-%d", i%3), "verb": "GET"}})
+	}
+	bucket.First_ts = start
+	bucket.Ovflw_ts = start.Add(time.Minute)
+	bucket.Total_count = count
+	return bucket
+}
+
+func TestAlertContext(t *testing.T) {
+	cfg := &csconfig.AlertContextCfg{
+		Context:   map[string]string{"target_uri": "evt.Parsed.request", "method": "evt.Parsed.verb", "missing": "evt.Parsed.nope"},
+		MaxValues: 2,
+		Events:    &csconfig.AlertEventsCfg{Keep: csconfig.EventsKeepLast, Count: 3},
+	}
+	bucket := contextBucket(t, cfg, 10)
+	alert, err := NewAlert(bucket, bucket.Queue)
+	if err != nil {
+		t.Fatalf("while creating alert : %s", err)
+	}
+	meta := map[string]string{}
+	for _, item := range alert.Alert.Meta {
+		meta[item.Key] = item.Value
+	}
+	/*the values are deduplicated and capped, the keys without value are left out*/
+	expected := map[string]string{"method": `["GET"]`, "target_uri": `["/page-0","/page-1"]`}
+	if fmt.Sprintf("%v", meta) != fmt.Sprintf("%v", expected) {
+		t.Fatalf("expected meta %v, got %v", expected, meta)
+	}
+	if len(alert.Alert.Events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(alert.Alert.Events))
+	}
+	for _, item := range alert.Alert.Events[0].Meta {
+		if item.Key == "seq" && item.Value != "7" {
+			t.Fatalf("expected the last events, first one is %s", item.Value)
+		}
+	}
+}
+
+func TestAlertContextLimits(t *testing.T) {
+	/*the values beyond the max size are dropped*/
+	cfg := &csconfig.AlertContextCfg{Context: map[string]string{"target_uri": "evt.Parsed.request"}, MaxSize: 25}
+	bucket := contextBucket(t, cfg, 10)
+	alert, err := NewAlert(bucket, bucket.Queue)
+	if err != nil {
+		t.Fatalf("while creating alert : %s", err)
+	}
+	if len(alert.Alert.Meta) != 1 || alert.Alert.Meta[0].Value != `["/page-0","/page-1"]` {
+		t.Fatalf("unexpected context %s", alert.Alert.GetMeta("target_uri"))
+	}
+	if len(alert.Alert.Events) != 10 {
+		t.Fatalf("expected all the events, got %d", len(alert.Alert.Events))
+	}
+
+	tests := []struct {
+		keep     string
+		expected []int
+	}{
+		{csconfig.EventsKeepFirst, []int{0, 1, 2, 3}},
+		{csconfig.EventsKeepLast, []int{6, 7, 8, 9}},
+		{csconfig.EventsKeepSample, []int{0, 2, 5, 7}},
+		{csconfig.EventsKeepNone, []int{}},
+	}
+	for _, test := range tests {
+		events := &csconfig.AlertEventsCfg{Keep: test.keep, Count: 4}
+		kept := keptEvents(bucket.Queue, events)
+		seqs := []int{}
+		for _, evt := range kept.Queue {
+			var seq int
+			fmt.Sscanf(evt.Meta["seq"], "%d", &seq)
+			seqs = append(seqs, seq)
+		}
+		if fmt.Sprintf("%v", seqs) != fmt.Sprintf("%v", test.expected) {
+			t.Fatalf("%s : expected events %v, got %v", test.keep, test.expected, seqs)
+		}
+	}
+
+	/*whatever the max size, a meta value fits in the database*/
+	cfg = &csconfig.AlertContextCfg{Context: map[string]string{"target_uri": "evt.Parsed.long"}, MaxSize: 100000}
+	bucket = contextBucket(t, cfg, 10)
+	for idx := range bucket.Queue.Queue {
+		bucket.Queue.Queue[idx].Parsed["long"] = fmt.Sprintf("/%d/%s", idx, strings.Repeat("a", 1000))
+	}
+	alert, err = NewAlert(bucket, bucket.Queue)
+	if err != nil {
+		t.Fatalf("while creating alert : %s", err)
+	}
+	if len(alert.Alert.Meta) != 1 || len(alert.Alert.Meta[0].Value) > maxContextValueSize {
+		t.Fatalf("expected a context of at most %d bytes, got %d", maxContextValueSize, len(alert.Alert.GetMeta("target_uri")))
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(alert.Alert.Meta[0].Value), &values); err != nil || len(values) != 4 {
+		t.Fatalf("expected 4 values, got %d (%v)", len(values), err)
+	}
+
+	if err := (&csconfig.AlertContextCfg{Events: &csconfig.AlertEventsCfg{Keep: "random"}}).Load(); err == nil {
+		t.Fatalf("expected unknown keep to fail")
+	}
+	if err := (&csconfig.AlertContextCfg{Context: map[string]string{types.IncidentTargetMeta: "evt.Parsed.user"}}).Load(); err == nil {
+		t.Fatalf("expected reserved context key to fail")
+	}
+}
@@ -0,0 +1,326 @@
+package leakybucket
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	//BaselineEWMA learns the mean rate of the partition
+	BaselineEWMA = "ewma"
+	//BaselineSeasonal learns the mean rate of the partition for each hour of the day
+	BaselineSeasonal = "seasonal"
+
+	DefaultAnomalyAlpha    = 0.1
+	DefaultAnomalyFactor   = 3
+	DefaultAnomalyTraining = "24h"
+	//DefaultAnomalyMaxPartitions is the max number of partitions learned by a scenario without max_partitions nor max_buckets
+	DefaultAnomalyMaxPartitions = 100000
+
+	//maxSkippedWindows bounds the work done to account for the windows without events of an idle partition
+	maxSkippedWindows = 10000
+)
+
+//AnomalyCfg configures the baselines of 'anomaly' buckets
+type AnomalyCfg struct {
+	Baseline      string  `yaml:"baseline,omitempty"`       //ewma (default) or seasonal
+	Alpha         float64 `yaml:"alpha,omitempty"`          //smoothing factor of the moving averages, defaults to 0.1
+	Training      string  `yaml:"training,omitempty"`       //how long a partition is observed before it can overflow, defaults to 24h
+	Factor        float64 `yaml:"factor,omitempty"`         //the bucket overflows when the rate of its window exceeds the baseline by this factor, defaults to 3
+	MinCount      int     `yaml:"min_count,omitempty"`      //the minimum number of events in the window to overflow
+	MaxPartitions int     `yaml:"max_partitions,omitempty"` //the max number of partitions learned, beyond the least recently seen are forgotten. Defaults to max_buckets
+	training      time.Duration
+}
+
+var AnomalyBaselines = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cs_anomaly_baselines",
+		Help: "Number of partitions learned by anomaly buckets.",
+	},
+	[]string{"name"},
+)
+
+func (a *AnomalyCfg) Validate() error {
+	switch a.Baseline {
+	case "":
+		a.Baseline = BaselineEWMA
+	case BaselineEWMA, BaselineSeasonal:
+	default:
+		return fmt.Errorf("unknown baseline '%s', must be %s or %s", a.Baseline, BaselineEWMA, BaselineSeasonal)
+	}
+	if a.Alpha == 0 {
+		a.Alpha = DefaultAnomalyAlpha
+	}
+	if a.Alpha < 0 || a.Alpha > 1 {
+		return fmt.Errorf("alpha must be between 0 and 1, got %f", a.Alpha)
+	}
+	if a.Factor == 0 {
+		a.Factor = DefaultAnomalyFactor
+	}
+	if a.Factor <= 1 {
+		return fmt.Errorf("factor must be greater than 1, got %f", a.Factor)
+	}
+	if a.Training == "" {
+		a.Training = DefaultAnomalyTraining
+	}
+	training, err := time.ParseDuration(a.Training)
+	if err != nil {
+		return fmt.Errorf("invalid training '%s' : %s", a.Training, err)
+	}
+	if training < 0 {
+		return fmt.Errorf("training must be positive, got %s", a.Training)
+	}
+	a.training = training
+	if a.MinCount < 0 {
+		return fmt.Errorf("min_count must be positive, got %d", a.MinCount)
+	}
+	if a.MaxPartitions < 0 {
+		return fmt.Errorf("max_partitions must be positive, got %d", a.MaxPartitions)
+	}
+	return nil
+}
+
+//AnomalyBaseline is what an 'anomaly' bucket learned about a partition
+type AnomalyBaseline struct {
+	Since     time.Time `json:"since"`                //start of the first window of the partition
+	Window    time.Time `json:"window"`               //start of the current window
+	Count     int       `json:"count"`                //number of events of the current window
+	Anomalous bool      `json:"anomalous"`            //the current window overflowed, it isn't learned
+	Windows   int       `json:"windows"`              //number of windows learned
+	Mean      float64   `json:"mean"`                 //moving average of the number of events per window
+	Hours     []float64 `json:"hours,omitempty"`      //moving average of the number of events per window, for each hour of the day (seasonal)
+	HoursSeen []int     `json:"hours_seen,omitempty"` //number of windows learned for each hour of the day (seasonal)
+}
+
+/*
+Anomaly overflows when the number of events of a partition within a window (the duration of the scenario) exceeds what was learned for
+this partition by factor. The baselines are kept by the scenario rather than by the buckets, so that they outlive them, and are written in the buckets snapshots.
+Partitions can't overflow before they have been observed for the training duration, and the windows that overflowed aren't learned.
+Like the live buckets, the number of partitions learned is capped, so that a flood of distinct partitions can't exhaust the memory.
+*/
+type Anomaly struct {
+	name          string
+	cfg           *AnomalyCfg
+	window        time.Duration
+	retention     time.Duration //partitions without events for that long are forgotten
+	maxPartitions int           //beyond, the least recently seen partitions are forgotten
+	baselines     map[string]*AnomalyBaseline
+	lastPrune     time.Time
+	lock          sync.Mutex //the baselines are shared by the buckets of the scenario
+	DumbProcessor
+}
+
+func NewAnomaly(bucketFactory *BucketFactory) (*Anomaly, error) {
+	if bucketFactory.Anomaly == nil {
+		return nil, fmt.Errorf("anomaly bucket must have an anomaly configuration")
+	}
+	if err := bucketFactory.Anomaly.Validate(); err != nil {
+		return nil, err
+	}
+	if bucketFactory.duration == 0 {
+		return nil, fmt.Errorf("anomaly bucket must have a duration")
+	}
+	retention := bucketFactory.Anomaly.training
+	if retention < 24*time.Hour {
+		retention = 24 * time.Hour
+	}
+	maxPartitions := bucketFactory.Anomaly.MaxPartitions
+	if maxPartitions == 0 {
+		maxPartitions = bucketFactory.MaxBuckets
+	}
+	if maxPartitions == 0 {
+		maxPartitions = DefaultAnomalyMaxPartitions
+	}
+	return &Anomaly{
+		name:          bucketFactory.Name,
+		cfg:           bucketFactory.Anomaly,
+		window:        bucketFactory.duration,
+		retention:     retention,
+		maxPartitions: maxPartitions,
+		baselines:     make(map[string]*AnomalyBaseline),
+	}, nil
+}
+
+func (b *AnomalyBaseline) learn(cfg *AnomalyCfg, window time.Time, count int) {
+	update := func(mean float64, seen int) float64 {
+		if seen == 0 {
+			return float64(count)
+		}
+		return cfg.Alpha*float64(count) + (1-cfg.Alpha)*mean
+	}
+	if cfg.Baseline == BaselineSeasonal {
+		if len(b.Hours) != 24 || len(b.HoursSeen) != 24 {
+			b.Hours = make([]float64, 24)
+			b.HoursSeen = make([]int, 24)
+		}
+		hour := window.UTC().Hour()
+		b.Hours[hour] = update(b.Hours[hour], b.HoursSeen[hour])
+		b.HoursSeen[hour]++
+	}
+	b.Mean = update(b.Mean, b.Windows)
+	b.Windows++
+}
+
+//baseline returns the expected number of events for the window, and false if it isn't known yet
+func (b *AnomalyBaseline) baseline(cfg *AnomalyCfg) (float64, bool) {
+	if b.Windows == 0 || b.Window.Sub(b.Since) < cfg.training {
+		return 0, false
+	}
+	if cfg.Baseline == BaselineSeasonal {
+		hour := b.Window.UTC().Hour()
+		if len(b.HoursSeen) != 24 || b.HoursSeen[hour] == 0 {
+			return 0, false
+		}
+		return b.Hours[hour], true
+	}
+	return b.Mean, true
+}
+
+//advance closes the windows that ended before now, and learns them
+func (a *Anomaly) advance(b *AnomalyBaseline, now time.Time) {
+	if now.Before(b.Window.Add(a.window)) {
+		return
+	}
+	elapsed := int64(now.Sub(b.Window) / a.window)
+	if !b.Anomalous {
+		b.learn(a.cfg, b.Window, b.Count)
+	}
+	/*the windows without any event are learned as well*/
+	skipped := elapsed - 1
+	if skipped > maxSkippedWindows {
+		skipped = maxSkippedWindows
+	}
+	if a.cfg.Baseline == BaselineEWMA && skipped > 0 {
+		b.Mean *= math.Pow(1-a.cfg.Alpha, float64(skipped))
+		b.Windows += int(skipped)
+	} else {
+		for i := int64(1); i <= skipped; i++ {
+			b.learn(a.cfg, b.Window.Add(time.Duration(elapsed-skipped-1+i)*a.window), 0)
+		}
+	}
+	b.Window = b.Window.Add(time.Duration(elapsed) * a.window)
+	b.Count = 0
+	b.Anomalous = false
+}
+
+//prune forgets the partitions that didn't get any event for the retention duration
+func (a *Anomaly) prune(now time.Time) {
+	if now.Sub(a.lastPrune) < a.window {
+		return
+	}
+	a.lastPrune = now
+	for key, b := range a.baselines {
+		if now.Sub(b.Window) > a.retention {
+			delete(a.baselines, key)
+		}
+	}
+	AnomalyBaselines.With(prometheus.Labels{"name": a.name}).Set(float64(len(a.baselines)))
+}
+
+/*
+evict forgets the least recently seen partitions to make room for extra new ones. Like the live buckets, they are evicted by batches of
+1% of the cap, so that the baselines aren't sorted for each new partition during a flood.
+*/
+func (a *Anomaly) evict(extra int) {
+	if len(a.baselines)+extra <= a.maxPartitions {
+		return
+	}
+	batch := a.maxPartitions / 100
+	if batch < len(a.baselines)+extra-a.maxPartitions {
+		batch = len(a.baselines) + extra - a.maxPartitions
+	}
+	keys := make([]string, 0, len(a.baselines))
+	for key := range a.baselines {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return a.baselines[keys[i]].Window.Before(a.baselines[keys[j]].Window)
+	})
+	if batch > len(keys) {
+		batch = len(keys)
+	}
+	for _, key := range keys[:batch] {
+		delete(a.baselines, key)
+	}
+	BucketsEvicted.With(prometheus.Labels{"name": a.name}).Add(float64(batch))
+}
+
+func (a *Anomaly) OnBucketPour(bucketFactory *BucketFactory) func(types.Event, *Leaky) *types.Event {
+	return func(msg types.Event, l *Leaky) *types.Event {
+		now := eventTime(l, msg)
+
+		a.lock.Lock()
+		a.prune(now)
+		b, ok := a.baselines[l.Partition]
+		if !ok {
+			a.evict(1)
+			b = &AnomalyBaseline{Since: now, Window: now}
+			a.baselines[l.Partition] = b
+			AnomalyBaselines.With(prometheus.Labels{"name": a.name}).Set(float64(len(a.baselines)))
+		}
+		a.advance(b, now)
+		b.Count++
+		count := b.Count
+		baseline, trained := b.baseline(a.cfg)
+		anomalous := trained && !b.Anomalous && count >= a.cfg.MinCount && float64(count) > baseline*a.cfg.Factor
+		if anomalous {
+			b.Anomalous = true
+		}
+		a.lock.Unlock()
+
+		if !anomalous {
+			l.logger.Tracef("%d events in window (baseline %.2f, trained:%t)", count, baseline, trained)
+			return &msg
+		}
+
+		l.logger.Infof("%d events in window, baseline is %.2f : bucket overflow", count, baseline)
+		l.Total_count += 1
+		if l.First_ts.IsZero() {
+			l.First_ts = now
+		}
+		l.Last_ts = now
+		l.Ovflw_ts = now
+		l.Queue.Add(msg)
+		l.Out <- l.Queue
+		return nil
+	}
+}
+
+//dumpBaselines returns a copy of the baselines of the partitions
+func (a *Anomaly) dumpBaselines() map[string]AnomalyBaseline {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	ret := make(map[string]AnomalyBaseline, len(a.baselines))
+	for key, b := range a.baselines {
+		copied := *b
+		copied.Hours = append([]float64(nil), b.Hours...)
+		copied.HoursSeen = append([]int(nil), b.HoursSeen...)
+		ret[key] = copied
+	}
+	return ret
+}
+
+//restoreBaselines restores the baselines of the partitions that weren't forgotten at now
+func (a *Anomaly) restoreBaselines(baselines map[string]AnomalyBaseline, now time.Time) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	restored := 0
+	for key, b := range baselines {
+		if now.Sub(b.Window) > a.retention {
+			continue
+		}
+		b := b
+		a.baselines[key] = &b
+		restored++
+	}
+	/*the cap may have been lowered since the dump*/
+	a.evict(0)
+	AnomalyBaselines.With(prometheus.Labels{"name": a.name}).Set(float64(len(a.baselines)))
+	return restored
+}
@@ -0,0 +1,193 @@
+package leakybucket
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+func loadAnomalyHolder(t *testing.T, cfg AnomalyCfg) BucketFactory {
+	holder := BucketFactory{Name: "test_anomaly", Description: "test_anomaly", Type: "anomaly", Capacity: 100, Duration: "1m",
+		Filter: "true", GroupBy: "evt.Meta.source_ip", Anomaly: &cfg}
+	if err := LoadBucket(&holder); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	holder.ret = make(chan types.Event, 10)
+	return holder
+}
+
+func anomalyProcessor(holder BucketFactory) *Anomaly {
+	for _, processor := range holder.processors {
+		if anomaly, ok := processor.(*Anomaly); ok {
+			return anomaly
+		}
+	}
+	return nil
+}
+
+//pourWindow pours count events of partition in the window starting at start, and returns the number of overflows
+func pourWindow(holder BucketFactory, partition string, start time.Time, count int) int {
+	pour := anomalyProcessor(holder).OnBucketPour(&holder)
+	overflows := 0
+	for i := 0; i < count; i++ {
+		bucket := NewTimeMachine(holder)
+		bucket.Partition = partition
+		bucket.logger = holder.logger
+		ts, _ := start.Add(time.Duration(i) * time.Second).MarshalText()
+		if pour(types.Event{MarshaledTime: string(ts)}, bucket) == nil {
+			overflows++
+		}
+	}
+	return overflows
+}
+
+func TestAnomalyBaseline(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	holder := loadAnomalyHolder(t, AnomalyCfg{Training: "10m", Factor: 3, MinCount: 5})
+
+	/*no overflow while training, whatever the rate*/
+	for i := 0; i < 10; i++ {
+		if ovflw := pourWindow(holder, "1.2.3.4", start.Add(time.Duration(i)*time.Minute), 10); ovflw != 0 {
+			t.Fatalf("window %d : overflow while training", i)
+		}
+	}
+	if ovflw := pourWindow(holder, "1.2.3.4", start.Add(10*time.Minute), 25); ovflw != 0 {
+		t.Fatalf("overflow below the baseline by factor")
+	}
+	/*only the first event above the threshold overflows*/
+	if ovflw := pourWindow(holder, "1.2.3.4", start.Add(11*time.Minute), 40); ovflw != 1 {
+		t.Fatalf("expected 1 overflow above the baseline by factor, got %d", ovflw)
+	}
+	/*the overflowing window isn't learned*/
+	baselines := anomalyProcessor(holder).dumpBaselines()
+	if mean := baselines["1.2.3.4"].Mean; mean < 10 || mean > 12 {
+		t.Fatalf("unexpected baseline %f", mean)
+	}
+	/*other partitions have their own baseline*/
+	if ovflw := pourWindow(holder, "1.2.3.5", start.Add(12*time.Minute), 40); ovflw != 0 {
+		t.Fatalf("overflow of an untrained partition")
+	}
+
+	/*idle windows lower the baseline*/
+	if ovflw := pourWindow(holder, "1.2.3.4", start.Add(60*time.Minute), 5); ovflw != 1 {
+		t.Fatalf("expected 1 overflow after idle windows, got %d", ovflw)
+	}
+}
+
+func TestSeasonalBaseline(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	holder := loadAnomalyHolder(t, AnomalyCfg{Baseline: BaselineSeasonal, Training: "1h", Factor: 2})
+
+	/*busy at 00:xx, quiet at 01:xx*/
+	for i := 0; i < 120; i++ {
+		count := 20
+		if i >= 60 {
+			count = 2
+		}
+		pourWindow(holder, "1.2.3.4", start.Add(time.Duration(i)*time.Minute), count)
+	}
+	/*the next day, 30 events are normal at 00:xx, but not at 01:xx*/
+	if ovflw := pourWindow(holder, "1.2.3.4", start.Add(24*time.Hour), 30); ovflw != 0 {
+		t.Fatalf("overflow within the baseline of the hour")
+	}
+	if ovflw := pourWindow(holder, "1.2.3.4", start.Add(25*time.Hour), 30); ovflw != 1 {
+		t.Fatalf("expected 1 overflow above the baseline of the hour, got %d", ovflw)
+	}
+}
+
+func TestAnomalyMaxPartitions(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	holder := loadAnomalyHolder(t, AnomalyCfg{Training: "10m", MaxPartitions: 3})
+
+	for i := 0; i < 3; i++ {
+		pourWindow(holder, fmt.Sprintf("1.2.3.%d", i), start.Add(time.Duration(i)*time.Minute), 1)
+	}
+	/*1.2.3.0 is seen again, so 1.2.3.1 is the least recently seen*/
+	pourWindow(holder, "1.2.3.0", start.Add(5*time.Minute), 1)
+	pourWindow(holder, "1.2.3.9", start.Add(6*time.Minute), 1)
+
+	baselines := anomalyProcessor(holder).dumpBaselines()
+	if len(baselines) != 3 {
+		t.Fatalf("expected 3 partitions, got %d", len(baselines))
+	}
+	if _, ok := baselines["1.2.3.1"]; ok {
+		t.Fatalf("the least recently seen partition wasn't forgotten")
+	}
+	for _, partition := range []string{"1.2.3.0", "1.2.3.2", "1.2.3.9"} {
+		if _, ok := baselines[partition]; !ok {
+			t.Fatalf("partition %s was forgotten", partition)
+		}
+	}
+
+	/*defaults to max_buckets*/
+	holder = BucketFactory{Name: "test_anomaly", Description: "test_anomaly", Type: "anomaly", Capacity: 100, Duration: "1m",
+		Filter: "true", GroupBy: "evt.Meta.source_ip", Anomaly: &AnomalyCfg{}, MaxBuckets: 50}
+	if err := LoadBucket(&holder); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	if max := anomalyProcessor(holder).maxPartitions; max != 50 {
+		t.Fatalf("expected max partitions of 50, got %d", max)
+	}
+}
+
+func TestAnomalySnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crowdsec-snapshot-")
+	if err != nil {
+		t.Fatalf("while creating temp dir : %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "buckets_state.json")
+
+	start := time.Now().Add(-time.Hour)
+	holder := loadAnomalyHolder(t, AnomalyCfg{Training: "10m"})
+	for i := 0; i < 20; i++ {
+		pourWindow(holder, "1.2.3.4", start.Add(time.Duration(i)*time.Minute), 10)
+	}
+	buckets := NewBuckets()
+	defer ShutdownAllBuckets(buckets)
+	if err := SnapshotBucketsState(path, buckets, []BucketFactory{holder}); err != nil {
+		t.Fatalf("while writing snapshot : %s", err)
+	}
+
+	/*restart*/
+	restored := loadAnomalyHolder(t, AnomalyCfg{Training: "10m"})
+	if err := RestoreBucketsSnapshot(path, time.Hour, buckets, []BucketFactory{restored}); err != nil {
+		t.Fatalf("while restoring snapshot : %s", err)
+	}
+	before := anomalyProcessor(holder).dumpBaselines()["1.2.3.4"]
+	after, ok := anomalyProcessor(restored).dumpBaselines()["1.2.3.4"]
+	if !ok || after.Windows != before.Windows || after.Mean != before.Mean || !after.Since.Equal(before.Since) {
+		t.Fatalf("baseline wasn't restored : %+v", after)
+	}
+}
+
+func TestAnomalyDump(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	holder := loadAnomalyHolder(t, AnomalyCfg{Training: "10m"})
+	for i := 0; i < 20; i++ {
+		pourWindow(holder, "1.2.3.4", start.Add(time.Duration(i)*time.Minute), 10)
+	}
+	buckets := NewBuckets()
+	defer ShutdownAllBuckets(buckets)
+	file, err := DumpBucketsStateAt(time.Now(), ".", buckets, []BucketFactory{holder})
+	if err != nil {
+		t.Fatalf("while dumping : %s", err)
+	}
+	defer os.Remove(file)
+
+	/*reload*/
+	restored := loadAnomalyHolder(t, AnomalyCfg{Training: "10m"})
+	if err := LoadBucketsState(file, NewBuckets(), []BucketFactory{restored}); err != nil {
+		t.Fatalf("while loading dump : %s", err)
+	}
+	before := anomalyProcessor(holder).dumpBaselines()["1.2.3.4"]
+	after, ok := anomalyProcessor(restored).dumpBaselines()["1.2.3.4"]
+	if !ok || after.Windows != before.Windows || after.Mean != before.Mean || !after.Since.Equal(before.Since) {
+		t.Fatalf("baseline wasn't restored : %+v", after)
+	}
+}
@@ -2,19 +2,21 @@ package leakybucket
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 )
 
 type HiddenKey struct {
-	key        string
-	expiration time.Time
+	Key        string    `json:"key"`
+	Expiration time.Time `json:"expiration"`
 }
 
 type Blackhole struct {
 	duration   time.Duration
 	hiddenKeys []HiddenKey
+	lock       sync.Mutex //the hidden keys are shared by the buckets of the scenario
 	DumbProcessor
 }
 
@@ -38,20 +40,22 @@ func (bl *Blackhole) OnBucketOverflow(bucketFactory *BucketFactory) func(*Leaky,
 	return func(leaky *Leaky, alert types.RuntimeAlert, queue *Queue) (types.RuntimeAlert, *Queue) {
 		var blackholed bool = false
 		var tmp []HiddenKey
+		bl.lock.Lock()
+		defer bl.lock.Unlock()
 		// search if we are blackholed and refresh the slice
 		for _, element := range bl.hiddenKeys {
 
-			if element.key == leaky.Mapkey {
-				if element.expiration.After(leaky.Ovflw_ts) {
-					leaky.logger.Debugf("Overflow discarded, still blackholed for %s", element.expiration.Sub(leaky.Ovflw_ts))
+			if element.Key == leaky.Mapkey {
+				if element.Expiration.After(leaky.Ovflw_ts) {
+					leaky.logger.Debugf("Overflow discarded, still blackholed for %s", element.Expiration.Sub(leaky.Ovflw_ts))
 					blackholed = true
 				}
 			}
 
-			if element.expiration.After(leaky.Ovflw_ts) {
+			if element.Expiration.After(leaky.Ovflw_ts) {
 				tmp = append(tmp, element)
 			} else {
-				leaky.logger.Debugf("%s left blackhole %s ago", element.key, leaky.Ovflw_ts.Sub(element.expiration))
+				leaky.logger.Debugf("%s left blackhole %s ago", element.Key, leaky.Ovflw_ts.Sub(element.Expiration))
 
 			}
 		}
@@ -69,3 +73,30 @@ func (bl *Blackhole) OnBucketOverflow(bucketFactory *BucketFactory) func(*Leaky,
 	}
 
 }
+
+//hiddenKeysAt returns the partitions that are still blackholed at now
+func (bl *Blackhole) hiddenKeysAt(now time.Time) []HiddenKey {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	ret := []HiddenKey{}
+	for _, element := range bl.hiddenKeys {
+		if element.Expiration.After(now) {
+			ret = append(ret, element)
+		}
+	}
+	return ret
+}
+
+//restoreHiddenKeys blackholes again the partitions of keys that are not expired at now
+func (bl *Blackhole) restoreHiddenKeys(keys []HiddenKey, now time.Time) int {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	restored := 0
+	for _, element := range keys {
+		if element.Expiration.After(now) {
+			bl.hiddenKeys = append(bl.hiddenKeys, element)
+			restored++
+		}
+	}
+	return restored
+}
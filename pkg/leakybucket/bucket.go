@@ -1,11 +1,12 @@
 package leakybucket
 
 import (
-	"fmt"
 	"sync/atomic"
 	"time"
 
 	//"log"
+	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/crowdsecurity/crowdsec/pkg/sketch"
 	"github.com/crowdsecurity/crowdsec/pkg/time/rate"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 	"github.com/goombaio/namegenerator"
@@ -21,32 +22,28 @@ import (
 const (
 	LIVE = iota
 	TIMEMACHINE
+	HYBRID //live, but leaking according to the time of the events (cf. eventtime.go)
 )
 
 //Leaky represents one instance of a bucket
 type Leaky struct {
 	Name string
-	Mode int //LIVE or TIMEMACHINE
+	Mode int //LIVE, TIMEMACHINE or HYBRID
 	//the limiter is what holds the proper "leaky aspect", it determines when/if we can pour objects
 	Limiter         rate.RateLimiter `json:"-"`
 	SerializedState rate.Lstate
 	//Queue is used to held the cache of objects in the bucket, it is used to know 'how many' objects we have in buffer.
 	Queue *Queue
-	//Leaky buckets are receiving message through a chan
-	In chan types.Event `json:"-"`
 	//Leaky buckets are pushing their overflows through a chan
 	Out chan *Queue `json:"-"`
 	// shared for all buckets (the idea is to kill this afterwards)
-	AllOut     chan types.Event `json:"-"`
-	KillSwitch chan bool        `json:"-"`
+	AllOut chan types.Event `json:"-"`
 	//max capacity (for burst)
 	Capacity int
 	//CacheRatio is the number of elements that should be kept in memory (compared to capacity)
 	CacheSize int
 	//the unique identifier of the bucket (a hash)
-	Mapkey string
-	// chan for signaling
-	Signal       chan bool `json:"-"`
+	Mapkey       string
 	Reprocess    bool
 	Simulated    bool
 	Uuid         string
@@ -65,6 +62,24 @@ type Leaky struct {
 	scopeType       types.ScopeType
 	hash            string
 	scenarioVersion string
+
+	//Partition is the result of the groupby of the scenario for this bucket (Mapkey is a hash of it)
+	Partition string
+	//SequenceStep and SequenceCount are the progress of 'sequence' buckets
+	SequenceStep  int
+	SequenceCount int
+	//DistinctFilter holds the values seen by probabilistic distinct, Cardinality counts the values of 'cardinality' buckets
+	DistinctFilter *sketch.BloomFilter `json:",omitempty"`
+	Cardinality    *sketch.HyperLogLog `json:",omitempty"`
+	//Sources are the distinct sources recorded by the buckets of scenarios with a sources configuration
+	Sources map[string]models.Source `json:",omitempty"`
+
+	//deadline is when the bucket expires if it doesn't get any event, it is owned by the shard of the bucket
+	deadline  time.Time
+	scheduled bool
+	//dead is set by the shard when the bucket ends, killed once it has been asked to
+	dead   int32
+	killed int32
 }
 
 var BucketsPour = prometheus.NewCounterVec(
@@ -91,6 +106,14 @@ var BucketsUnderflow = prometheus.NewCounterVec(
 	[]string{"name"},
 )
 
+var BucketsCanceled = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cs_bucket_canceled_total",
+		Help: "Total buckets canceled by their cancel_on condition.",
+	},
+	[]string{"name"},
+)
+
 var BucketsInstanciation = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "cs_bucket_created_total",
@@ -107,8 +130,12 @@ var BucketsCurrentCount = prometheus.NewGaugeVec(
 	[]string{"name"},
 )
 
+//LeakyRoutineCount is the number of live buckets, including the ones waiting to send their overflow
 var LeakyRoutineCount int64
 
+//liveBuckets is the number of buckets that didn't end yet, the global cap is checked against it
+var liveBuckets int64
+
 // Newleaky creates a new leaky bucket from a BucketFactory
 // Events created by the bucket (overflow, bucket empty) are sent to a chan defined by BucketFactory
 // The leaky bucket implementation is based on rate limiter (see https://godoc.org/golang.org/x/time/rate)
@@ -122,6 +149,10 @@ func FromFactory(bucketFactory BucketFactory) *Leaky {
 	var limiter rate.RateLimiter
 	//golang rate limiter. It's mainly intended for http rate limiter
 	Qsize := bucketFactory.Capacity
+	if bucketFactory.Type == "sequence" {
+		//the queue holds the events of the steps
+		Qsize = -1
+	}
 	if bucketFactory.CacheSize > 0 {
 		//cache is smaller than actual capacity
 		if bucketFactory.CacheSize <= bucketFactory.Capacity {
@@ -131,7 +162,8 @@ func FromFactory(bucketFactory BucketFactory) *Leaky {
 			Qsize = bucketFactory.CacheSize
 		}
 	}
-	if bucketFactory.Capacity == -1 {
+	if bucketFactory.Capacity == -1 || bucketFactory.Type == "sequence" || bucketFactory.Type == "conditional" || bucketFactory.Type == "cardinality" ||
+		bucketFactory.Type == "anomaly" {
 		//In this case we allow all events to pass.
 		//maybe in the future we could avoid using a limiter
 		limiter = &rate.AlwaysFull{}
@@ -166,138 +198,193 @@ func FromFactory(bucketFactory BucketFactory) *Leaky {
 	}
 	if l.BucketConfig.duration != time.Duration(0) {
 		l.Duration = l.BucketConfig.duration
-		l.timedOverflow = true
+		//for sequences and cardinality, the duration is the time allowed to complete the steps, not a deadline to overflow (and the window of anomaly)
+		l.timedOverflow = bucketFactory.Type != "sequence" && bucketFactory.Type != "cardinality" && bucketFactory.Type != "anomaly"
 	}
 
 	return l
 }
 
-/* for now mimic a leak routine */
-//LeakRoutine us the life of a bucket. It dies when the bucket underflows or overflows
-func LeakRoutine(leaky *Leaky) {
+/*
+The life of a bucket is driven by the shard owning its partition (cf. shard.go) : it is started when it gets its first event
+(or is restored), the events are poured in it one at a time, and it ends when it overflows, reaches its deadline, is canceled or killed.
+*/
 
-	var (
-		durationTicker <-chan time.Time = make(<-chan time.Time)
-	)
+//startLeaky runs the init hooks of the bucket and accounts for it as a live bucket
+func startLeaky(leaky *Leaky) error {
+	leaky.logger = leaky.BucketConfig.logger.WithFields(log.Fields{"capacity": leaky.Capacity, "partition": leaky.Mapkey, "bucket_id": leaky.Uuid})
 
-	defer types.CatchPanic(fmt.Sprintf("crowdsec/LeakRoutine/%s", leaky.Name))
+	for _, f := range leaky.BucketConfig.processors {
+		err := f.OnBucketInit(leaky.BucketConfig)
+		if err != nil {
+			leaky.logger.Errorf("Problem at bucket initializiation. Bail out %T : %v", f, err)
+			return err
+		}
+	}
 
 	BucketsCurrentCount.With(prometheus.Labels{"name": leaky.Name}).Inc()
-	defer BucketsCurrentCount.With(prometheus.Labels{"name": leaky.Name}).Dec()
+	atomic.AddInt64(&LeakyRoutineCount, 1)
+	atomic.AddInt64(&liveBuckets, 1)
+	if leaky.BucketConfig.liveCount != nil {
+		atomic.AddInt64(leaky.BucketConfig.liveCount, 1)
+	}
+	leaky.logger.Debugf("Leaky bucket starting, lifetime : %s", leaky.Duration)
+	return nil
+}
 
-	/*todo : we create a logger at runtime while we want leakroutine to be up asap, might not be a good idea*/
-	leaky.logger = leaky.BucketConfig.logger.WithFields(log.Fields{"capacity": leaky.Capacity, "partition": leaky.Mapkey, "bucket_id": leaky.Uuid})
+//reserveLive accounts for a bucket of holder that its shard is about to create, until releaseLive
+func reserveLive(holder *BucketFactory) {
+	atomic.AddInt64(&liveBuckets, 1)
+	if holder.liveCount != nil {
+		atomic.AddInt64(holder.liveCount, 1)
+	}
+}
 
-	leaky.Signal <- true
-	atomic.AddInt64(&LeakyRoutineCount, 1)
-	defer atomic.AddInt64(&LeakyRoutineCount, -1)
+func releaseLive(holder *BucketFactory) {
+	atomic.AddInt64(&liveBuckets, -1)
+	if holder.liveCount != nil {
+		atomic.AddInt64(holder.liveCount, -1)
+	}
+}
+
+/*
+markDead marks the bucket as ended by its shard. It doesn't count towards the caps anymore, even if its last event is still
+waiting for the output routines.
+*/
+func markDead(leaky *Leaky) {
+	if !atomic.CompareAndSwapInt32(&leaky.dead, 0, 1) {
+		return
+	}
+	atomic.AddInt64(&liveBuckets, -1)
+	if leaky.BucketConfig.liveCount != nil {
+		atomic.AddInt64(leaky.BucketConfig.liveCount, -1)
+	}
+}
+
+//endLeaky accounts for the end of the bucket, once its last event has been sent
+func endLeaky(leaky *Leaky) {
+	BucketsCurrentCount.With(prometheus.Labels{"name": leaky.Name}).Dec()
+	atomic.AddInt64(&LeakyRoutineCount, -1)
+}
 
+//pourLeaky runs the pour hooks and pours msg in the bucket. It returns the overflowing queue, if any
+func pourLeaky(leaky *Leaky, msg types.Event) *Queue {
+	/*the msg var use is confusing and is redeclared in a different type :/*/
+	for _, processor := range leaky.BucketConfig.processors {
+		msg := processor.OnBucketPour(leaky.BucketConfig)(msg, leaky)
+		// if &msg == nil we stop processing
+		if msg == nil {
+			return overflowingQueue(leaky)
+		}
+	}
+	if leaky.logger.Level >= log.TraceLevel {
+		leaky.logger.Tracef("Pour event: %s", spew.Sdump(msg))
+	}
+	BucketsPour.With(prometheus.Labels{"name": leaky.Name, "source": msg.Line.Src}).Inc()
+
+	leaky.Pour(leaky, msg) // glue for now
+	//Clear cache on behalf of pour
+	if leaky.Duration != 0 {
+		leaky.deadline = time.Now().Add(leaky.Duration)
+		if leaky.Mode == HYBRID {
+			leaky.deadline = hybridDeadline(leaky)
+		}
+	}
+	return overflowingQueue(leaky)
+}
+
+/*the pour hooks and Pour functions push the queue on leaky.Out when the bucket overflows*/
+func overflowingQueue(leaky *Leaky) *Queue {
+	select {
+	case ofw := <-leaky.Out:
+		return ofw
+	default:
+		return nil
+	}
+}
+
+//overflowLeaky crafts the alert of the overflowing bucket
+func overflowLeaky(leaky *Leaky, ofw *Queue) types.Event {
+	alert, err := NewAlert(leaky, ofw)
+	if err != nil {
+		log.Errorf("%s", err)
+	}
+	leaky.logger.Tracef("Overflow hooks time : %v", leaky.BucketConfig.processors)
 	for _, f := range leaky.BucketConfig.processors {
-		err := f.OnBucketInit(leaky.BucketConfig)
-		if err != nil {
-			leaky.logger.Errorf("Problem at bucket initializiation. Bail out %T : %v", f, err)
-			close(leaky.Signal)
-			return
+		alert, ofw = f.OnBucketOverflow(leaky.BucketConfig)(leaky, alert, ofw)
+		if ofw == nil {
+			leaky.logger.Debugf("Overflow has been discarded (%T)", f)
+			break
 		}
 	}
+	if leaky.logger.Level >= log.TraceLevel {
+		leaky.logger.Tracef("Overflow event: %s", spew.Sdump(types.RuntimeAlert(alert)))
+	}
+	mt, _ := leaky.Ovflw_ts.MarshalText()
+	leaky.logger.Tracef("overflow time : %s", mt)
 
-	leaky.logger.Debugf("Leaky routine starting, lifetime : %s", leaky.Duration)
-	for {
-		select {
-		/*receiving an event*/
-		case msg := <-leaky.In:
-			/*the msg var use is confusing and is redeclared in a different type :/*/
-			for _, processor := range leaky.BucketConfig.processors {
-				msg := processor.OnBucketPour(leaky.BucketConfig)(msg, leaky)
-				// if &msg == nil we stop processing
-				if msg == nil {
-					goto End
-				}
-			}
-			if leaky.logger.Level >= log.TraceLevel {
-				leaky.logger.Tracef("Pour event: %s", spew.Sdump(msg))
-			}
-			BucketsPour.With(prometheus.Labels{"name": leaky.Name, "source": msg.Line.Src}).Inc()
-
-			leaky.Pour(leaky, msg) // glue for now
-			//Clear cache on behalf of pour
-			tmp := time.NewTicker(leaky.Duration)
-			durationTicker = tmp.C
-			leaky.Signal <- true
-			defer tmp.Stop()
-		/*a kill chan to allow externally killing the leaky routines*/
-		case <-leaky.KillSwitch:
-			close(leaky.Signal)
-			leaky.logger.Debugf("Bucket externally killed, return")
-			leaky.AllOut <- types.Event{Type: types.OVFLW, Overflow: types.RuntimeAlert{Mapkey: leaky.Mapkey}}
-			return
-		/*we overflowed*/
-		case ofw := <-leaky.Out:
-			close(leaky.Signal)
-			alert, err := NewAlert(leaky, ofw)
-			if err != nil {
-				log.Errorf("%s", err)
-			}
-			leaky.logger.Tracef("Overflow hooks time : %v", leaky.BucketConfig.processors)
-			for _, f := range leaky.BucketConfig.processors {
-				alert, ofw = f.OnBucketOverflow(leaky.BucketConfig)(leaky, alert, ofw)
-				if ofw == nil {
-					leaky.logger.Debugf("Overflow has been discarded (%T)", f)
-					break
-				}
-			}
-			if leaky.logger.Level >= log.TraceLevel {
-				leaky.logger.Tracef("Overflow event: %s", spew.Sdump(types.RuntimeAlert(alert)))
-			}
-			mt, _ := leaky.Ovflw_ts.MarshalText()
-			leaky.logger.Tracef("overflow time : %s", mt)
-
-			BucketsOverflow.With(prometheus.Labels{"name": leaky.Name}).Inc()
-
-			leaky.AllOut <- types.Event{Overflow: alert, Type: types.OVFLW, MarshaledTime: string(mt)}
-			return
-			/*we underflow or reach bucket deadline (timers)*/
-		case <-durationTicker:
-			var (
-				alert types.RuntimeAlert
-				err   error
-			)
-			leaky.Ovflw_ts = time.Now()
-			close(leaky.Signal)
-			ofw := leaky.Queue
-			alert = types.RuntimeAlert{Mapkey: leaky.Mapkey}
-
-			if leaky.timedOverflow {
-				BucketsOverflow.With(prometheus.Labels{"name": leaky.Name}).Inc()
-
-				alert, err = NewAlert(leaky, ofw)
-				if err != nil {
-					log.Errorf("%s", err)
-				}
-				for _, f := range leaky.BucketConfig.processors {
-					alert, ofw = f.OnBucketOverflow(leaky.BucketConfig)(leaky, alert, ofw)
-					if ofw == nil {
-						leaky.logger.Debugf("Overflow has been discarded (%T)", f)
-						break
-					}
-				}
-				leaky.logger.Infof("Timed Overflow")
-			} else {
-				leaky.logger.Debugf("bucket underflow, destroy")
-				BucketsUnderflow.With(prometheus.Labels{"name": leaky.Name}).Inc()
+	BucketsOverflow.With(prometheus.Labels{"name": leaky.Name}).Inc()
 
+	return types.Event{Overflow: alert, Type: types.OVFLW, MarshaledTime: string(mt)}
+}
+
+//expireLeaky is called when the bucket reaches its deadline : it either underflows or, for buckets with a duration, overflows
+func expireLeaky(leaky *Leaky) types.Event {
+	var (
+		alert types.RuntimeAlert
+		err   error
+	)
+	leaky.Ovflw_ts = time.Now()
+	if leaky.Mode == HYBRID {
+		leaky.Ovflw_ts = leaky.Last_ts.Add(leaky.Duration)
+	}
+	ofw := leaky.Queue
+	alert = types.RuntimeAlert{Mapkey: leaky.Mapkey}
+
+	if leaky.timedOverflow {
+		BucketsOverflow.With(prometheus.Labels{"name": leaky.Name}).Inc()
+
+		alert, err = NewAlert(leaky, ofw)
+		if err != nil {
+			log.Errorf("%s", err)
+		}
+		for _, f := range leaky.BucketConfig.processors {
+			alert, ofw = f.OnBucketOverflow(leaky.BucketConfig)(leaky, alert, ofw)
+			if ofw == nil {
+				leaky.logger.Debugf("Overflow has been discarded (%T)", f)
+				break
 			}
-			if leaky.logger.Level >= log.TraceLevel {
-				/*don't sdump if it's not going to printed, it's expensive*/
-				leaky.logger.Tracef("Overflow event: %s", spew.Sdump(types.Event{Overflow: alert}))
-			}
+		}
+		leaky.logger.Infof("Timed Overflow")
+	} else {
+		leaky.logger.Debugf("bucket underflow, destroy")
+		BucketsUnderflow.With(prometheus.Labels{"name": leaky.Name}).Inc()
+
+	}
+	if leaky.logger.Level >= log.TraceLevel {
+		/*don't sdump if it's not going to printed, it's expensive*/
+		leaky.logger.Tracef("Overflow event: %s", spew.Sdump(types.Event{Overflow: alert}))
+	}
+	return types.Event{Overflow: alert, Type: types.OVFLW}
+}
+
+//isDead returns true once the bucket overflowed, expired, or was canceled or killed
+func (l *Leaky) isDead() bool {
+	return atomic.LoadInt32(&l.dead) == 1
+}
 
-			leaky.AllOut <- types.Event{Overflow: alert, Type: types.OVFLW}
-			leaky.logger.Tracef("Returning from leaky routine.")
-			return
+//eventTime returns the time of the event in time-machine and event-time modes, the current time otherwise
+func eventTime(l *Leaky, msg types.Event) time.Time {
+	if l.Mode == HYBRID {
+		return hybridTime(msg, time.Now())
+	}
+	if l.Mode == TIMEMACHINE {
+		var d time.Time
+		if err := d.UnmarshalText([]byte(msg.MarshaledTime)); err == nil {
+			return d
 		}
-	End:
 	}
+	return time.Now()
 }
 
 func Pour(leaky *Leaky, msg types.Event) {
@@ -3,23 +3,147 @@ package leakybucket
 import (
 	"crypto/sha1"
 	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
 )
 
 // Buckets is the struct used to hold buckets in the context of
 // main.go the idea is to have one struct to rule them all
 type Buckets struct {
+	//Bucket_map holds the live buckets by key : the buckets themselves are owned by the shards, and must be read through Buckets.visit
 	Bucket_map sync.Map
+	creating   sync.Map //keys of the buckets accounted for by the pouring routines, that their shard didn't create yet
+	shards     []*bucketShard
+	lock       sync.RWMutex
+	stopped    bool
 }
 
-// NewBuckets create the Buckets struct
+// NewBuckets create the Buckets struct, with one shard per cpu
 func NewBuckets() *Buckets {
-	return &Buckets{
+	return NewBucketsWithShards(0)
+}
+
+//NewBucketsWithShards creates the Buckets struct and starts its shards (one per cpu if count is 0)
+func NewBucketsWithShards(count int) *Buckets {
+	if count <= 0 {
+		count = runtime.NumCPU()
+	}
+	buckets := &Buckets{
 		Bucket_map: sync.Map{},
+		shards:     make([]*bucketShard, count),
 	}
+	for i := range buckets.shards {
+		buckets.shards[i] = newBucketShard(&buckets.Bucket_map, &buckets.creating)
+		go buckets.shards[i].run()
+	}
+	return buckets
 }
 
 func GetKey(bucketCfg BucketFactory, stackkey string) string {
 	return fmt.Sprintf("%x", sha1.Sum([]byte(bucketCfg.Filter+stackkey+bucketCfg.Name)))
 
 }
+
+//shardOf returns the shard owning the bucket of key (fnv-1a, inlined to avoid allocating)
+func (b *Buckets) shardOf(key string) *bucketShard {
+	if len(b.shards) == 1 {
+		return b.shards[0]
+	}
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return b.shards[h%uint32(len(b.shards))]
+}
+
+//send hands req to the shard of its key, waiting if the shard is busy. It returns false if the buckets were stopped
+func (b *Buckets) send(req shardRequest) bool {
+	return b.sendTo(b.shardOf(req.key), req)
+}
+
+func (b *Buckets) sendTo(shard *bucketShard, req shardRequest) bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if b.stopped {
+		return false
+	}
+	shard.in <- req
+	return true
+}
+
+/*
+visit calls fn on the live buckets (only on the one of key if not empty) from the routine of the shard owning them, so that fn can
+read them safely. fn is never called concurrently, and must copy what it keeps : the buckets go on once it returned.
+It returns false if the buckets were stopped.
+*/
+func (b *Buckets) visit(key string, fn func(*Leaky)) bool {
+	shards := b.shards
+	if key != "" {
+		shards = []*bucketShard{b.shardOf(key)}
+	}
+	for _, shard := range shards {
+		var done sync.WaitGroup
+
+		done.Add(1)
+		if !b.sendTo(shard, shardRequest{op: opVisit, key: key, visit: fn, done: &done}) {
+			return false
+		}
+		done.Wait()
+	}
+	return true
+}
+
+//flush waits until the shards processed the requests sent so far (ie. the events poured). It returns false if the buckets were stopped
+func (b *Buckets) flush() bool {
+	for _, shard := range b.shards {
+		var done sync.WaitGroup
+
+		done.Add(1)
+		if !b.sendTo(shard, shardRequest{op: opFlush, done: &done}) {
+			return false
+		}
+		done.Wait()
+	}
+	return true
+}
+
+//kill asks the shard of bucket to destroy it without overflow. It returns false if it was already asked to
+func (b *Buckets) kill(bucket *Leaky) bool {
+	if !atomic.CompareAndSwapInt32(&bucket.killed, 0, 1) {
+		return false
+	}
+	b.send(shardRequest{op: opKill, key: bucket.Mapkey, bucket: bucket})
+	return true
+}
+
+//forget removes the killed bucket from Bucket_map once its shard destroyed it, unless it was replaced since
+func (b *Buckets) forget(bucket *Leaky) {
+	var done sync.WaitGroup
+
+	done.Add(1)
+	if !b.send(shardRequest{op: opForget, key: bucket.Mapkey, bucket: bucket, done: &done}) {
+		return
+	}
+	done.Wait()
+}
+
+//stop ends the buckets and the shards once they processed their pending requests, the requests sent afterwards are dropped
+func (b *Buckets) stop() {
+	var done sync.WaitGroup
+
+	b.lock.Lock()
+	if b.stopped {
+		b.lock.Unlock()
+		return
+	}
+	b.stopped = true
+	b.lock.Unlock()
+
+	for _, shard := range b.shards {
+		done.Add(1)
+		shard.in <- shardRequest{op: opStop, done: &done}
+	}
+	done.Wait()
+}
@@ -197,7 +197,7 @@ POLL_AGAIN:
 		if len(tf.Results) == 0 && len(results) == 0 {
 			log.Warningf("Test is successfull")
 			if dump {
-				if tmpFile, err = DumpBucketsStateAt(latest_ts, ".", buckets); err != nil {
+				if tmpFile, err = DumpBucketsStateAt(latest_ts, ".", buckets, holders); err != nil {
 					t.Fatalf("Failed dumping bucket state : %s", err)
 				}
 				log.Infof("dumped bucket to %s", tmpFile)
@@ -207,7 +207,7 @@ POLL_AGAIN:
 			log.Warningf("%d results to check against %d expected results", len(results), len(tf.Results))
 			if len(tf.Results) != len(results) {
 				if dump {
-					if tmpFile, err = DumpBucketsStateAt(latest_ts, ".", buckets); err != nil {
+					if tmpFile, err = DumpBucketsStateAt(latest_ts, ".", buckets, holders); err != nil {
 						t.Fatalf("Failed dumping bucket state : %s", err)
 					}
 					log.Infof("dumped bucket to %s", tmpFile)
@@ -0,0 +1,119 @@
+package leakybucket
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/sketch"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+const (
+	//DefaultSketchErrorRate is the error rate of the probabilistic structures when not set
+	DefaultSketchErrorRate = 0.01
+	//DefaultSketchExpectedItems sizes the bloom filters of probabilistic distinct when not set
+	DefaultSketchExpectedItems = 10000
+)
+
+/*
+ProbabilisticCfg configures the probabilistic structures used by distinct and cardinality buckets.
+The bloom filter of distinct never forgets a value until the bucket ends, even when the events holding it are dropped from the
+queue (cf. cache_size) : a value is distinct once per bucket, and past expected_items values, the false positive rate grows.
+*/
+type ProbabilisticCfg struct {
+	ErrorRate     float64 `yaml:"error_rate,omitempty"`     //false positive rate of bloom filters, standard error of hyperloglogs
+	ExpectedItems int     `yaml:"expected_items,omitempty"` //number of distinct values the bloom filters are sized for
+}
+
+func (p *ProbabilisticCfg) errorRate() float64 {
+	if p == nil || p.ErrorRate == 0 {
+		return DefaultSketchErrorRate
+	}
+	return p.ErrorRate
+}
+
+func (p *ProbabilisticCfg) expectedItems() int {
+	if p == nil || p.ExpectedItems == 0 {
+		return DefaultSketchExpectedItems
+	}
+	return p.ExpectedItems
+}
+
+func (p *ProbabilisticCfg) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.ErrorRate < 0 || p.ErrorRate >= 1 {
+		return fmt.Errorf("error_rate must be between 0 and 1, got %f", p.ErrorRate)
+	}
+	if p.ExpectedItems < 0 {
+		return fmt.Errorf("expected_items must be positive, got %d", p.ExpectedItems)
+	}
+	return nil
+}
+
+/*
+Cardinality overflows when the number of distinct values of the distinct expression reaches the capacity of the bucket within duration.
+The distinct values are counted with a hyperloglog held by the bucket (Leaky.Cardinality), so the memory doesn't depend on the number of values,
+and the queue only keeps the last events for the alert.
+*/
+type Cardinality struct {
+	DistinctCompiled *vm.Program
+	DumbProcessor
+}
+
+func NewCardinality(bucketFactory *BucketFactory) (*Cardinality, error) {
+	var err error
+
+	c := Cardinality{}
+	if bucketFactory.Distinct == "" {
+		return nil, fmt.Errorf("cardinality bucket must have a distinct")
+	}
+	c.DistinctCompiled, err = expr.Compile(bucketFactory.Distinct, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
+	if err != nil {
+		return nil, fmt.Errorf("invalid distinct '%s' : %v", bucketFactory.Distinct, err)
+	}
+	return &c, nil
+}
+
+func (c *Cardinality) OnBucketPour(bucketFactory *BucketFactory) func(types.Event, *Leaky) *types.Event {
+	return func(msg types.Event, l *Leaky) *types.Event {
+		now := eventTime(l, msg)
+		if !l.First_ts.IsZero() && now.Sub(l.First_ts) > bucketFactory.duration {
+			l.logger.Debugf("cardinality window expired (%d distinct values), start over", l.Cardinality.Count())
+			l.Cardinality = nil
+			l.Total_count = 0
+			l.First_ts = time.Time{}
+			l.Queue.Queue = make([]types.Event, 0)
+		}
+		if l.Cardinality == nil {
+			l.Cardinality = sketch.NewHyperLogLog(bucketFactory.Probabilistic.errorRate())
+		}
+
+		element, err := getElement(msg, c.DistinctCompiled)
+		if err != nil {
+			l.logger.Errorf("Cardinality distinct exec failed : %v", err)
+			return nil
+		}
+		l.Cardinality.Add(element)
+		count := l.Cardinality.Count()
+		l.logger.Tracef("Cardinality '%s' -> %d distinct values", element, count)
+		if count < uint64(l.Capacity) {
+			return &msg
+		}
+
+		l.logger.Infof("%d distinct values, bucket overflow", count)
+		l.Total_count += 1
+		if l.First_ts.IsZero() {
+			l.First_ts = now
+		}
+		l.Last_ts = now
+		l.Ovflw_ts = now
+		l.Queue.Add(msg)
+		l.Out <- l.Queue
+		return nil
+	}
+}
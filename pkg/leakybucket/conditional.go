@@ -0,0 +1,130 @@
+package leakybucket
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/ast"
+	"github.com/antonmedv/expr/parser"
+	"github.com/antonmedv/expr/vm"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+/*
+ConditionalOverflow overflows as soon as the condition is true for the content of the bucket (the poured event included).
+The capacity and leakspeed don't trigger overflows, they only bound the number of events kept and the lifetime of the bucket.
+*/
+type ConditionalOverflow struct {
+	Condition        string
+	ConditionRuntime *vm.Program
+	guard            *exprhelpers.ExprGuard
+	DumbProcessor
+}
+
+var conditionalEnvPool = sync.Pool{
+	New: func() interface{} {
+		return exprhelpers.GetExprEnv(map[string]interface{}{"queue": nil, "leaky": nil})
+	},
+}
+
+//queueExprVisitor compiles the expressions given to the queue helpers, so that errors show up when the scenario is loaded
+type queueExprVisitor struct {
+	err error
+}
+
+func (v *queueExprVisitor) Enter(node *ast.Node) {}
+
+func (v *queueExprVisitor) Exit(node *ast.Node) {
+	method, ok := (*node).(*ast.MethodNode)
+	if !ok || v.err != nil {
+		return
+	}
+	switch method.Method {
+	case "Sum", "Ratio", "Distinct":
+	default:
+		return
+	}
+	if len(method.Arguments) != 1 {
+		v.err = fmt.Errorf("%s expects one argument", method.Method)
+		return
+	}
+	arg, ok := method.Arguments[0].(*ast.StringNode)
+	if !ok {
+		v.err = fmt.Errorf("the argument of %s must be a string", method.Method)
+		return
+	}
+	if _, err := compileQueueExpr(arg.Value); err != nil {
+		v.err = fmt.Errorf("invalid expression '%s' in %s : %s", arg.Value, method.Method, err)
+	}
+}
+
+func NewConditionalOverflow(bucketFactory *BucketFactory) (*ConditionalOverflow, error) {
+	var err error
+
+	c := ConditionalOverflow{
+		Condition: bucketFactory.Condition,
+		guard:     bucketFactory.guard,
+	}
+	if c.Condition == "" {
+		return nil, fmt.Errorf("conditional bucket must have a condition")
+	}
+	compiledEnv := exprhelpers.GetExprEnv(map[string]interface{}{"queue": &Queue{}, "leaky": &Leaky{}})
+	c.ConditionRuntime, err = expr.Compile(c.Condition, expr.Env(compiledEnv))
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition '%s' : %v", c.Condition, err)
+	}
+	if err := exprhelpers.CheckReturnKind(c.Condition, compiledEnv, reflect.Bool); err != nil {
+		return nil, fmt.Errorf("invalid condition : %v", err)
+	}
+	tree, err := parser.Parse(c.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition '%s' : %v", c.Condition, err)
+	}
+	visitor := &queueExprVisitor{}
+	ast.Walk(&tree.Node, visitor)
+	if visitor.err != nil {
+		return nil, fmt.Errorf("invalid condition '%s' : %v", c.Condition, visitor.err)
+	}
+	return &c, nil
+}
+
+func (c *ConditionalOverflow) OnBucketPour(bucketFactory *BucketFactory) func(types.Event, *Leaky) *types.Event {
+	return func(msg types.Event, l *Leaky) *types.Event {
+		/*the condition sees the queue as it will be once the event is poured*/
+		events := append(l.Queue.GetQueue(), msg)
+		if len(events) > l.Queue.L+1 {
+			events = events[len(events)-l.Queue.L-1:]
+		}
+
+		env := conditionalEnvPool.Get().(map[string]interface{})
+		queue := &Queue{Queue: events, L: l.Queue.L}
+		env["queue"] = queue
+		env["leaky"] = l
+		condition, err := c.guard.RunBoolCheck(c.ConditionRuntime, env, queue.Err)
+		env["queue"] = nil
+		env["leaky"] = nil
+		conditionalEnvPool.Put(env)
+		if err != nil {
+			l.logger.Errorf("failed condition : %v", err)
+			return &msg
+		}
+		if !condition {
+			return &msg
+		}
+
+		now := eventTime(l, msg)
+		l.logger.Infof("Condition is true, bucket overflow")
+		l.Total_count += 1
+		if l.First_ts.IsZero() {
+			l.First_ts = now
+		}
+		l.Last_ts = now
+		l.Ovflw_ts = now
+		l.Queue.Add(msg)
+		l.Out <- l.Queue
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package leakybucket
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+func TestConditionalHelpersErrors(t *testing.T) {
+	meta := func(count int) types.Event {
+		evt := types.Event{Meta: map[string]string{}}
+		for i := 0; i < count; i++ {
+			evt.Meta[fmt.Sprintf("key%d", i)] = "value"
+		}
+		return evt
+	}
+	queue := &Queue{Queue: []types.Event{meta(3), meta(4)}, L: 10}
+	if sum := queue.Sum("10 % len(evt.Meta)"); sum != 3 || queue.Err() != nil {
+		t.Fatalf("expected sum 3, got %f (%v)", sum, queue.Err())
+	}
+	/*modulo by zero fails*/
+	queue.Queue = append(queue.Queue, types.Event{})
+	if sum := queue.Sum("10 % len(evt.Meta)"); sum != 0 || queue.Err() == nil {
+		t.Fatalf("expected an error, got sum %f", sum)
+	}
+
+	/*the errors of the helpers are faults of the scenario*/
+	exprhelpers.SetExprLimits(0, 3)
+	defer exprhelpers.SetExprLimits(0, 0)
+	holder := BucketFactory{Name: "test_conditional_errors", Description: "test_conditional_errors", Type: "conditional", Capacity: 10,
+		LeakSpeed: "1s", Filter: "true", Condition: "queue.Sum(\"10 % len(evt.Meta)\") > 10"}
+	if err := LoadBucket(&holder); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	var conditional *ConditionalOverflow
+	for _, processor := range holder.processors {
+		if c, ok := processor.(*ConditionalOverflow); ok {
+			conditional = c
+		}
+	}
+	pour := conditional.OnBucketPour(&holder)
+	bucket := NewLeaky(holder)
+	bucket.logger = holder.logger
+	for i := 0; i < 3; i++ {
+		if pour(types.Event{}, bucket) == nil {
+			t.Fatalf("unexpected overflow")
+		}
+	}
+	if !holder.guard.Quarantined() {
+		t.Fatalf("expected the scenario to be quarantined")
+	}
+	holder.guard.Release()
+}
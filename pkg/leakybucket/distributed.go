@@ -0,0 +1,157 @@
+package leakybucket
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+const DefaultMaxSources = 1000
+
+//SourcesCfg makes the bucket record the distinct sources of its events, for the alerts of distributed attacks
+type SourcesCfg struct {
+	Scope string `yaml:"scope,omitempty"` //Ip (default) or Range
+	Max   int    `yaml:"max,omitempty"`   //the max number of sources recorded by a bucket, defaults to 1000
+}
+
+func (s *SourcesCfg) Validate() error {
+	switch s.Scope {
+	case types.Undefined:
+		s.Scope = types.Ip
+	case types.Ip, types.Range:
+	default:
+		return fmt.Errorf("unknown sources scope '%s', must be %s or %s", s.Scope, types.Ip, types.Range)
+	}
+	if s.Max == 0 {
+		s.Max = DefaultMaxSources
+	}
+	if s.Max < 0 {
+		return fmt.Errorf("sources max must be positive, got %d", s.Max)
+	}
+	return nil
+}
+
+/*
+The buckets of the scenarios grouped by target (ie. the user of a distributed bruteforce) see many sources, but their alerts only carry
+the sources of the events still in the queue. SourcesCollector records the distinct sources of all the events poured in the bucket, and the
+overflow carries all of them : each source gets an alert of its own, and the alerts are tied together as an incident by their metas.
+*/
+type SourcesCollector struct {
+	cfg *SourcesCfg
+	DumbProcessor
+}
+
+func NewSourcesCollector(bucketFactory *BucketFactory) (*SourcesCollector, error) {
+	if err := bucketFactory.Sources.Validate(); err != nil {
+		return nil, err
+	}
+	return &SourcesCollector{cfg: bucketFactory.Sources}, nil
+}
+
+func (s *SourcesCollector) OnBucketPour(bucketFactory *BucketFactory) func(types.Event, *Leaky) *types.Event {
+	return func(msg types.Event, l *Leaky) *types.Event {
+		srcs, err := sourcesOfScope(msg, s.cfg.Scope, l)
+		if err != nil {
+			l.logger.Tracef("no source recorded : %s", err)
+			return &msg
+		}
+		for key, src := range srcs {
+			if _, ok := l.Sources[key]; ok {
+				continue
+			}
+			if len(l.Sources) >= s.cfg.Max {
+				l.logger.Debugf("max sources (%d) reached, %s isn't recorded", s.cfg.Max, key)
+				continue
+			}
+			if l.Sources == nil {
+				l.Sources = make(map[string]models.Source)
+			}
+			l.Sources[key] = src
+		}
+		return &msg
+	}
+}
+
+//sourcesOfScope returns the sources of scope Ip or Range of the event
+func sourcesOfScope(evt types.Event, scope string, leaky *Leaky) (map[string]models.Source, error) {
+	srcs := make(map[string]models.Source)
+	if evt.Type == types.OVFLW {
+		for _, v := range evt.Overflow.Sources {
+			if *v.Scope == scope {
+				srcs[*v.Value] = v
+			} else if scope == types.Range && *v.Scope == types.Ip && v.Range != "" {
+				src := rangeFromIpSource(v)
+				srcs[*src.Value] = src
+			}
+		}
+		return srcs, nil
+	}
+	src, err := sourceFromMeta(evt, scope, leaky)
+	if err != nil {
+		return srcs, err
+	}
+	if *src.Value == "" {
+		return srcs, fmt.Errorf("no %s for %s", scope, src.IP)
+	}
+	srcs[*src.Value] = src
+	return srcs, nil
+}
+
+//incidentSources returns the sources recorded by the bucket, completed by the ones of the queue
+func incidentSources(leaky *Leaky, queue *Queue) map[string]models.Source {
+	cfg := leaky.BucketConfig.Sources
+	sources := make(map[string]models.Source, len(leaky.Sources))
+	for key, src := range leaky.Sources {
+		sources[key] = src
+	}
+	for _, evt := range queue.Queue {
+		srcs, err := sourcesOfScope(evt, cfg.Scope, leaky)
+		if err != nil {
+			leaky.logger.Tracef("no source from queue : %s", err)
+			continue
+		}
+		for key, src := range srcs {
+			if _, ok := sources[key]; !ok && len(sources) < cfg.Max {
+				sources[key] = src
+			}
+		}
+	}
+	return sources
+}
+
+/*
+incidentTarget returns the target of the incident (the partition of the bucket, ie. a username) as the redaction policy lets it
+leave crowdsec, and false if it must not
+*/
+func incidentTarget(leaky *Leaky) (string, bool) {
+	if leaky.Partition == "" {
+		return "", false
+	}
+	if redaction := leaky.BucketConfig.redaction; redaction != nil {
+		return redaction.RedactMeta(types.IncidentTargetMeta, leaky.Partition)
+	}
+	return leaky.Partition, true
+}
+
+//incidentMeta returns the metas tying together the alerts of the sources of the overflow
+func incidentMeta(leaky *Leaky, sources map[string]models.Source) models.Meta {
+	keys := make([]string, 0, len(sources))
+	for key := range sources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	meta := models.Meta{
+		&models.MetaItems0{Key: types.IncidentMeta, Value: fmt.Sprintf("%.16s-%d", leaky.Mapkey, leaky.Ovflw_ts.UnixNano())},
+		&models.MetaItems0{Key: types.IncidentSourcesMeta, Value: strconv.Itoa(len(sources))},
+	}
+	if target, ok := incidentTarget(leaky); ok {
+		meta = append(meta, &models.MetaItems0{Key: types.IncidentTargetMeta, Value: target})
+	}
+	if len(keys) > 0 {
+		meta = append(meta, &models.MetaItems0{Key: types.IncidentLeaderMeta, Value: keys[0]})
+	}
+	return meta
+}
@@ -0,0 +1,101 @@
+package leakybucket
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+func TestIncidentAlert(t *testing.T) {
+	holder := BucketFactory{Name: "test_distributed", Description: "test_distributed", Type: "counter", Capacity: -1, Duration: "1m",
+		Filter: "true", GroupBy: "evt.Meta.target_user", CacheSize: 2, Sources: &SourcesCfg{Max: 5}}
+	if err := LoadBucket(&holder); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	if holder.Sources.Scope != types.Ip {
+		t.Fatalf("expected default scope %s, got %s", types.Ip, holder.Sources.Scope)
+	}
+
+	bucket := NewTimeMachine(holder)
+	bucket.Partition = "admin"
+	bucket.Mapkey = "0123456789abcdef0123456789abcdef"
+	bucket.logger = holder.logger
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		evt := types.Event{Time: start.Add(time.Duration(i) * time.Second),
+			Meta: map[string]string{"source_ip": fmt.Sprintf("1.2.3.%d", i%8), "target_user": "admin"}}
+		for _, processor := range holder.processors {
+			if processor.OnBucketPour(&holder)(evt, bucket) == nil {
+				t.Fatalf("event %d discarded", i)
+			}
+		}
+		bucket.Queue.Add(evt)
+	}
+	/*the recorded sources are capped*/
+	if len(bucket.Sources) != 5 {
+		t.Fatalf("expected 5 recorded sources, got %d", len(bucket.Sources))
+	}
+	bucket.First_ts = start
+	bucket.Ovflw_ts = start.Add(time.Minute)
+	bucket.Total_count = 10
+
+	alert, err := NewAlert(bucket, bucket.Queue)
+	if err != nil {
+		t.Fatalf("while creating alert : %s", err)
+	}
+	if len(alert.APIAlerts) != 5 {
+		t.Fatalf("expected 5 alerts, got %d", len(alert.APIAlerts))
+	}
+	meta := map[string]string{}
+	for _, item := range alert.Alert.Meta {
+		meta[item.Key] = item.Value
+	}
+	expected := map[string]string{
+		types.IncidentMeta:        fmt.Sprintf("0123456789abcdef-%d", bucket.Ovflw_ts.UnixNano()),
+		types.IncidentSourcesMeta: "5",
+		types.IncidentTargetMeta:  "admin",
+		types.IncidentLeaderMeta:  "1.2.3.0",
+	}
+	for key, value := range expected {
+		if meta[key] != value {
+			t.Fatalf("expected meta %s to be '%s', got '%s'", key, value, meta[key])
+		}
+	}
+	for _, apiAlert := range alert.APIAlerts {
+		if len(apiAlert.Meta) != len(expected) {
+			t.Fatalf("alert of %s isn't part of the incident", *apiAlert.Source.Value)
+		}
+	}
+	if !strings.HasSuffix(*alert.Alert.Message, " against admin") {
+		t.Fatalf("expected the target in the message : %s", *alert.Alert.Message)
+	}
+
+	/*the target goes through the redaction policy*/
+	tests := []struct {
+		redaction *csconfig.RedactionCfg
+		target    string
+	}{
+		{&csconfig.RedactionCfg{DenyMeta: []string{types.IncidentTargetMeta}}, ""},
+		{&csconfig.RedactionCfg{Mask: []*csconfig.RedactionMaskCfg{{Key: types.IncidentTargetMeta, Regexp: "adm", Replacement: "***"}}}, "***in"},
+	}
+	for _, test := range tests {
+		if err := test.redaction.Load(""); err != nil {
+			t.Fatalf("while loading redaction : %s", err)
+		}
+		bucket.BucketConfig.redaction = test.redaction
+		alert, err := NewAlert(bucket, bucket.Queue)
+		if err != nil {
+			t.Fatalf("while creating alert : %s", err)
+		}
+		if target := alert.Alert.GetMeta(types.IncidentTargetMeta); target != test.target {
+			t.Fatalf("expected target '%s', got '%s'", test.target, target)
+		}
+		if strings.Contains(*alert.Alert.Message, "admin") {
+			t.Fatalf("the target leaked in the message : %s", *alert.Alert.Message)
+		}
+	}
+}
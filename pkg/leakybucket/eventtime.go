@@ -0,0 +1,84 @@
+package leakybucket
+
+import (
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+In event-time mode, the live buckets (HYBRID) leak according to the time of the events rather than the time they are read at :
+delayed logs (batched syslog forwarding, journald catching up after a restart ...) are counted when they happened.
+The events can be late by up to the allowed lateness, the later ones aren't poured and are only counted. The buckets expire when
+no event is expected anymore : the allowed lateness after the end of their duration.
+*/
+
+var BucketsLateEvents = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cs_bucket_late_events_total",
+		Help: "Total events not poured because they were later than the allowed lateness.",
+	},
+	[]string{"name"},
+)
+
+//hybridTime returns the time of the event, the time it is read at if it has none or if it's in the future
+func hybridTime(evt types.Event, now time.Time) time.Time {
+	var d time.Time
+	if evt.MarshaledTime == "" {
+		return now
+	}
+	if err := d.UnmarshalText([]byte(evt.MarshaledTime)); err != nil || d.After(now) {
+		return now
+	}
+	return d
+}
+
+//lateEvent returns true if the live event must be counted as late by the holder rather than poured
+func lateEvent(holder *BucketFactory, evt types.Event, now time.Time) bool {
+	if now.Sub(hybridTime(evt, now)) <= holder.eventTime.AllowedLateness {
+		return false
+	}
+	holder.logger.Debugf("event of %s is later than %s, not poured", evt.MarshaledTime, holder.eventTime.AllowedLateness)
+	BucketsLateEvents.With(prometheus.Labels{"name": holder.Name}).Inc()
+	return true
+}
+
+func HybridPour(l *Leaky, msg types.Event) {
+	d := hybridTime(msg, time.Now())
+
+	l.Total_count += 1
+	if l.First_ts.IsZero() || d.Before(l.First_ts) {
+		l.First_ts = d
+	}
+	if d.After(l.Last_ts) {
+		l.Last_ts = d
+	}
+	/*the bucket never leaks backward : the late events are counted at the time of the latest one*/
+	if l.Limiter.AllowN(l.Last_ts, 1) {
+		l.logger.Tracef("Hybrid-Pouring event %s (tokens:%f)", d, l.Limiter.GetTokensCount())
+		l.Queue.Add(msg)
+	} else {
+		l.Ovflw_ts = l.Last_ts
+		l.logger.Debugf("Bucket overflow at %s", l.Ovflw_ts)
+		l.Queue.Add(msg)
+		l.Out <- l.Queue
+	}
+}
+
+func NewHybrid(g BucketFactory) *Leaky {
+	l := NewLeaky(g)
+	g.logger.Tracef("Instanciating hybrid bucket")
+	l.Pour = HybridPour
+	l.Mode = HYBRID
+	return l
+}
+
+//hybridDeadline is when the bucket expires if it doesn't get any event : its duration after its latest event, plus the allowed lateness
+func hybridDeadline(l *Leaky) time.Time {
+	lateness := time.Duration(0)
+	if l.BucketConfig.eventTime != nil {
+		lateness = l.BucketConfig.eventTime.AllowedLateness
+	}
+	return l.Last_ts.Add(l.Duration + lateness)
+}
@@ -0,0 +1,243 @@
+package leakybucket
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//EvictOldest evicts the buckets that were created first
+	EvictOldest = "oldest"
+	//EvictLeastFilled evicts the buckets that received the less events
+	EvictLeastFilled = "least_filled"
+	//EvictRefuse keeps the existing buckets and doesn't create new ones
+	EvictRefuse = "refuse"
+)
+
+//CapScenario is the scenario of the alerts sent when a max number of live buckets is reached
+const CapScenario = "crowdsec/buckets-cap"
+
+//capAlertInterval is the minimum time between two warnings (and alerts) for the same cap
+var capAlertInterval = 10 * time.Minute
+
+var BucketsEvicted = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cs_bucket_evicted_total",
+		Help: "Total buckets evicted, or not created, because the max number of live buckets was reached.",
+	},
+	[]string{"name"},
+)
+
+/*the global cap, set by LoadBuckets*/
+var bucketsCap csconfig.BucketsCapCfg
+
+var capWarnings = struct {
+	sync.Mutex
+	last map[string]time.Time
+}{last: make(map[string]time.Time)}
+
+func validateEviction(policy string) error {
+	switch policy {
+	case "", EvictOldest, EvictLeastFilled, EvictRefuse:
+		return nil
+	}
+	return fmt.Errorf("unknown eviction policy '%s' (expected %s, %s or %s)", policy, EvictOldest, EvictLeastFilled, EvictRefuse)
+}
+
+func setBucketsCap(cfg *csconfig.BucketsCapCfg) error {
+	bucketsCap = csconfig.BucketsCapCfg{}
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxBuckets < 0 {
+		return fmt.Errorf("max_buckets must be positive, got %d", cfg.MaxBuckets)
+	}
+	if err := validateEviction(cfg.Eviction); err != nil {
+		return err
+	}
+	bucketsCap = *cfg
+	return nil
+}
+
+/*
+makeRoom is called once the new bucket of holder is accounted for (cf. reserveLive), so that concurrent pouring routines don't all
+see room for their bucket. If the scenario or the whole set of scenarios goes over its max number of live buckets, existing buckets
+are evicted according to the eviction policy. It returns false if the new bucket must not be created.
+*/
+func makeRoom(holder *BucketFactory, buckets *Buckets) bool {
+	if holder.MaxBuckets > 0 && holder.liveCount != nil && atomic.LoadInt64(holder.liveCount) > int64(holder.MaxBuckets) {
+		policy := holder.Eviction
+		if policy == "" {
+			policy = bucketsCap.Eviction
+		}
+		if !evictBuckets(holder, buckets, holder.Name, holder.MaxBuckets, policy) {
+			return false
+		}
+	}
+	if bucketsCap.MaxBuckets > 0 && atomic.LoadInt64(&liveBuckets) > int64(bucketsCap.MaxBuckets) {
+		return evictBuckets(holder, buckets, "", bucketsCap.MaxBuckets, bucketsCap.Eviction)
+	}
+	return true
+}
+
+/*
+overCap tells if a new bucket of holder, that wasn't accounted for by the pouring routine, would go over a max number of live buckets.
+It happens when the bucket the pouring routine saw was evicted or ended before the event reached the shard : the shard can't evict
+buckets itself, so it refuses the new bucket.
+*/
+func overCap(holder *BucketFactory) bool {
+	if holder.MaxBuckets > 0 && holder.liveCount != nil && atomic.LoadInt64(holder.liveCount) >= int64(holder.MaxBuckets) {
+		return true
+	}
+	return bucketsCap.MaxBuckets > 0 && atomic.LoadInt64(&liveBuckets) >= int64(bucketsCap.MaxBuckets)
+}
+
+type evictCandidate struct {
+	bucket *Leaky
+	first  time.Time
+	count  int
+}
+
+/*
+evictBuckets kills a batch of live buckets (of scenario, or of any scenario if empty) to make room for a new one.
+The batch is 1% of the cap, so that the buckets map isn't scanned for each new partition during a flood.
+*/
+func evictBuckets(holder *BucketFactory, buckets *Buckets, scenario string, max int, policy string) bool {
+	capReached(holder, buckets, scenario, max, policy)
+	if policy == EvictRefuse {
+		holder.logger.Debugf("max number of live buckets (%d) reached, refuse new bucket", max)
+		BucketsEvicted.With(prometheus.Labels{"name": holder.Name}).Inc()
+		return false
+	}
+
+	/*the candidates are read by the shards owning them, the other routines must not touch the buckets*/
+	candidates := []evictCandidate{}
+	buckets.visit("", func(val *Leaky) {
+		if scenario != "" && val.Name != scenario {
+			return
+		}
+		/*overflowed buckets are already on their way out, and buckets that didn't get their first event yet are being created*/
+		if !val.Ovflw_ts.IsZero() || val.First_ts.IsZero() {
+			return
+		}
+		candidates = append(candidates, evictCandidate{bucket: val, first: val.First_ts, count: val.Total_count})
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if policy == EvictLeastFilled && candidates[i].count != candidates[j].count {
+			return candidates[i].count < candidates[j].count
+		}
+		return candidates[i].first.Before(candidates[j].first)
+	})
+
+	batch := max / 100
+	if batch < 1 {
+		batch = 1
+	}
+	evicted := 0
+	for _, candidate := range candidates {
+		if evicted >= batch {
+			break
+		}
+		if !buckets.kill(candidate.bucket) {
+			/*already killed*/
+			continue
+		}
+		candidate.bucket.logger.Debugf("evicted to make room for a new bucket of %s", holder.Name)
+		buckets.forget(candidate.bucket)
+		BucketsEvicted.With(prometheus.Labels{"name": candidate.bucket.Name}).Inc()
+		evicted++
+	}
+	holder.logger.Debugf("evicted %d buckets (%s)", evicted, policy)
+	return true
+}
+
+/*
+capReached warns (at most once per capAlertInterval for each cap) that detection is degraded, and sends an alert if alert_on_cap is set.
+The alert goes through a shard, along with the overflows, so that the pouring routine never waits for the output routines.
+*/
+func capReached(holder *BucketFactory, buckets *Buckets, scenario string, max int, policy string) {
+	capName := scenario
+	if capName == "" {
+		capName = "*"
+	}
+	capWarnings.Lock()
+	if last, ok := capWarnings.last[capName]; ok && time.Since(last) < capAlertInterval {
+		capWarnings.Unlock()
+		return
+	}
+	capWarnings.last[capName] = time.Now()
+	capWarnings.Unlock()
+
+	if policy == "" {
+		policy = EvictOldest
+	}
+	message := fmt.Sprintf("max number of live buckets (%d) reached for scenario %s, %s : detection is degraded", max, capName, evictionEffect(policy))
+	log.Warningf("%s", message)
+	if !bucketsCap.AlertOnCap {
+		return
+	}
+	/*holder may be the copy of the pouring routine, that changes with the next scenario*/
+	owner := *holder
+	buckets.send(shardRequest{op: opOutput, key: capName, holder: &owner, out: holder.ret,
+		evt: types.Event{Type: types.OVFLW, Overflow: NewCapAlert(capName, max, message)}})
+}
+
+func evictionEffect(policy string) string {
+	switch policy {
+	case EvictRefuse:
+		return "new buckets are refused"
+	case EvictLeastFilled:
+		return "least filled buckets are evicted"
+	default:
+		return "oldest buckets are evicted"
+	}
+}
+
+//NewCapAlert crafts the alert warning that the max number of live buckets of scenario ("*" for the global cap) was reached
+func NewCapAlert(scenario string, max int, message string) types.RuntimeAlert {
+	return newAgentAlert(CapScenario, scenario, 0, max, message)
+}
+
+//newAgentAlert crafts an alert of crowdsec itself about scenario, with the scope 'scenario' so that profiles don't take decisions
+func newAgentAlert(agentScenario string, scenario string, count int, max int, message string) types.RuntimeAlert {
+	now, _ := time.Now().UTC().MarshalText()
+	nowStr := string(now)
+	capacity := int32(max)
+	eventsCount := int32(count)
+	leakSpeed := time.Duration(0).String()
+	empty := ""
+	simulated := false
+	scope := "scenario"
+	value := scenario
+	source := models.Source{Scope: &scope, Value: &value}
+
+	apiAlert := models.Alert{
+		Scenario:        &agentScenario,
+		ScenarioHash:    &empty,
+		ScenarioVersion: &empty,
+		Capacity:        &capacity,
+		EventsCount:     &eventsCount,
+		Leakspeed:       &leakSpeed,
+		Message:         &message,
+		Events:          []*models.Event{},
+		StartAt:         &nowStr,
+		StopAt:          &nowStr,
+		Simulated:       &simulated,
+		Source:          &source,
+	}
+	runtimeAlert := types.RuntimeAlert{
+		APIAlerts: []models.Alert{apiAlert},
+		Sources:   map[string]models.Source{scenario: source},
+	}
+	runtimeAlert.Alert = &runtimeAlert.APIAlerts[0]
+	return runtimeAlert
+}
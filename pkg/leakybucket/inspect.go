@@ -0,0 +1,116 @@
+package leakybucket
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/models"
+	"github.com/crowdsecurity/crowdsec/pkg/time/rate"
+)
+
+//BucketInfo is the state of a live bucket, as exposed to the operators
+type BucketInfo struct {
+	Key         string          `json:"key"`
+	Scenario    string          `json:"scenario"`
+	Type        string          `json:"type"`
+	Partition   string          `json:"partition"`
+	Capacity    int             `json:"capacity"`
+	Fill        float64         `json:"fill"` //tokens used for leaky buckets, number of events otherwise
+	EventsCount int             `json:"events_count"`
+	FirstTs     time.Time       `json:"first_ts"`
+	LastTs      time.Time       `json:"last_ts"`
+	ExpiresAt   time.Time       `json:"expires_at,omitempty"` //when the bucket dies if it doesn't get any event
+	Events      []*models.Event `json:"events"`
+}
+
+//bucketInfo describes the bucket, with at most maxEvents of its last events (all of them if maxEvents is negative). It must be called from the shard of the bucket
+func bucketInfo(key string, val *Leaky, now time.Time, maxEvents int) BucketInfo {
+	info := BucketInfo{
+		Key:         key,
+		Scenario:    val.Name,
+		Type:        val.BucketConfig.Type,
+		Partition:   val.Partition,
+		Capacity:    val.Capacity,
+		Fill:        float64(val.Total_count),
+		EventsCount: val.Total_count,
+		FirstTs:     val.First_ts,
+		LastTs:      val.Last_ts,
+	}
+	if _, ok := val.Limiter.(*rate.AlwaysFull); !ok {
+		info.Fill = float64(val.Capacity) - val.Limiter.GetTokensCountAt(now)
+		if info.Fill < 0 {
+			info.Fill = 0
+		}
+	}
+	if val.Duration != 0 {
+		info.ExpiresAt = val.Last_ts.Add(val.Duration)
+	}
+	events := val.Queue.GetQueue()
+	if maxEvents >= 0 && len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+	info.Events = EventsFromQueue(&Queue{Queue: events}, val.BucketConfig.redaction)
+	return info
+}
+
+//ListBuckets returns the live buckets of scenario and partition (all of them if empty), with at most maxEvents events each
+func ListBuckets(buckets *Buckets, scenario string, partition string, maxEvents int) []BucketInfo {
+	now := time.Now()
+	ret := []BucketInfo{}
+	buckets.visit("", func(val *Leaky) {
+		/*buckets without events are being created*/
+		if val.First_ts.IsZero() {
+			return
+		}
+		if scenario != "" && val.Name != scenario {
+			return
+		}
+		if partition != "" && val.Partition != partition {
+			return
+		}
+		ret = append(ret, bucketInfo(val.Mapkey, val, now, maxEvents))
+	})
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Scenario != ret[j].Scenario {
+			return ret[i].Scenario < ret[j].Scenario
+		}
+		return ret[i].Partition < ret[j].Partition
+	})
+	return ret
+}
+
+//InspectBucket returns the bucket of key, with all its events
+func InspectBucket(buckets *Buckets, key string) (BucketInfo, error) {
+	var (
+		info  BucketInfo
+		found bool
+		err   error
+	)
+
+	buckets.visit(key, func(val *Leaky) {
+		found = true
+		if val.First_ts.IsZero() {
+			err = fmt.Errorf("bucket %s is being created", key)
+			return
+		}
+		info = bucketInfo(key, val, time.Now(), -1)
+	})
+	if !found {
+		return BucketInfo{}, fmt.Errorf("no bucket %s", key)
+	}
+	return info, err
+}
+
+//DeleteBucket destroys the bucket of key without overflow
+func DeleteBucket(buckets *Buckets, key string) error {
+	biface, ok := buckets.Bucket_map.Load(key)
+	if !ok {
+		return fmt.Errorf("no bucket %s", key)
+	}
+	if !buckets.kill(biface.(*Leaky)) {
+		return fmt.Errorf("bucket %s is already being destroyed", key)
+	}
+	buckets.forget(biface.(*Leaky))
+	return nil
+}
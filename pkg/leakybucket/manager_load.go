@@ -7,7 +7,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
@@ -34,28 +36,43 @@ type BucketFactory struct {
 	Author          string                    `yaml:"author"`
 	Description     string                    `yaml:"description"`
 	References      []string                  `yaml:"references"`
-	Type            string                    `yaml:"type"`                //Type can be : leaky, counter, trigger. It determines the main bucket characteristics
+	Type            string                    `yaml:"type"`                //Type can be : leaky, counter, trigger, sequence, conditional, cardinality. It determines the main bucket characteristics
 	Name            string                    `yaml:"name"`                //Name of the bucket, used later in log and user-messages. Should be unique
 	Capacity        int                       `yaml:"capacity"`            //Capacity is applicable to leaky buckets and determines the "burst" capacity
 	LeakSpeed       string                    `yaml:"leakspeed"`           //Leakspeed is a float representing how many events per second leak out of the bucket
-	Duration        string                    `yaml:"duration"`            //Duration allows 'counter' buckets to have a fixed life-time
+	Duration        string                    `yaml:"duration"`            //Duration allows 'counter' buckets to have a fixed life-time, and is the max time for 'sequence' buckets to complete their steps
+	Steps           []SequenceStep            `yaml:"steps,omitempty"`     //Steps are the ordered filters a 'sequence' bucket must match before overflowing
+	Condition       string                    `yaml:"condition,omitempty"` //Condition is an expr evaluated against the queue of 'conditional' buckets, they overflow when it's true
 	Filter          string                    `yaml:"filter"`              //Filter is an expr that determines if an event is elligible for said bucket. Filter is evaluated against the Event struct
 	GroupBy         string                    `yaml:"groupby,omitempty"`   //groupy is an expr that allows to determine the partitions of the bucket. A common example is the source_ip
+	CancelOn        string                    `yaml:"cancel_on,omitempty"` //CancelOn is an expr that, when true for an event, destroys the existing bucket of its partition without overflow
 	Distinct        string                    `yaml:"distinct"`            //Distinct, when present, adds a `Pour()` processor that will only pour uniq items (based on distinct expr result)
+	Probabilistic   *ProbabilisticCfg         `yaml:"probabilistic"`       //Probabilistic, when present, makes distinct use a bloom filter, and sets the error rate of 'cardinality' buckets
+	Anomaly         *AnomalyCfg               `yaml:"anomaly,omitempty"`   //Anomaly configures how 'anomaly' buckets learn the baselines of the partitions
+	Sources         *SourcesCfg               `yaml:"sources,omitempty"`   //Sources, when present, makes the alerts carry all the distinct sources seen by the bucket
+	Metrics         []*MetricCfg              `yaml:"metrics,omitempty"`   //Metrics are the prometheus metrics fed by the events matching the filter
+	AlertContext    *csconfig.AlertContextCfg `yaml:"alert_context"`       //AlertContext selects the fields of the events and the events carried by the alerts, instead of the global one
 	Debug           bool                      `yaml:"debug"`               //Debug, when set to true, will enable debugging for _this_ scenario specifically
 	Labels          map[string]string         `yaml:"labels"`              //Labels is K:V list aiming at providing context the overflow
+	Severity        string                    `yaml:"severity"`            //Severity is how serious the detected behavior is : info, low, medium, high or critical
+	Confidence      int                       `yaml:"confidence"`          //Confidence is how likely the overflows are true positives, from 0 to 100
 	Blackhole       string                    `yaml:"blackhole,omitempty"` //Blackhole is a duration that, if present, will prevent same bucket partition to overflow more often than $duration
+	MaxBuckets      int                       `yaml:"max_buckets"`         //MaxBuckets, if > 0, is the max number of live buckets (partitions) of the scenario
+	Eviction        string                    `yaml:"eviction,omitempty"`  //Eviction is what to do when MaxBuckets is reached : oldest, least_filled or refuse. Defaults to the global policy
 	logger          *log.Entry                `yaml:"-"`                   //logger is bucket-specific logger (used by Debug as well)
 	Reprocess       bool                      `yaml:"reprocess"`           //Reprocess, if true, will for the bucket to be re-injected into processing chain
 	CacheSize       int                       `yaml:"cache_size"`          //CacheSize, if > 0, limits the size of in-memory cache of the bucket
 	Profiling       bool                      `yaml:"profiling"`           //Profiling, if true, will make the bucket record pours/overflows/etc.
 	OverflowFilter  string                    `yaml:"overflow_filter"`     //OverflowFilter if present, is a filter that must return true for the overflow to go through
 	ScopeType       types.ScopeType           `yaml:"scope,omitempty"`     //to enforce a different remediation than blocking an IP. Will default this to IP
+	Params          map[string]interface{}    `yaml:"params,omitempty"`    //Params are the parameters the scenario was instantiated with, if it's a template
+	Template        string                    `yaml:"-"`                   //Template is the name of the scenario template, if the scenario is one of its instances
 	BucketName      string                    `yaml:"-"`
 	Filename        string                    `yaml:"-"`
 	RunTimeFilter   *vm.Program               `json:"-"`
 	ExprDebugger    *exprhelpers.ExprDebugger `yaml:"-" json:"-"` // used to debug expression by printing the content of each variable of the expression
 	RunTimeGroupBy  *vm.Program               `json:"-"`
+	RunTimeCancelOn *vm.Program               `json:"-"`
 	Data            []*types.DataSource       `yaml:"data,omitempty"`
 	DataDir         string                    `yaml:"-"`
 	leakspeed       time.Duration             //internal representation of `Leakspeed`
@@ -66,6 +83,12 @@ type BucketFactory struct {
 	ScenarioVersion string                    `yaml:"version,omitempty"`
 	hash            string                    `yaml:"-"`
 	Simulated       bool                      `yaml:"simulated"` //Set to true if the scenario instanciating the bucket was in the exclusion list
+	redaction       *csconfig.RedactionCfg    //privacy rules applied to the meta of the events sent in alerts
+	eventTime       *csconfig.EventTimeCfg    //if set, the live buckets of the scenario use the time of the events (cf. eventtime.go)
+	guard           *exprhelpers.ExprGuard    //counts the expressions faults, the scenario is skipped once quarantined
+	liveCount       *int64                    //number of live buckets of the scenario, shared by the copies of the factory
+	metrics         []*scenarioMetric         //the compiled metrics of the scenario (cf. metrics.go)
+	alertContext    *alertContext             //the compiled alert context of the scenario, if any (cf. alert_context.go)
 }
 
 func ValidateFactory(bucketFactory *BucketFactory) error {
@@ -99,10 +122,86 @@ func ValidateFactory(bucketFactory *BucketFactory) error {
 		if bucketFactory.Capacity != 0 {
 			return fmt.Errorf("trigger bucket must have 0 capacity")
 		}
+	} else if bucketFactory.Type == "conditional" {
+		if bucketFactory.Condition == "" {
+			return fmt.Errorf("conditional bucket must have a condition")
+		}
+		if bucketFactory.Capacity <= 0 { //capacity must be a positive int
+			return fmt.Errorf("bad capacity for conditional '%d'", bucketFactory.Capacity)
+		}
+		if bucketFactory.leakspeed == 0 {
+			return fmt.Errorf("bad leakspeed for conditional '%s'", bucketFactory.LeakSpeed)
+		}
+	} else if bucketFactory.Type == "cardinality" {
+		if bucketFactory.Distinct == "" {
+			return fmt.Errorf("cardinality bucket must have a distinct")
+		}
+		if bucketFactory.Capacity <= 0 {
+			return fmt.Errorf("bad capacity for cardinality '%d'", bucketFactory.Capacity)
+		}
+		if bucketFactory.duration == 0 {
+			return fmt.Errorf("cardinality bucket must have a duration")
+		}
+	} else if bucketFactory.Type == "anomaly" {
+		if bucketFactory.Anomaly == nil {
+			return fmt.Errorf("anomaly bucket must have an anomaly configuration")
+		}
+		if err := bucketFactory.Anomaly.Validate(); err != nil {
+			return fmt.Errorf("invalid anomaly : %s", err)
+		}
+		if bucketFactory.Capacity <= 0 {
+			return fmt.Errorf("bad capacity for anomaly '%d'", bucketFactory.Capacity)
+		}
+		if bucketFactory.duration == 0 {
+			return fmt.Errorf("anomaly bucket must have a duration")
+		}
+	} else if bucketFactory.Type == "sequence" {
+		if len(bucketFactory.Steps) == 0 {
+			return fmt.Errorf("sequence bucket must have steps")
+		}
+		if bucketFactory.duration == 0 {
+			return fmt.Errorf("sequence bucket must have a duration")
+		}
+		if bucketFactory.Capacity != 0 {
+			return fmt.Errorf("sequence bucket must have 0 capacity")
+		}
+	} else if bucketFactory.Type == "metric" {
+		if len(bucketFactory.Metrics) == 0 {
+			return fmt.Errorf("metric bucket must have metrics")
+		}
 	} else {
 		return fmt.Errorf("unknown bucket type '%s'", bucketFactory.Type)
 	}
 
+	if err := bucketFactory.Probabilistic.Validate(); err != nil {
+		return fmt.Errorf("invalid probabilistic : %s", err)
+	}
+	if bucketFactory.MaxBuckets < 0 {
+		return fmt.Errorf("max_buckets must be positive, got %d", bucketFactory.MaxBuckets)
+	}
+	if err := validateEviction(bucketFactory.Eviction); err != nil {
+		return err
+	}
+	for _, metric := range bucketFactory.Metrics {
+		if metric == nil {
+			return fmt.Errorf("empty metric")
+		}
+		if err := metric.Validate(); err != nil {
+			return fmt.Errorf("invalid metric : %s", err)
+		}
+	}
+	if err := validateSeverity(bucketFactory.Severity); err != nil {
+		return err
+	}
+	if bucketFactory.Confidence < 0 || bucketFactory.Confidence > 100 {
+		return fmt.Errorf("confidence must be between 0 and 100, got %d", bucketFactory.Confidence)
+	}
+	if bucketFactory.Sources != nil {
+		if err := bucketFactory.Sources.Validate(); err != nil {
+			return fmt.Errorf("invalid sources : %s", err)
+		}
+	}
+
 	switch bucketFactory.ScopeType.Scope {
 	case types.Undefined:
 		bucketFactory.ScopeType.Scope = types.Ip
@@ -122,6 +221,18 @@ func ValidateFactory(bucketFactory *BucketFactory) error {
 	return nil
 }
 
+func validateSeverity(severity string) error {
+	if severity == "" {
+		return nil
+	}
+	for _, level := range types.SeverityLevels {
+		if severity == level {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity '%s', must be one of %s", severity, strings.Join(types.SeverityLevels, ", "))
+}
+
 func LoadBuckets(cscfg *csconfig.CrowdsecServiceCfg, files []string) ([]BucketFactory, chan types.Event, error) {
 	var (
 		ret      []BucketFactory = []BucketFactory{}
@@ -130,6 +241,18 @@ func LoadBuckets(cscfg *csconfig.CrowdsecServiceCfg, files []string) ([]BucketFa
 
 	var seed namegenerator.Generator = namegenerator.NewNameGenerator(time.Now().UTC().UnixNano())
 
+	if err := setBucketsCap(cscfg.BucketsCap); err != nil {
+		return nil, nil, fmt.Errorf("invalid buckets_cap : %s", err)
+	}
+	/*the metrics of the previously loaded scenarios are dropped, they are registered again as the scenarios are loaded*/
+	ScenarioMetrics.reset()
+
+	overrides, err := LoadScenariosOverrides(cscfg.OverridesFilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid scenarios overrides : %s", err)
+	}
+	overridden := make(map[string]bool)
+
 	response = make(chan types.Event, 1)
 	for _, f := range files {
 		log.Debugf("Loading '%s'", f)
@@ -147,8 +270,8 @@ func LoadBuckets(cscfg *csconfig.CrowdsecServiceCfg, files []string) ([]BucketFa
 		dec := yaml.NewDecoder(bucketConfigurationFile)
 		dec.SetStrict(true)
 		for {
-			bucketFactory := BucketFactory{}
-			err = dec.Decode(&bucketFactory)
+			var document yaml.MapSlice
+			err = dec.Decode(&document)
 			if err != nil {
 				if err == io.EOF {
 					log.Tracef("End of yaml file")
@@ -158,50 +281,83 @@ func LoadBuckets(cscfg *csconfig.CrowdsecServiceCfg, files []string) ([]BucketFa
 					return nil, nil, fmt.Errorf("bad yaml in %s : %v", f, err)
 				}
 			}
-			bucketFactory.DataDir = cscfg.DataDir
-			//check empty
-			if bucketFactory.Name == "" {
-				log.Errorf("Won't load nameless bucket")
-				return nil, nil, fmt.Errorf("nameless bucket")
-			}
-			//check compat
-			if bucketFactory.FormatVersion == "" {
-				log.Tracef("no version in %s : %s, assuming '1.0'", bucketFactory.Name, f)
-				bucketFactory.FormatVersion = "1.0"
-			}
-			ok, err := cwversion.Statisfies(bucketFactory.FormatVersion, cwversion.Constraint_scenario)
+			bucketFactories, err := expandScenario(document)
 			if err != nil {
-				log.Fatalf("Failed to check version : %s", err)
-			}
-			if !ok {
-				log.Errorf("can't load %s : %s doesn't satisfy scenario format %s, skip", bucketFactory.Name, bucketFactory.FormatVersion, cwversion.Constraint_scenario)
-				continue
+				log.Errorf("Bad scenario in %s : %v", f, err)
+				return nil, nil, fmt.Errorf("bad scenario in %s : %v", f, err)
 			}
+			for _, bucketFactory := range bucketFactories {
+				bucketFactory.DataDir = cscfg.DataDir
+				bucketFactory.redaction = cscfg.Redaction
+				bucketFactory.eventTime = cscfg.EventTime
+				if bucketFactory.AlertContext == nil {
+					bucketFactory.AlertContext = cscfg.AlertContext
+				}
+				//check empty
+				if bucketFactory.Name == "" {
+					log.Errorf("Won't load nameless bucket")
+					return nil, nil, fmt.Errorf("nameless bucket")
+				}
+				//check compat
+				if bucketFactory.FormatVersion == "" {
+					log.Tracef("no version in %s : %s, assuming '1.0'", bucketFactory.Name, f)
+					bucketFactory.FormatVersion = "1.0"
+				}
+				ok, err := cwversion.Statisfies(bucketFactory.FormatVersion, cwversion.Constraint_scenario)
+				if err != nil {
+					log.Fatalf("Failed to check version : %s", err)
+				}
+				if !ok {
+					log.Errorf("can't load %s : %s doesn't satisfy scenario format %s, skip", bucketFactory.Name, bucketFactory.FormatVersion, cwversion.Constraint_scenario)
+					continue
+				}
 
-			bucketFactory.Filename = filepath.Clean(f)
-			bucketFactory.BucketName = seed.Generate()
-			bucketFactory.ret = response
-			hubItem, err := cwhub.GetItemByPath(cwhub.SCENARIOS, bucketFactory.Filename)
-			if err != nil {
-				log.Errorf("scenario %s (%s) couldn't be find in hub (ignore if in unit tests)", bucketFactory.Name, bucketFactory.Filename)
-			} else {
-				if cscfg.SimulationConfig != nil {
-					bucketFactory.Simulated = cscfg.SimulationConfig.IsSimulated(hubItem.Name)
+				bucketFactory.Filename = filepath.Clean(f)
+				bucketFactory.BucketName = seed.Generate()
+				bucketFactory.ret = response
+				override, hasOverride := overrides[bucketFactory.Name]
+				if hasOverride {
+					changed := applyOverride(&bucketFactory, override)
+					log.Infof("scenario %s : %s overridden by %s", bucketFactory.Name, strings.Join(changed, ", "), cscfg.OverridesFilePath)
+					overridden[bucketFactory.Name] = true
 				}
-				if hubItem != nil {
-					bucketFactory.ScenarioVersion = hubItem.LocalVersion
-					bucketFactory.hash = hubItem.LocalHash
-				} else {
+				hubItem, err := cwhub.GetItemByPath(cwhub.SCENARIOS, bucketFactory.Filename)
+				if err != nil {
 					log.Errorf("scenario %s (%s) couldn't be find in hub (ignore if in unit tests)", bucketFactory.Name, bucketFactory.Filename)
+				} else {
+					if cscfg.SimulationConfig != nil {
+						bucketFactory.Simulated = cscfg.SimulationConfig.IsSimulated(hubItem.Name)
+						/*the instances of a template can be simulated on their own*/
+						if bucketFactory.Template != "" && !bucketFactory.Simulated {
+							bucketFactory.Simulated = cscfg.SimulationConfig.IsSimulated(bucketFactory.Name)
+						}
+					}
+					if hubItem != nil {
+						bucketFactory.ScenarioVersion = hubItem.LocalVersion
+						bucketFactory.hash = hubItem.LocalHash
+						if bucketFactory.Template != "" {
+							bucketFactory.hash = instanceHash(hubItem.LocalHash, bucketFactory.Name)
+						}
+					} else {
+						log.Errorf("scenario %s (%s) couldn't be find in hub (ignore if in unit tests)", bucketFactory.Name, bucketFactory.Filename)
+					}
 				}
-			}
 
-			err = LoadBucket(&bucketFactory)
-			if err != nil {
-				log.Errorf("Failed to load bucket %s : %v", bucketFactory.Name, err)
-				return nil, nil, fmt.Errorf("loading of %s failed : %v", bucketFactory.Name, err)
+				err = LoadBucket(&bucketFactory)
+				if err != nil && hasOverride {
+					err = fmt.Errorf("%v (with the overrides of %s)", err, cscfg.OverridesFilePath)
+				}
+				if err != nil {
+					log.Errorf("Failed to load bucket %s : %v", bucketFactory.Name, err)
+					return nil, nil, fmt.Errorf("loading of %s failed : %v", bucketFactory.Name, err)
+				}
+				ret = append(ret, bucketFactory)
 			}
-			ret = append(ret, bucketFactory)
+		}
+	}
+	for name := range overrides {
+		if !overridden[name] {
+			log.Warningf("scenario %s of %s isn't loaded, its overrides are ignored", name, cscfg.OverridesFilePath)
 		}
 	}
 	log.Warningf("Loaded %d scenarios", len(ret))
@@ -252,6 +408,9 @@ func LoadBucket(bucketFactory *BucketFactory) error {
 	if err != nil {
 		return fmt.Errorf("invalid filter '%s' in %s : %v", bucketFactory.Filter, bucketFactory.Filename, err)
 	}
+	if err := exprhelpers.CheckReturnKind(bucketFactory.Filter, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}}), reflect.Bool); err != nil {
+		return fmt.Errorf("invalid filter in %s : %v", bucketFactory.Filename, err)
+	}
 	if bucketFactory.Debug {
 		bucketFactory.ExprDebugger, err = exprhelpers.NewDebugger(bucketFactory.Filter, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
 		if err != nil {
@@ -264,7 +423,21 @@ func LoadBucket(bucketFactory *BucketFactory) error {
 		if err != nil {
 			return fmt.Errorf("invalid groupby '%s' in %s : %v", bucketFactory.GroupBy, bucketFactory.Filename, err)
 		}
+		if err := exprhelpers.CheckReturnKind(bucketFactory.GroupBy, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}}), reflect.String); err != nil {
+			return fmt.Errorf("invalid groupby in %s : %v", bucketFactory.Filename, err)
+		}
+	}
+	if bucketFactory.CancelOn != "" {
+		bucketFactory.RunTimeCancelOn, err = expr.Compile(bucketFactory.CancelOn, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
+		if err != nil {
+			return fmt.Errorf("invalid cancel_on '%s' in %s : %v", bucketFactory.CancelOn, bucketFactory.Filename, err)
+		}
+		if err := exprhelpers.CheckReturnKind(bucketFactory.CancelOn, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}}), reflect.Bool); err != nil {
+			return fmt.Errorf("invalid cancel_on in %s : %v", bucketFactory.Filename, err)
+		}
 	}
+	bucketFactory.guard = exprhelpers.NewExprGuard("scenario", bucketFactory.Name, bucketFactory.logger)
+	bucketFactory.liveCount = new(int64)
 
 	bucketFactory.logger.Infof("Adding %s bucket", bucketFactory.Type)
 	//return the Holder correponding to the type of bucket
@@ -276,15 +449,61 @@ func LoadBucket(bucketFactory *BucketFactory) error {
 		bucketFactory.processors = append(bucketFactory.processors, &Trigger{})
 	case "counter":
 		bucketFactory.processors = append(bucketFactory.processors, &DumbProcessor{})
+	case "conditional":
+		conditional, err := NewConditionalOverflow(bucketFactory)
+		if err != nil {
+			return fmt.Errorf("invalid conditional in %s : %v", bucketFactory.Filename, err)
+		}
+		bucketFactory.processors = append(bucketFactory.processors, conditional)
+	case "cardinality":
+		cardinality, err := NewCardinality(bucketFactory)
+		if err != nil {
+			return fmt.Errorf("invalid cardinality in %s : %v", bucketFactory.Filename, err)
+		}
+		bucketFactory.processors = append(bucketFactory.processors, cardinality)
+	case "anomaly":
+		anomaly, err := NewAnomaly(bucketFactory)
+		if err != nil {
+			return fmt.Errorf("invalid anomaly in %s : %v", bucketFactory.Filename, err)
+		}
+		bucketFactory.processors = append(bucketFactory.processors, anomaly)
+	case "sequence":
+		sequence, err := NewSequence(bucketFactory)
+		if err != nil {
+			return fmt.Errorf("invalid sequence in %s : %v", bucketFactory.Filename, err)
+		}
+		bucketFactory.processors = append(bucketFactory.processors, sequence)
+	case "metric":
+		/*metric scenarios don't have buckets, they only feed their metrics*/
 	default:
 		return fmt.Errorf("invalid type '%s' in %s : %v", bucketFactory.Type, bucketFactory.Filename, err)
 	}
 
-	if bucketFactory.Distinct != "" {
+	/*cardinality buckets count the distinct values themselves*/
+	if bucketFactory.Distinct != "" && bucketFactory.Type != "cardinality" {
 		bucketFactory.logger.Tracef("Adding a non duplicate filter on %s.", bucketFactory.Name)
 		bucketFactory.processors = append(bucketFactory.processors, &Uniq{})
 	}
 
+	if bucketFactory.Sources != nil {
+		bucketFactory.logger.Tracef("Adding a sources collector")
+		collector, err := NewSourcesCollector(bucketFactory)
+		if err != nil {
+			return fmt.Errorf("invalid sources in %s : %v", bucketFactory.Filename, err)
+		}
+		bucketFactory.processors = append(bucketFactory.processors, collector)
+	}
+
+	if bucketFactory.AlertContext != nil {
+		if bucketFactory.alertContext, err = newAlertContext(bucketFactory.AlertContext); err != nil {
+			return fmt.Errorf("invalid alert_context in %s : %v", bucketFactory.Filename, err)
+		}
+	}
+
+	if err := loadMetrics(bucketFactory); err != nil {
+		return fmt.Errorf("invalid metrics in %s : %v", bucketFactory.Filename, err)
+	}
+
 	if bucketFactory.OverflowFilter != "" {
 		bucketFactory.logger.Tracef("Adding an overflow filter")
 		filovflw, err := NewOverflowFilter(bucketFactory)
@@ -327,15 +546,25 @@ func LoadBucket(bucketFactory *BucketFactory) error {
 }
 
 func LoadBucketsState(file string, buckets *Buckets, bucketFactories []BucketFactory) error {
-	var state map[string]Leaky
+	var state bucketsSnapshot
+	var fields map[string]json.RawMessage
 	body, err := ioutil.ReadFile(file)
 	if err != nil {
 		return fmt.Errorf("can't state file %s : %s", file, err)
 	}
-	if err := json.Unmarshal(body, &state); err != nil {
+	if err := json.Unmarshal(body, &fields); err != nil {
 		return fmt.Errorf("can't unmarshal state file %s : %s", file, err)
 	}
-	for k, v := range state {
+	/*older dumps only hold the buckets, by key*/
+	if _, ok := fields["buckets"]; ok {
+		err = json.Unmarshal(body, &state)
+	} else {
+		err = json.Unmarshal(body, &state.Buckets)
+	}
+	if err != nil {
+		return fmt.Errorf("can't unmarshal state file %s : %s", file, err)
+	}
+	for k, v := range state.Buckets {
 		var tbucket *Leaky
 		log.Debugf("Reloading bucket %s", k)
 		val, ok := buckets.Bucket_map.Load(k)
@@ -347,29 +576,9 @@ func LoadBucketsState(file string, buckets *Buckets, bucketFactories []BucketFac
 		for _, h := range bucketFactories {
 			if h.Name == v.Name {
 				log.Debugf("found factory %s/%s -> %s", h.Author, h.Name, h.Description)
-				//check in which mode the bucket was
-				if v.Mode == TIMEMACHINE {
-					tbucket = NewTimeMachine(h)
-				} else if v.Mode == LIVE {
-					tbucket = NewLeaky(h)
-				} else {
-					log.Errorf("Unknown bucket type : %d", v.Mode)
+				if tbucket = restoreBucket(k, v, h); tbucket != nil {
+					startBucket(tbucket, buckets)
 				}
-				/*Trying to restore queue state*/
-				tbucket.Queue = v.Queue
-				/*Trying to set the limiter to the saved values*/
-				tbucket.Limiter.Load(v.SerializedState)
-				tbucket.In = make(chan types.Event)
-				tbucket.Mapkey = k
-				tbucket.Signal = make(chan bool, 1)
-				tbucket.KillSwitch = make(chan bool, 1)
-				tbucket.First_ts = v.First_ts
-				tbucket.Last_ts = v.Last_ts
-				tbucket.Ovflw_ts = v.Ovflw_ts
-				tbucket.Total_count = v.Total_count
-				buckets.Bucket_map.Store(k, tbucket)
-				go LeakRoutine(tbucket)
-				<-tbucket.Signal
 				found = true
 				break
 			}
@@ -379,7 +588,68 @@ func LoadBucketsState(file string, buckets *Buckets, bucketFactories []BucketFac
 		}
 	}
 
-	log.Infof("Restored %d buckets from dump", len(state))
+	factories := make(map[string]BucketFactory)
+	for _, h := range bucketFactories {
+		factories[h.Name] = h
+	}
+	learned := restoreHoldersBaselines(state.Baselines, factories, state.Time)
+
+	log.Infof("Restored %d buckets and %d anomaly baselines from dump", len(state.Buckets), learned)
 	return nil
 
 }
+
+//restoreBucket creates back the bucket key of factory h from its dumped state v, without starting it
+func restoreBucket(k string, v Leaky, h BucketFactory) *Leaky {
+	var tbucket *Leaky
+	//check in which mode the bucket was
+	if v.Mode == TIMEMACHINE {
+		tbucket = NewTimeMachine(h)
+	} else if v.Mode == LIVE {
+		tbucket = NewLeaky(h)
+	} else if v.Mode == HYBRID {
+		tbucket = NewHybrid(h)
+	} else {
+		log.Errorf("Unknown bucket type : %d", v.Mode)
+		return nil
+	}
+	/*Trying to restore queue state*/
+	tbucket.Queue = v.Queue
+	/*Trying to set the limiter to the saved values*/
+	tbucket.Limiter.Load(v.SerializedState)
+	tbucket.Mapkey = k
+	tbucket.Partition = v.Partition
+	tbucket.First_ts = v.First_ts
+	tbucket.Last_ts = v.Last_ts
+	tbucket.Ovflw_ts = v.Ovflw_ts
+	tbucket.Total_count = v.Total_count
+	tbucket.SequenceStep = v.SequenceStep
+	tbucket.SequenceCount = v.SequenceCount
+	tbucket.Sources = v.Sources
+	if v.DistinctFilter != nil {
+		if err := v.DistinctFilter.Validate(); err != nil {
+			log.Errorf("ignoring distinct filter of bucket %s : %s", k, err)
+		} else {
+			tbucket.DistinctFilter = v.DistinctFilter
+		}
+	}
+	if v.Cardinality != nil {
+		if err := v.Cardinality.Validate(); err != nil {
+			log.Errorf("ignoring cardinality of bucket %s : %s", k, err)
+		} else {
+			tbucket.Cardinality = v.Cardinality
+		}
+	}
+	return tbucket
+}
+
+//startBucket hands the bucket to its shard, and waits for it to be stored
+func startBucket(tbucket *Leaky, buckets *Buckets) {
+	var done sync.WaitGroup
+
+	done.Add(1)
+	if !buckets.send(shardRequest{op: opRestore, key: tbucket.Mapkey, bucket: tbucket, done: &done}) {
+		return
+	}
+	done.Wait()
+}
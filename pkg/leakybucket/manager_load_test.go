@@ -2,7 +2,13 @@ package leakybucket
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	yaml "gopkg.in/yaml.v2"
 )
 
 type cfgTest struct {
@@ -116,3 +122,255 @@ func TestCounterBucketsConfig(t *testing.T) {
 	}
 
 }
+
+func TestConditionalBucketsConfig(t *testing.T) {
+	var CfgTests = []cfgTest{
+		//basic valid conditional
+		{BucketFactory{Name: "test", Description: "test1", Type: "conditional", Capacity: 10, LeakSpeed: "1s", Filter: "true",
+			Condition: "queue.Count() > 2 && queue.Ratio(\"evt.Meta.status == '404'\") > 0.5"}, true, true},
+		//missing condition
+		{BucketFactory{Name: "test", Description: "test1", Type: "conditional", Capacity: 10, LeakSpeed: "1s", Filter: "true"}, false, false},
+		//non-bool condition
+		{BucketFactory{Name: "test", Description: "test1", Type: "conditional", Capacity: 10, LeakSpeed: "1s", Filter: "true",
+			Condition: "queue.Count()"}, false, true},
+		//invalid helper expression
+		{BucketFactory{Name: "test", Description: "test1", Type: "conditional", Capacity: 10, LeakSpeed: "1s", Filter: "true",
+			Condition: "queue.Sum(\"evt.Meta.\") > 10"}, false, true},
+		//helper expression must be a literal
+		{BucketFactory{Name: "test", Description: "test1", Type: "conditional", Capacity: 10, LeakSpeed: "1s", Filter: "true",
+			Condition: "queue.Distinct(leaky.Name) > 10"}, false, true},
+		//missing leakspeed
+		{BucketFactory{Name: "test", Description: "test1", Type: "conditional", Capacity: 10, Filter: "true",
+			Condition: "queue.Count() > 2"}, false, false},
+	}
+	if err := runTest(CfgTests); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func TestCardinalityBucketsConfig(t *testing.T) {
+	var CfgTests = []cfgTest{
+		//basic valid cardinality
+		{BucketFactory{Name: "test", Description: "test1", Type: "cardinality", Capacity: 10, Duration: "10m", Filter: "true", Distinct: "evt.Meta.source_ip"}, true, true},
+		//with error rate
+		{BucketFactory{Name: "test", Description: "test1", Type: "cardinality", Capacity: 10, Duration: "10m", Filter: "true", Distinct: "evt.Meta.source_ip",
+			Probabilistic: &ProbabilisticCfg{ErrorRate: 0.05}}, true, true},
+		//invalid error rate
+		{BucketFactory{Name: "test", Description: "test1", Type: "cardinality", Capacity: 10, Duration: "10m", Filter: "true", Distinct: "evt.Meta.source_ip",
+			Probabilistic: &ProbabilisticCfg{ErrorRate: 2}}, false, false},
+		//missing distinct
+		{BucketFactory{Name: "test", Description: "test1", Type: "cardinality", Capacity: 10, Duration: "10m", Filter: "true"}, false, false},
+		//missing duration
+		{BucketFactory{Name: "test", Description: "test1", Type: "cardinality", Capacity: 10, Filter: "true", Distinct: "evt.Meta.source_ip"}, false, false},
+		//probabilistic distinct on leaky
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", Distinct: "evt.Meta.source_ip",
+			Probabilistic: &ProbabilisticCfg{ErrorRate: 0.001, ExpectedItems: 100}}, true, true},
+	}
+	if err := runTest(CfgTests); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func TestAnomalyBucketsConfig(t *testing.T) {
+	var CfgTests = []cfgTest{
+		//default baseline
+		{BucketFactory{Name: "test", Description: "test1", Type: "anomaly", Capacity: 10, Duration: "1m", Filter: "true", Anomaly: &AnomalyCfg{}}, true, true},
+		//seasonal baseline
+		{BucketFactory{Name: "test", Description: "test1", Type: "anomaly", Capacity: 10, Duration: "1m", Filter: "true",
+			Anomaly: &AnomalyCfg{Baseline: BaselineSeasonal, Training: "168h", Factor: 5, MinCount: 10}}, true, true},
+		//unknown baseline
+		{BucketFactory{Name: "test", Description: "test1", Type: "anomaly", Capacity: 10, Duration: "1m", Filter: "true", Anomaly: &AnomalyCfg{Baseline: "median"}}, false, false},
+		//factor below 1
+		{BucketFactory{Name: "test", Description: "test1", Type: "anomaly", Capacity: 10, Duration: "1m", Filter: "true", Anomaly: &AnomalyCfg{Factor: 0.5}}, false, false},
+		//invalid training
+		{BucketFactory{Name: "test", Description: "test1", Type: "anomaly", Capacity: 10, Duration: "1m", Filter: "true", Anomaly: &AnomalyCfg{Training: "1 day"}}, false, false},
+		//missing anomaly
+		{BucketFactory{Name: "test", Description: "test1", Type: "anomaly", Capacity: 10, Duration: "1m", Filter: "true"}, false, false},
+		//missing duration
+		{BucketFactory{Name: "test", Description: "test1", Type: "anomaly", Capacity: 10, Filter: "true", Anomaly: &AnomalyCfg{}}, false, false},
+	}
+	if err := runTest(CfgTests); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func TestMaxBucketsConfig(t *testing.T) {
+	var CfgTests = []cfgTest{
+		//max buckets with default eviction
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", MaxBuckets: 1000}, true, true},
+		//least filled eviction
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", MaxBuckets: 1000, Eviction: EvictLeastFilled}, true, true},
+		//unknown eviction
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", MaxBuckets: 1000, Eviction: "random"}, false, false},
+		//negative max buckets
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", MaxBuckets: -1}, false, false},
+	}
+	if err := runTest(CfgTests); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func TestSeverityConfig(t *testing.T) {
+	var CfgTests = []cfgTest{
+		//severity and confidence
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", Severity: "high", Confidence: 90}, true, true},
+		//unknown severity
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", Severity: "urgent"}, false, false},
+		//confidence out of range
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", Confidence: 101}, false, false},
+	}
+	if err := runTest(CfgTests); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	holder := BucketFactory{Labels: map[string]string{"type": "bruteforce", "severity": "low"}, Severity: "high", Confidence: 90}
+	labels := alertLabels(&holder)
+	expected := []string{"confidence:90", "severity:high", "type:bruteforce"}
+	if fmt.Sprintf("%v", labels) != fmt.Sprintf("%v", expected) {
+		t.Fatalf("expected labels %v, got %v", expected, labels)
+	}
+}
+
+func TestScenariosOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crowdsec-overrides")
+	if err != nil {
+		t.Fatalf("unable to create temp dir : %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	scenario := filepath.Join(dir, "scenario.yaml")
+	err = ioutil.WriteFile(scenario, []byte(`type: leaky
+name: test/overridden
+description: overridden scenario
+filter: "evt.Meta.log_type == 'ssh_failed-auth'"
+groupby: evt.Meta.source_ip
+capacity: 5
+leakspeed: 10s
+blackhole: 1m
+labels:
+  remediation: true
+  service: ssh
+`), 0644)
+	if err != nil {
+		t.Fatalf("unable to write scenario : %s", err)
+	}
+	overrides := filepath.Join(dir, "scenarios_overrides.yaml")
+	cscfg := &csconfig.CrowdsecServiceCfg{DataDir: dir, OverridesFilePath: overrides}
+
+	/*no overrides file*/
+	holders, _, err := LoadBuckets(cscfg, []string{scenario})
+	if err != nil {
+		t.Fatalf("failed loading bucket without overrides : %s", err)
+	}
+	if holders[0].Capacity != 5 || holders[0].LeakSpeed != "10s" {
+		t.Fatalf("unexpected settings without overrides : %d/%s", holders[0].Capacity, holders[0].LeakSpeed)
+	}
+
+	err = ioutil.WriteFile(overrides, []byte(`test/overridden:
+  capacity: 10
+  leakspeed: 20s
+  exclude: evt.Meta.source_ip == '1.2.3.4'
+  labels:
+    remediation: false
+test/not-loaded:
+  capacity: 1
+`), 0644)
+	if err != nil {
+		t.Fatalf("unable to write overrides : %s", err)
+	}
+	holders, _, err = LoadBuckets(cscfg, []string{scenario})
+	if err != nil {
+		t.Fatalf("failed loading bucket with overrides : %s", err)
+	}
+	bucketFactory := holders[0]
+	if bucketFactory.Capacity != 10 || bucketFactory.LeakSpeed != "20s" || bucketFactory.Blackhole != "1m" {
+		t.Fatalf("unexpected overridden settings : %d/%s/%s", bucketFactory.Capacity, bucketFactory.LeakSpeed, bucketFactory.Blackhole)
+	}
+	if bucketFactory.Filter != "(evt.Meta.log_type == 'ssh_failed-auth') && !(evt.Meta.source_ip == '1.2.3.4')" {
+		t.Fatalf("unexpected overridden filter : %s", bucketFactory.Filter)
+	}
+	if bucketFactory.Labels["remediation"] != "false" || bucketFactory.Labels["service"] != "ssh" {
+		t.Fatalf("unexpected overridden labels : %+v", bucketFactory.Labels)
+	}
+
+	/*the overridden scenario must still be valid*/
+	if err := ioutil.WriteFile(overrides, []byte("test/overridden:\n  capacity: 0\n"), 0644); err != nil {
+		t.Fatalf("unable to write overrides : %s", err)
+	}
+	if _, _, err := LoadBuckets(cscfg, []string{scenario}); err == nil {
+		t.Fatalf("expected a leaky bucket without capacity to fail")
+	}
+	if err := ioutil.WriteFile(overrides, []byte("test/overridden:\n  groupby: evt.Meta.target_user\n"), 0644); err != nil {
+		t.Fatalf("unable to write overrides : %s", err)
+	}
+	if _, _, err := LoadBuckets(cscfg, []string{scenario}); err == nil {
+		t.Fatalf("expected an unknown override to fail")
+	}
+}
+
+func TestScenarioTemplates(t *testing.T) {
+	var tests = []struct {
+		document string
+		names    []string
+		valid    bool
+	}{
+		//no params
+		{"name: test\ncapacity: 1\n", []string{"test"}, true},
+		//defaults only
+		{"name: test\nparams:\n  cap: 2\ncapacity: '{{ .cap }}'\n", []string{"test"}, true},
+		//instances
+		{"name: test\nparams:\n  cap: 2\ncapacity: '{{ .cap }}'\ninstances:\n - name: test-1\n - name: test-2\n   params:\n     cap: 3\n", []string{"test-1", "test-2"}, true},
+		//undeclared param in template
+		{"name: test\ncapacity: '{{ .cap }}'\n", nil, false},
+		//undeclared param in instance
+		{"name: test\nparams:\n  cap: 2\ninstances:\n - name: test-1\n   params:\n     capa: 3\n", nil, false},
+		//nameless instance
+		{"name: test\ninstances:\n - params: {}\n", nil, false},
+		//duplicate instance
+		{"name: test\ninstances:\n - name: test-1\n - name: test-1\n", nil, false},
+		//param of the wrong type
+		{"name: test\nparams:\n  cap: two\ncapacity: '{{ .cap }}'\n", nil, false},
+	}
+	for idx, test := range tests {
+		var document yaml.MapSlice
+		if err := yaml.Unmarshal([]byte(test.document), &document); err != nil {
+			t.Fatalf("(%d/%d) invalid test document : %s", idx+1, len(tests), err)
+		}
+		bucketFactories, err := expandScenario(document)
+		if !test.valid {
+			if err == nil {
+				t.Fatalf("(%d/%d) expected an error", idx+1, len(tests))
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("(%d/%d) unexpected error : %s", idx+1, len(tests), err)
+		}
+		if len(bucketFactories) != len(test.names) {
+			t.Fatalf("(%d/%d) expected %d scenarios, got %d", idx+1, len(tests), len(test.names), len(bucketFactories))
+		}
+		for i, bucketFactory := range bucketFactories {
+			if bucketFactory.Name != test.names[i] {
+				t.Fatalf("(%d/%d) expected scenario %s, got %s", idx+1, len(tests), test.names[i], bucketFactory.Name)
+			}
+		}
+	}
+
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal([]byte("name: test\nparams:\n  cap: 2\ncapacity: '{{ .cap }}'\nfilter: \"evt.Meta.cap == '{{ .cap }}'\"\ninstances:\n - name: test-1\n - name: test-2\n   params:\n     cap: 3\n"), &document); err != nil {
+		t.Fatalf("invalid test document : %s", err)
+	}
+	bucketFactories, err := expandScenario(document)
+	if err != nil {
+		t.Fatalf("unexpected error : %s", err)
+	}
+	if bucketFactories[0].Capacity != 2 || bucketFactories[1].Capacity != 3 || bucketFactories[1].Filter != "evt.Meta.cap == '3'" {
+		t.Fatalf("unexpected instances : %d, %d/%s", bucketFactories[0].Capacity, bucketFactories[1].Capacity, bucketFactories[1].Filter)
+	}
+	if bucketFactories[1].Template != "test" {
+		t.Fatalf("expected the template of the instance to be test, got '%s'", bucketFactories[1].Template)
+	}
+	if instanceHash("hash", "test-1") == instanceHash("hash", "test-2") {
+		t.Fatalf("expected distinct hashes for the instances")
+	}
+}
@@ -2,7 +2,6 @@ package leakybucket
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
@@ -11,8 +10,8 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
-	"github.com/antonmedv/expr"
 	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/models"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -25,51 +24,106 @@ Thus we need to garbage collect them to avoid a skyrocketing memory usage.*/
 func GarbageCollectBuckets(deadline time.Time, buckets *Buckets) error {
 	total := 0
 	discard := 0
-	toflush := []string{}
+	toflush := []*Leaky{}
+	//bucket already overflowed (or ended), we can forget it
 	buckets.Bucket_map.Range(func(rkey, rvalue interface{}) bool {
-		key := rkey.(string)
 		val := rvalue.(*Leaky)
-		total += 1
-		//bucket already overflowed, we can kill it
-		if !val.Ovflw_ts.IsZero() {
+		if val.isDead() {
 			discard += 1
-			val.logger.Debugf("overflowed at %s.", val.Ovflw_ts)
-			toflush = append(toflush, key)
-			val.KillSwitch <- true
-			return true
+			toflush = append(toflush, val)
 		}
-		/*FIXME : sometimes the gettokenscountat has some rounding issues when we try to
-		match it with bucket capacity, even if the bucket has long due underflow. Round to 2 decimals*/
-		tokat := val.Limiter.GetTokensCountAt(deadline)
-		tokcapa := float64(val.Capacity)
-		tokat = math.Round(tokat*100) / 100
-		tokcapa = math.Round(tokcapa*100) / 100
+		return true
+	})
+	buckets.visit("", func(val *Leaky) {
+		total += 1
 		//bucket actually underflowed based on log time, but no in real time
-		if tokat >= tokcapa {
+		if underflowAt(val, deadline) {
 			BucketsUnderflow.With(prometheus.Labels{"name": val.Name}).Inc()
-			val.logger.Debugf("UNDERFLOW : first_ts:%s tokens_at:%f capcity:%f", val.First_ts, tokat, tokcapa)
-			toflush = append(toflush, key)
-			val.KillSwitch <- true
-			return true
-		} else {
-			val.logger.Tracef("(%s) not dead, count:%f capacity:%f", val.First_ts, tokat, tokcapa)
-		}
-		if _, ok := serialized[key]; ok {
-			log.Errorf("entry %s already exists", key)
-			return false
-		} else {
-			log.Debugf("serialize %s of %s : %s", val.Name, val.Uuid, val.Mapkey)
+			toflush = append(toflush, val)
 		}
-		return true
 	})
 	log.Infof("Cleaned %d buckets", len(toflush))
-	for _, flushkey := range toflush {
-		buckets.Bucket_map.Delete(flushkey)
+	for _, val := range toflush {
+		buckets.kill(val)
+		buckets.forget(val)
 	}
 	return nil
 }
 
-func DumpBucketsStateAt(deadline time.Time, outputdir string, buckets *Buckets) (string, error) {
+/*
+underflowAt returns true if the bucket underflowed at deadline.
+Sequence, conditional and cardinality buckets don't leak (their limiter is always full) : they underflow after their duration without events.
+*/
+func underflowAt(val *Leaky, deadline time.Time) bool {
+	switch val.BucketConfig.Type {
+	case "sequence", "conditional", "cardinality":
+		if val.Duration != 0 && deadline.After(val.Last_ts.Add(val.Duration)) {
+			val.logger.Debugf("UNDERFLOW : last_ts:%s duration:%s", val.Last_ts, val.Duration)
+			return true
+		}
+		val.logger.Tracef("(%s) not dead, last_ts:%s duration:%s", val.First_ts, val.Last_ts, val.Duration)
+		return false
+	}
+	/*FIXME : sometimes the gettokenscountat has some rounding issues when we try to
+	match it with bucket capacity, even if the bucket has long due underflow. Round to 2 decimals*/
+	tokat := val.Limiter.GetTokensCountAt(deadline)
+	tokcapa := float64(val.Capacity)
+	tokat = math.Round(tokat*100) / 100
+	tokcapa = math.Round(tokcapa*100) / 100
+	if tokat >= tokcapa {
+		val.logger.Debugf("UNDERFLOW : first_ts:%s tokens_at:%f capcity:%f", val.First_ts, tokat, tokcapa)
+		return true
+	}
+	val.logger.Tracef("(%s) not dead, count:%f capacity:%f", val.First_ts, tokat, tokcapa)
+	return false
+}
+
+//copyLeaky returns a copy of the serialized state of the bucket, that stays valid once the shard of the bucket goes on
+func copyLeaky(val *Leaky) Leaky {
+	ret := Leaky{
+		Name:            val.Name,
+		Mode:            val.Mode,
+		SerializedState: val.Limiter.Dump(),
+		Queue:           &Queue{Queue: append([]types.Event{}, val.Queue.Queue...), L: val.Queue.L},
+		Capacity:        val.Capacity,
+		CacheSize:       val.CacheSize,
+		Mapkey:          val.Mapkey,
+		Reprocess:       val.Reprocess,
+		Simulated:       val.Simulated,
+		Uuid:            val.Uuid,
+		First_ts:        val.First_ts,
+		Last_ts:         val.Last_ts,
+		Ovflw_ts:        val.Ovflw_ts,
+		Total_count:     val.Total_count,
+		Leakspeed:       val.Leakspeed,
+		BucketConfig:    val.BucketConfig,
+		Duration:        val.Duration,
+		Profiling:       val.Profiling,
+		Partition:       val.Partition,
+		SequenceStep:    val.SequenceStep,
+		SequenceCount:   val.SequenceCount,
+	}
+	if val.DistinctFilter != nil {
+		filter := *val.DistinctFilter
+		filter.Bits = append([]byte{}, filter.Bits...)
+		ret.DistinctFilter = &filter
+	}
+	if val.Cardinality != nil {
+		cardinality := *val.Cardinality
+		cardinality.Registers = append([]byte{}, cardinality.Registers...)
+		ret.Cardinality = &cardinality
+	}
+	if val.Sources != nil {
+		ret.Sources = make(map[string]models.Source, len(val.Sources))
+		for key, src := range val.Sources {
+			ret.Sources[key] = src
+		}
+	}
+	return ret
+}
+
+//DumpBucketsStateAt dumps the buckets that are still alive at deadline, and the anomaly baselines of the scenarios of holders, to a temp file
+func DumpBucketsStateAt(deadline time.Time, outputdir string, buckets *Buckets, holders []BucketFactory) (string, error) {
 	//var file string
 
 	if outputdir == "" {
@@ -85,41 +139,27 @@ func DumpBucketsStateAt(deadline time.Time, outputdir string, buckets *Buckets)
 	log.Printf("Dumping buckets state at %s", deadline)
 	total := 0
 	discard := 0
-	buckets.Bucket_map.Range(func(rkey, rvalue interface{}) bool {
-		key := rkey.(string)
-		val := rvalue.(*Leaky)
+	buckets.visit("", func(val *Leaky) {
 		total += 1
 		if !val.Ovflw_ts.IsZero() {
 			discard += 1
 			val.logger.Debugf("overflowed at %s.", val.Ovflw_ts)
-			return true
+			return
 		}
-		/*FIXME : sometimes the gettokenscountat has some rounding issues when we try to
-		match it with bucket capacity, even if the bucket has long due underflow. Round to 2 decimals*/
-		tokat := val.Limiter.GetTokensCountAt(deadline)
-		tokcapa := float64(val.Capacity)
-		tokat = math.Round(tokat*100) / 100
-		tokcapa = math.Round(tokcapa*100) / 100
-
-		if tokat >= tokcapa {
+		if underflowAt(val, deadline) {
 			BucketsUnderflow.With(prometheus.Labels{"name": val.Name}).Inc()
-			val.logger.Debugf("UNDERFLOW : first_ts:%s tokens_at:%f capcity:%f", val.First_ts, tokat, tokcapa)
 			discard += 1
-			return true
-		} else {
-			val.logger.Debugf("(%s) not dead, count:%f capacity:%f", val.First_ts, tokat, tokcapa)
+			return
 		}
-		if _, ok := serialized[key]; ok {
-			log.Errorf("entry %s already exists", key)
-			return false
-		} else {
-			log.Debugf("serialize %s of %s : %s", val.Name, val.Uuid, val.Mapkey)
+		if _, ok := serialized[val.Mapkey]; ok {
+			log.Errorf("entry %s already exists", val.Mapkey)
+			return
 		}
-		val.SerializedState = val.Limiter.Dump()
-		serialized[key] = *val
-		return true
+		log.Debugf("serialize %s of %s : %s", val.Name, val.Uuid, val.Mapkey)
+		serialized[val.Mapkey] = copyLeaky(val)
 	})
-	bbuckets, err := json.MarshalIndent(serialized, "", " ")
+	dump := bucketsSnapshot{Time: deadline, Buckets: serialized, Baselines: holdersBaselines(holders)}
+	bbuckets, err := json.MarshalIndent(dump, "", " ")
 	if err != nil {
 		log.Fatalf("Failed to unmarshal buckets : %s", err)
 	}
@@ -127,7 +167,7 @@ func DumpBucketsStateAt(deadline time.Time, outputdir string, buckets *Buckets)
 	if err != nil {
 		return "", fmt.Errorf("failed to write temp file : %s", err)
 	}
-	log.Infof("Serialized %d live buckets (+%d expired) in %d bytes to %s", len(serialized), discard, size, tmpFd.Name())
+	log.Infof("Serialized %d live buckets (+%d expired) and the baselines of %d scenarios in %d bytes to %s", len(serialized), discard, len(dump.Baselines), size, tmpFd.Name())
 	serialized = nil
 	return tmpFileName, nil
 }
@@ -136,36 +176,55 @@ func ShutdownAllBuckets(buckets *Buckets) error {
 	buckets.Bucket_map.Range(func(rkey, rvalue interface{}) bool {
 		key := rkey.(string)
 		val := rvalue.(*Leaky)
-		val.KillSwitch <- true
+		buckets.kill(val)
 		log.Infof("killed %s", key)
 		return true
 	})
+	buckets.stop()
 	return nil
 }
 
+/*
+PourItemToHolders pours the event in the buckets of the holders it matches. The events are handed to the shards owning the buckets,
+without waiting for them : each shard processes its events in order, and the readers (dumps, snapshots ...) go through the shards.
+*/
 func PourItemToHolders(parsed types.Event, holders []BucketFactory, buckets *Buckets) (bool, error) {
 	var (
-		ok, condition, sent bool
-		err                 error
+		condition, sent bool
+		err             error
 	)
 
+	/*the same environment is used to evaluate the filter and groupby of all holders*/
+	env := exprhelpers.AcquireEvtEnv(&parsed)
+	defer exprhelpers.ReleaseEvtEnv(env)
+
 	for idx, holder := range holders {
 
-		if holder.RunTimeFilter != nil {
-			holder.logger.Tracef("event against holder %d/%d", idx, len(holders))
-			output, err := expr.Run(holder.RunTimeFilter, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &parsed}))
+		if holder.guard.Quarantined() {
+			holder.logger.Tracef("scenario is quarantined, skip")
+			continue
+		}
+
+		/*an event matching cancel_on destroys the bucket of its partition, whether it matches the filter or not*/
+		cancel := false
+		if holder.RunTimeCancelOn != nil {
+			cancel, err = holder.guard.RunBool(holder.RunTimeCancelOn, env)
 			if err != nil {
-				holder.logger.Errorf("failed parsing : %v", err)
-				return false, fmt.Errorf("leaky failed : %s", err)
+				holder.logger.Errorf("failed cancel_on : %v", err)
+				cancel = false
 			}
-			// we assume we a bool should add type check here
-			if condition, ok = output.(bool); !ok {
-				holder.logger.Errorf("unexpected non-bool return : %T", output)
-				holder.logger.Fatalf("Filter issue")
+		}
+
+		if holder.RunTimeFilter != nil && !cancel {
+			holder.logger.Tracef("event against holder %d/%d", idx, len(holders))
+			condition, err = holder.guard.RunBool(holder.RunTimeFilter, env)
+			if err != nil {
+				holder.logger.Errorf("failed filter : %v", err)
+				continue
 			}
 
 			if holder.Debug {
-				holder.ExprDebugger.Run(holder.logger, condition, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &parsed}))
+				holder.ExprDebugger.Run(holder.logger, condition, env)
 			}
 			if !condition {
 				holder.logger.Debugf("Event leaving node : ko (filter mismatch)")
@@ -173,115 +232,72 @@ func PourItemToHolders(parsed types.Event, holders []BucketFactory, buckets *Buc
 			}
 		}
 
+		/*the metrics of the scenario are fed by all the events matching its filter, metric scenarios don't have buckets*/
+		if !cancel {
+			observeMetrics(&holder, env)
+		}
+		if holder.Type == "metric" {
+			continue
+		}
+
 		sent = false
 		var groupby string
 		if holder.RunTimeGroupBy != nil {
-			tmpGroupBy, err := expr.Run(holder.RunTimeGroupBy, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &parsed}))
+			groupby, err = holder.guard.RunString(holder.RunTimeGroupBy, env)
 			if err != nil {
 				holder.logger.Errorf("failed groupby : %v", err)
-				return false, errors.New("leaky failed :/")
-			}
-
-			if groupby, ok = tmpGroupBy.(string); !ok {
-				holder.logger.Fatalf("failed groupby type : %v", err)
-				return false, errors.New("groupby wrong type")
+				continue
 			}
 		}
 		buckey := GetKey(holder, groupby)
 
-		sigclosed := 0
-		keymiss := 0
-		failed_sent := 0
-		attempts := 0
-		start := time.Now()
-		for !sent {
-			attempts += 1
-			/* Warn the user if we used more than a 100 ms to pour an event, it's at least an half lock*/
-			if attempts%100000 == 0 && start.Add(100*time.Millisecond).Before(time.Now()) {
-				holder.logger.Warningf("stuck for %s sending event to %s (sigclosed:%d keymiss:%d failed_sent:%d attempts:%d)", time.Since(start),
-					buckey, sigclosed, keymiss, failed_sent, attempts)
+		/*the buckets that ended stay in the map until their last event is processed*/
+		biface, ok := buckets.Bucket_map.Load(buckey)
+		exists := ok && !biface.(*Leaky).isDead()
+		if cancel {
+			if !exists {
+				holder.logger.Debugf("No bucket %s to cancel", buckey)
+				continue
 			}
-			biface, ok := buckets.Bucket_map.Load(buckey)
-			//biface, bigout
-			/* the bucket doesn't exist, create it !*/
-			if !ok {
-				/*
-					not found in map
-				*/
-
-				holder.logger.Debugf("Creating bucket %s", buckey)
-				keymiss += 1
-				var fresh_bucket *Leaky
-
-				switch parsed.ExpectMode {
-				case TIMEMACHINE:
-					fresh_bucket = NewTimeMachine(holder)
-					holder.logger.Debugf("Creating TimeMachine bucket")
-				case LIVE:
-					fresh_bucket = NewLeaky(holder)
-					holder.logger.Debugf("Creating Live bucket")
-				default:
-					holder.logger.Fatalf("input event has no expected mode, malformed : %+v", parsed)
-				}
-				fresh_bucket.In = make(chan types.Event)
-				fresh_bucket.Mapkey = buckey
-				fresh_bucket.Signal = make(chan bool, 1)
-				fresh_bucket.KillSwitch = make(chan bool, 1)
-				buckets.Bucket_map.Store(buckey, fresh_bucket)
-				go LeakRoutine(fresh_bucket)
-				holder.logger.Debugf("Created new bucket %s", buckey)
-				//wait for signal to be opened
-				<-fresh_bucket.Signal
+			buckets.send(shardRequest{op: opCancel, key: buckey, holder: &holders[idx]})
+			continue
+		}
+		/*in event-time mode, the live events are poured according to their time, the late ones are only counted*/
+		evt := parsed
+		if parsed.ExpectMode == LIVE && holder.eventTime != nil {
+			if lateEvent(&holder, parsed, time.Now()) {
 				continue
 			}
+			evt.ExpectMode = HYBRID
+		}
 
-			bucket := biface.(*Leaky)
-			/* check if leak routine is up */
-			select {
-			case _, ok := <-bucket.Signal:
-				if !ok {
-					//it's closed, delete it
-					bucket.logger.Debugf("Bucket %s found dead, cleanup the body", buckey)
-					buckets.Bucket_map.Delete(buckey)
-					sigclosed += 1
-					continue
-				}
-				holder.logger.Tracef("Signal exists, try to pour :)")
-
-			default:
-				/*nothing to read, but not closed, try to pour */
-				holder.logger.Tracef("Signal exists but empty, try to pour :)")
-
-			}
-			/*let's see if this time-bucket should have expired */
-			if bucket.Mode == TIMEMACHINE && !bucket.First_ts.IsZero() {
-				var d time.Time
-				err = d.UnmarshalText([]byte(parsed.MarshaledTime))
-				if err != nil {
-					holder.logger.Warningf("Failed unmarshaling event time (%s) : %v", parsed.MarshaledTime, err)
-				}
-				if d.After(bucket.Last_ts.Add(bucket.Duration)) {
-					bucket.logger.Tracef("bucket is expired (curr event: %s, bucket deadline: %s), kill", d, bucket.Last_ts.Add(bucket.Duration))
-					buckets.Bucket_map.Delete(buckey)
+		/*
+			the bucket is created later on by its shard : it is accounted for right away, so that the next events see the caps,
+			and the next events of the partition don't account for it again
+		*/
+		reserved := false
+		if !exists {
+			if _, creating := buckets.creating.LoadOrStore(buckey, true); !creating {
+				reserveLive(&holder)
+				if !makeRoom(&holder, buckets) {
+					releaseLive(&holder)
+					buckets.creating.Delete(buckey)
+					holder.logger.Debugf("Max number of live buckets reached, no bucket for %s", buckey)
 					continue
 				}
+				reserved = true
 			}
-			/*if we're here, let's try to pour */
-
-			select {
-			case bucket.In <- parsed:
-				holder.logger.Tracef("Successfully sent !")
-				//sent was successful !
-				sent = true
-				continue
-			default:
-				failed_sent += 1
-				holder.logger.Tracef("Failed to send, try again")
-				continue
+		}
 
+		sent = buckets.send(shardRequest{op: opPour, key: buckey, holder: &holders[idx], groupby: groupby, evt: evt, reserved: reserved})
+		if !sent {
+			if reserved {
+				releaseLive(&holder)
+				buckets.creating.Delete(buckey)
 			}
+			holder.logger.Warningf("buckets are stopped, event not poured in %s", buckey)
+			continue
 		}
-
 		holder.logger.Debugf("bucket '%s' is poured", holder.Name)
 	}
 	return sent, nil
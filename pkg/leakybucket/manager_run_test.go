@@ -2,10 +2,17 @@ package leakybucket
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	"github.com/crowdsecurity/crowdsec/pkg/models"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/go-openapi/strfmt"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -73,7 +80,7 @@ func TestGCandDump(t *testing.T) {
 
 	log.Printf("Dumping buckets state")
 	//dump remaining buckets
-	if _, err := DumpBucketsStateAt(time.Now(), ".", buckets); err != nil {
+	if _, err := DumpBucketsStateAt(time.Now(), ".", buckets, Holders); err != nil {
 		t.Fatalf("failed to dump buckets : %s", err)
 	}
 }
@@ -122,3 +129,479 @@ func TestShutdownBuckets(t *testing.T) {
 	}
 
 }
+
+func TestCancelOnLive(t *testing.T) {
+	var buckets *Buckets = NewBuckets()
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_leaky_cancel", Description: "test_leaky_cancel", Debug: true, Type: "leaky", Capacity: 5, LeakSpeed: "10m",
+			Filter: "evt.Meta.log_type == 'failed'", CancelOn: "evt.Meta.log_type == 'success'", GroupBy: "evt.Meta.source_ip"},
+	}
+	if err := LoadBucket(&Holders[0]); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	if err := ValidateFactory(&Holders[0]); err != nil {
+		t.Fatalf("while validating : %s", err)
+	}
+	Holders[0].ret = make(chan types.Event, 1)
+
+	pour := func(logType string, ip string) {
+		in := types.Event{Meta: map[string]string{"log_type": logType, "source_ip": ip}}
+		if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+			t.Fatalf("while pouring item : %s", err)
+		}
+		buckets.flush()
+	}
+	pour("failed", "1.2.3.4")
+	pour("failed", "1.2.3.5")
+	if err := expectBucketCount(buckets, 2); err != nil {
+		t.Fatal(err)
+	}
+	//no bucket to cancel in this partition
+	pour("success", "1.2.3.6")
+	pour("success", "1.2.3.4")
+
+	select {
+	case ret := <-Holders[0].ret:
+		if ret.Overflow.Alert != nil {
+			t.Fatalf("canceled bucket overflowed")
+		}
+		if ret.Overflow.Mapkey != GetKey(Holders[0], "1.2.3.4") {
+			t.Fatalf("wrong bucket canceled : %s", ret.Overflow.Mapkey)
+		}
+		buckets.Bucket_map.Delete(ret.Overflow.Mapkey)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("bucket wasn't canceled")
+	}
+	if err := expectBucketCount(buckets, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectBucketKeys(buckets *Buckets, holder BucketFactory, ips ...string) error {
+	if err := expectBucketCount(buckets, len(ips)); err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if _, ok := buckets.Bucket_map.Load(GetKey(holder, ip)); !ok {
+			return fmt.Errorf("no bucket for %s", ip)
+		}
+	}
+	return nil
+}
+
+func TestMaxBuckets(t *testing.T) {
+	tests := []struct {
+		eviction string
+		pours    []string
+		expected []string
+	}{
+		{EvictOldest, []string{"1.2.3.4", "1.2.3.5", "1.2.3.6"}, []string{"1.2.3.5", "1.2.3.6"}},
+		{EvictLeastFilled, []string{"1.2.3.4", "1.2.3.4", "1.2.3.5", "1.2.3.4", "1.2.3.6"}, []string{"1.2.3.4", "1.2.3.6"}},
+		{EvictRefuse, []string{"1.2.3.4", "1.2.3.5", "1.2.3.6"}, []string{"1.2.3.4", "1.2.3.5"}},
+	}
+	for _, test := range tests {
+		var buckets *Buckets = NewBuckets()
+		var Holders = []BucketFactory{
+			BucketFactory{Name: "test_max_buckets", Description: "test_max_buckets", Type: "leaky", Capacity: 5, LeakSpeed: "10m",
+				Filter: "true", GroupBy: "evt.Meta.source_ip", MaxBuckets: 2, Eviction: test.eviction},
+		}
+		if err := LoadBucket(&Holders[0]); err != nil {
+			t.Fatalf("while loading : %s", err)
+		}
+		/*the killed buckets send their cleanup event there*/
+		Holders[0].ret = make(chan types.Event, 10)
+		for _, ip := range test.pours {
+			in := types.Event{Meta: map[string]string{"source_ip": ip}}
+			if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+				t.Fatalf("while pouring item : %s", err)
+			}
+			/*let the bucket routine take the event and the evicted ones die*/
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err := expectBucketKeys(buckets, Holders[0], test.expected...); err != nil {
+			t.Fatalf("%s : %s", test.eviction, err)
+		}
+		if err := ShutdownAllBuckets(buckets); err != nil {
+			t.Fatalf("while shuting down buckets : %s", err)
+		}
+	}
+}
+
+func TestMaxBucketsSlowOutput(t *testing.T) {
+	var buckets *Buckets = NewBuckets()
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_max_buckets_slow", Description: "test_max_buckets_slow", Type: "leaky", Capacity: 5, LeakSpeed: "10m",
+			Filter: "true", GroupBy: "evt.Meta.source_ip", MaxBuckets: 2},
+	}
+	if err := LoadBucket(&Holders[0]); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	/*the output routines don't take the events of the evicted buckets*/
+	Holders[0].ret = make(chan types.Event)
+	for i := 0; i < 6; i++ {
+		in := types.Event{Meta: map[string]string{"source_ip": fmt.Sprintf("1.2.3.%d", i)}}
+		if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+			t.Fatalf("while pouring item : %s", err)
+		}
+		buckets.flush()
+	}
+	/*the evicted buckets don't count anymore, even if their last event wasn't sent*/
+	if count := atomic.LoadInt64(Holders[0].liveCount); count != 2 {
+		t.Fatalf("expected 2 live buckets, got %d", count)
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+}
+
+func TestEventTime(t *testing.T) {
+	var buckets *Buckets = NewBuckets()
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_event_time", Description: "test_event_time", Type: "leaky", Capacity: 2, LeakSpeed: "10s",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+	}
+	if err := LoadBucket(&Holders[0]); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	Holders[0].eventTime = &csconfig.EventTimeCfg{AllowedLateness: time.Minute}
+	Holders[0].ret = make(chan types.Event, 10)
+
+	now := time.Now()
+	pour := func(ago time.Duration) {
+		ts, _ := now.Add(-ago).MarshalText()
+		in := types.Event{MarshaledTime: string(ts), Meta: map[string]string{"source_ip": "1.2.3.4"}}
+		if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+			t.Fatalf("while pouring item : %s", err)
+		}
+	}
+	/*the late event isn't poured, and the bucket leaks between the delayed events read at once*/
+	pour(2 * time.Minute)
+	pour(50 * time.Second)
+	pour(30 * time.Second)
+	pour(10 * time.Second)
+	buckets.flush()
+
+	select {
+	case <-Holders[0].ret:
+		t.Fatalf("bucket overflowed, events weren't leaked according to their time")
+	default:
+	}
+	biface, ok := buckets.Bucket_map.Load(GetKey(Holders[0], "1.2.3.4"))
+	if !ok {
+		t.Fatalf("no bucket for 1.2.3.4")
+	}
+	bucket := biface.(*Leaky)
+	if bucket.Mode != HYBRID {
+		t.Fatalf("expected hybrid bucket, got mode %d", bucket.Mode)
+	}
+	if bucket.Total_count != 3 {
+		t.Fatalf("expected 3 events poured, got %d", bucket.Total_count)
+	}
+	if !bucket.First_ts.Equal(now.Add(-50*time.Second)) || !bucket.Last_ts.Equal(now.Add(-10*time.Second)) {
+		t.Fatalf("unexpected bucket times %s - %s", bucket.First_ts, bucket.Last_ts)
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+}
+
+func TestAlertsThrottle(t *testing.T) {
+	throttle := NewAlertsThrottle(&csconfig.AlertsCapCfg{Interval: time.Minute, MaxAlerts: 5, MaxPerScenario: 3,
+		Scenarios: map[string]int{"test/unbounded": 0}})
+	start := time.Now()
+	alert := func(scenario string) types.RuntimeAlert {
+		return types.RuntimeAlert{Alert: &models.Alert{Scenario: &scenario}}
+	}
+	allowed := func(scenario string, count int) int {
+		ret := 0
+		for i := 0; i < count; i++ {
+			if throttle.Allow(alert(scenario), start) {
+				ret++
+			}
+		}
+		return ret
+	}
+
+	if ok := allowed("test/runaway", 10); ok != 3 {
+		t.Fatalf("expected 3 alerts of test/runaway, got %d", ok)
+	}
+	/*the scenario cap doesn't apply, but the global one does*/
+	if ok := allowed("test/unbounded", 10); ok != 2 {
+		t.Fatalf("expected 2 alerts of test/unbounded, got %d", ok)
+	}
+	if summaries := throttle.Summaries(start.Add(30*time.Second), false); len(summaries) != 0 {
+		t.Fatalf("expected no summary before the end of the interval, got %d", len(summaries))
+	}
+	summaries := throttle.Summaries(start.Add(time.Minute), false)
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(summaries))
+	}
+	for idx, expected := range []struct {
+		scenario string
+		dropped  int32
+	}{{"test/runaway", 7}, {"test/unbounded", 8}} {
+		summary := summaries[idx]
+		if *summary.Alert.Scenario != ThrottleScenario || *summary.Alert.Source.Value != expected.scenario || *summary.Alert.EventsCount != expected.dropped ||
+			*summary.Alert.Source.Scope != "scenario" {
+			t.Fatalf("unexpected summary %d : %+v", idx, summary.Alert)
+		}
+		if err := summary.Alert.Validate(strfmt.Default); err != nil {
+			t.Fatalf("invalid summary : %s", err)
+		}
+	}
+	/*a new interval starts*/
+	start = start.Add(time.Minute)
+	if ok := allowed("test/runaway", 3); ok != 3 {
+		t.Fatalf("expected 3 alerts of test/runaway in the new interval, got %d", ok)
+	}
+	if summaries := throttle.Summaries(start.Add(time.Minute), false); len(summaries) != 0 {
+		t.Fatalf("expected no summary without dropped alerts, got %d", len(summaries))
+	}
+
+	/*no cap*/
+	throttle = NewAlertsThrottle(nil)
+	if ok := allowed("test/runaway", 10); ok != 10 {
+		t.Fatalf("expected all alerts without cap, got %d", ok)
+	}
+}
+
+func TestMaxBucketsAlert(t *testing.T) {
+	var buckets *Buckets = NewBuckets()
+	if err := setBucketsCap(&csconfig.BucketsCapCfg{MaxBuckets: 1, Eviction: EvictRefuse, AlertOnCap: true}); err != nil {
+		t.Fatalf("while setting cap : %s", err)
+	}
+	defer setBucketsCap(nil)
+	capWarnings.Lock()
+	capWarnings.last = make(map[string]time.Time)
+	capWarnings.Unlock()
+
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_max_buckets_alert", Description: "test_max_buckets_alert", Type: "leaky", Capacity: 5, LeakSpeed: "10m",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+	}
+	if err := LoadBucket(&Holders[0]); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	/*nobody reads the alerts while pouring : the alert must not block the pouring routine*/
+	Holders[0].ret = make(chan types.Event)
+	/*other tests might have left live buckets : either the first pour or the second one reaches the global cap*/
+	poured := make(chan error)
+	go func() {
+		for _, ip := range []string{"1.2.3.4", "1.2.3.5"} {
+			in := types.Event{Meta: map[string]string{"source_ip": ip}}
+			if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+				poured <- err
+				return
+			}
+		}
+		poured <- nil
+	}()
+	select {
+	case err := <-poured:
+		if err != nil {
+			t.Fatalf("while pouring item : %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("pouring is blocked by the alert")
+	}
+	if _, ok := buckets.Bucket_map.Load(GetKey(Holders[0], "1.2.3.5")); ok {
+		t.Fatalf("bucket was created over the global cap")
+	}
+
+	select {
+	case ret := <-Holders[0].ret:
+		if ret.Overflow.Alert == nil || *ret.Overflow.Alert.Scenario != CapScenario {
+			t.Fatalf("expected a %s alert, got %+v", CapScenario, ret.Overflow)
+		}
+		if err := ret.Overflow.Alert.Validate(strfmt.Default); err != nil {
+			t.Fatalf("invalid alert : %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no alert sent")
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+}
+
+func loadSnapshotHolders(t *testing.T) []BucketFactory {
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_snapshot_leaky", Description: "test_snapshot_leaky", Type: "leaky", Capacity: 5, LeakSpeed: "10m",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+		BucketFactory{Name: "test_snapshot_trigger", Description: "test_snapshot_trigger", Type: "trigger", Blackhole: "1h",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+	}
+	for idx := range Holders {
+		if err := LoadBucket(&Holders[idx]); err != nil {
+			t.Fatalf("while loading (%d/%d): %s", idx, len(Holders), err)
+		}
+		Holders[idx].ret = make(chan types.Event, 10)
+	}
+	return Holders
+}
+
+func blackholedKeys(holder BucketFactory) []HiddenKey {
+	for _, processor := range holder.processors {
+		if blackhole, ok := processor.(*Blackhole); ok {
+			return blackhole.hiddenKeysAt(time.Now())
+		}
+	}
+	return nil
+}
+
+func TestBucketsSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crowdsec-snapshot-")
+	if err != nil {
+		t.Fatalf("while creating temp dir : %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "buckets_state.json")
+
+	var buckets *Buckets = NewBuckets()
+	Holders := loadSnapshotHolders(t)
+	now, _ := time.Now().MarshalText()
+	in := types.Event{Meta: map[string]string{"source_ip": "1.2.3.4"}, MarshaledTime: string(now)}
+	if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+		t.Fatalf("while pouring item : %s", err)
+	}
+	buckets.flush()
+	if len(blackholedKeys(Holders[1])) != 1 {
+		t.Fatalf("trigger didn't overflow")
+	}
+	for i := 0; i < 2; i++ {
+		if err := SnapshotBucketsState(path, buckets, Holders); err != nil {
+			t.Fatalf("while writing snapshot : %s", err)
+		}
+	}
+	if _, err := os.Stat(previousSnapshot(path)); err != nil {
+		t.Fatalf("previous snapshot wasn't kept : %s", err)
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+
+	/*restart*/
+	buckets = NewBuckets()
+	Holders = loadSnapshotHolders(t)
+	if err := RestoreBucketsSnapshot(path, time.Hour, buckets, Holders); err != nil {
+		t.Fatalf("while restoring snapshot : %s", err)
+	}
+	if err := expectBucketKeys(buckets, Holders[0], "1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	biface, _ := buckets.Bucket_map.Load(GetKey(Holders[0], "1.2.3.4"))
+	if biface.(*Leaky).Total_count != 1 || len(biface.(*Leaky).Queue.GetQueue()) != 1 {
+		t.Fatalf("bucket state wasn't restored : %+v", biface)
+	}
+	if keys := blackholedKeys(Holders[1]); len(keys) != 1 || keys[0].Key != GetKey(Holders[1], "1.2.3.4") {
+		t.Fatalf("blackhole wasn't restored : %+v", keys)
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+
+	/*a corrupted snapshot falls back to the previous one*/
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatalf("while corrupting snapshot : %s", err)
+	}
+	buckets = NewBuckets()
+	Holders = loadSnapshotHolders(t)
+	if err := RestoreBucketsSnapshot(path, time.Hour, buckets, Holders); err != nil {
+		t.Fatalf("while restoring snapshot : %s", err)
+	}
+	if err := expectBucketKeys(buckets, Holders[0], "1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+
+	/*too old snapshots are ignored*/
+	buckets = NewBuckets()
+	if err := RestoreBucketsSnapshot(previousSnapshot(path), time.Nanosecond, buckets, Holders); err != nil {
+		t.Fatalf("while restoring snapshot : %s", err)
+	}
+	if err := expectBucketCount(buckets, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInspectBuckets(t *testing.T) {
+	var buckets *Buckets = NewBuckets()
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_inspect", Description: "test_inspect", Type: "leaky", Capacity: 5, LeakSpeed: "10m",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+	}
+	if err := LoadBucket(&Holders[0]); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	Holders[0].ret = make(chan types.Event, 10)
+	now, _ := time.Now().MarshalText()
+	for _, ip := range []string{"1.2.3.4", "1.2.3.4", "1.2.3.5"} {
+		in := types.Event{Meta: map[string]string{"source_ip": ip}, MarshaledTime: string(now)}
+		if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+			t.Fatalf("while pouring item : %s", err)
+		}
+	}
+	buckets.flush()
+
+	if infos := ListBuckets(buckets, "", "", 1); len(infos) != 2 || infos[0].Partition != "1.2.3.4" || len(infos[0].Events) != 1 {
+		t.Fatalf("unexpected buckets : %+v", infos)
+	}
+	if infos := ListBuckets(buckets, "other_scenario", "", 1); len(infos) != 0 {
+		t.Fatalf("unexpected buckets : %+v", infos)
+	}
+	infos := ListBuckets(buckets, "test_inspect", "1.2.3.4", 1)
+	if len(infos) != 1 {
+		t.Fatalf("expected one bucket, got %+v", infos)
+	}
+	info, err := InspectBucket(buckets, infos[0].Key)
+	if err != nil {
+		t.Fatalf("while inspecting : %s", err)
+	}
+	if info.EventsCount != 2 || len(info.Events) != 2 || info.Fill < 1.9 || info.Fill > 2 || info.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected bucket : %+v", info)
+	}
+	if err := DeleteBucket(buckets, info.Key); err != nil {
+		t.Fatalf("while deleting : %s", err)
+	}
+	if _, err := InspectBucket(buckets, info.Key); err == nil {
+		t.Fatalf("bucket wasn't deleted")
+	}
+	if err := expectBucketKeys(buckets, Holders[0], "1.2.3.5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+}
+
+func BenchmarkPourItemToHolders(b *testing.B) {
+	log.SetLevel(log.ErrorLevel)
+	defer log.SetLevel(log.InfoLevel)
+
+	var buckets *Buckets = NewBuckets()
+	/*like on a real setup, most scenarios don't match the event and only the filter is evaluated*/
+	var Holders []BucketFactory
+	for i := 0; i < 20; i++ {
+		Holders = append(Holders, BucketFactory{Name: fmt.Sprintf("bench_%d", i), Description: "bench", Type: "leaky", Capacity: 5, LeakSpeed: "10s",
+			Filter: fmt.Sprintf("evt.Meta.log_type == 'type_%d'", i), GroupBy: "evt.Meta.source_ip"})
+	}
+	for idx := range Holders {
+		if err := LoadBucket(&Holders[idx]); err != nil {
+			b.Fatalf("while loading (%d/%d): %s", idx, len(Holders), err)
+		}
+		if err := ValidateFactory(&Holders[idx]); err != nil {
+			b.Fatalf("while validating (%d/%d): %s", idx, len(Holders), err)
+		}
+	}
+
+	var in = types.Event{Meta: map[string]string{"log_type": "unknown", "source_ip": "1.2.3.4"}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+			b.Fatalf("while pouring item : %s", err)
+		}
+	}
+}
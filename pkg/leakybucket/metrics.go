@@ -0,0 +1,273 @@
+package leakybucket
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+The metrics of a scenario turn the events matching its filter into Prometheus counters or histograms, labeled by expressions on the
+events (ie. the failed logins by username, the 404 by vhost). They are exported on the prometheus listener along with crowdsec's own
+metrics. The scenarios of type 'metric' only feed their metrics, they don't have buckets and never overflow.
+*/
+
+const (
+	MetricCounter   = "counter"
+	MetricHistogram = "histogram"
+
+	DefaultMaxSeries = 1000
+)
+
+var (
+	metricNameRe  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	metricLabelRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+var ScenarioMetricsDropped = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cs_scenario_metrics_dropped_total",
+		Help: "Total observations of scenario metrics dropped because the max number of series was reached.",
+	},
+	[]string{"name"},
+)
+
+//MetricCfg is a Prometheus metric fed by the events matching the filter of the scenario
+type MetricCfg struct {
+	Name      string            `yaml:"name"`
+	Type      string            `yaml:"type,omitempty"`       //counter (default) or histogram
+	Help      string            `yaml:"help,omitempty"`       //the description of the metric, defaults to the description of the scenario
+	Labels    map[string]string `yaml:"labels,omitempty"`     //the names of the labels, and the expressions of their values
+	Value     string            `yaml:"value,omitempty"`      //the expression of the value added to the counter (defaults to 1) or observed by the histogram
+	Buckets   []float64         `yaml:"buckets,omitempty"`    //the buckets of the histogram, defaults to the prometheus ones
+	MaxSeries int               `yaml:"max_series,omitempty"` //the max number of distinct label values, defaults to 1000
+}
+
+func (m *MetricCfg) Validate() error {
+	if !metricNameRe.MatchString(m.Name) {
+		return fmt.Errorf("invalid metric name '%s'", m.Name)
+	}
+	if strings.HasPrefix(m.Name, "cs_") {
+		return fmt.Errorf("invalid metric name '%s' : the cs_ prefix is reserved to crowdsec metrics", m.Name)
+	}
+	switch m.Type {
+	case "":
+		m.Type = MetricCounter
+	case MetricCounter:
+	case MetricHistogram:
+		if m.Value == "" {
+			return fmt.Errorf("histogram %s must have a value", m.Name)
+		}
+	default:
+		return fmt.Errorf("unknown type '%s' for metric %s, must be %s or %s", m.Type, m.Name, MetricCounter, MetricHistogram)
+	}
+	for label := range m.Labels {
+		if !metricLabelRe.MatchString(label) || strings.HasPrefix(label, "__") {
+			return fmt.Errorf("invalid label name '%s' for metric %s", label, m.Name)
+		}
+	}
+	if m.MaxSeries == 0 {
+		m.MaxSeries = DefaultMaxSeries
+	}
+	if m.MaxSeries < 0 {
+		return fmt.Errorf("max_series of metric %s must be positive, got %d", m.Name, m.MaxSeries)
+	}
+	return nil
+}
+
+type scenarioMetric struct {
+	cfg           *MetricCfg
+	scenario      string
+	labelNames    []string
+	runTimeLabels []*vm.Program
+	runTimeValue  *vm.Program
+	counter       *prometheus.CounterVec
+	histogram     *prometheus.HistogramVec
+	lock          sync.Mutex
+	series        map[string]struct{}
+}
+
+func newScenarioMetric(bucketFactory *BucketFactory, cfg *MetricCfg) (*scenarioMetric, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	env := exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})
+	m := &scenarioMetric{cfg: cfg, scenario: bucketFactory.Name, series: make(map[string]struct{})}
+	for label := range cfg.Labels {
+		m.labelNames = append(m.labelNames, label)
+	}
+	sort.Strings(m.labelNames)
+	for _, label := range m.labelNames {
+		program, err := expr.Compile(cfg.Labels[label], expr.Env(env))
+		if err != nil {
+			return nil, fmt.Errorf("invalid label %s of metric %s : %v", label, cfg.Name, err)
+		}
+		m.runTimeLabels = append(m.runTimeLabels, program)
+	}
+	if cfg.Value != "" {
+		program, err := expr.Compile(cfg.Value, expr.Env(env))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of metric %s : %v", cfg.Name, err)
+		}
+		m.runTimeValue = program
+	}
+
+	help := cfg.Help
+	if help == "" {
+		help = bucketFactory.Description
+	}
+	if cfg.Type == MetricHistogram {
+		buckets := cfg.Buckets
+		if len(buckets) == 0 {
+			buckets = prometheus.DefBuckets
+		}
+		m.histogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: cfg.Name, Help: help, Buckets: buckets}, m.labelNames)
+	} else {
+		m.counter = prometheus.NewCounterVec(prometheus.CounterOpts{Name: cfg.Name, Help: help}, m.labelNames)
+	}
+	return m, nil
+}
+
+//toFloat converts the output of the value expression
+func toFloat(output interface{}) (float64, error) {
+	switch out := output.(type) {
+	case float64:
+		return out, nil
+	case int:
+		return float64(out), nil
+	case int64:
+		return float64(out), nil
+	case string:
+		return strconv.ParseFloat(out, 64)
+	}
+	return 0, fmt.Errorf("unexpected non-numeric value : %T", output)
+}
+
+//observe evaluates the labels and value of the metric against the event, and updates the series
+func (m *scenarioMetric) observe(holder *BucketFactory, env map[string]interface{}) {
+	values := make([]string, len(m.runTimeLabels))
+	for idx, program := range m.runTimeLabels {
+		output, err := holder.guard.Run(program, env)
+		if err != nil {
+			holder.logger.Errorf("failed label %s of metric %s : %v", m.labelNames[idx], m.cfg.Name, err)
+			return
+		}
+		values[idx] = fmt.Sprintf("%v", output)
+	}
+	value := float64(1)
+	if m.runTimeValue != nil {
+		output, err := holder.guard.Run(m.runTimeValue, env)
+		if err != nil {
+			holder.logger.Errorf("failed value of metric %s : %v", m.cfg.Name, err)
+			return
+		}
+		if value, err = toFloat(output); err != nil {
+			holder.logger.Debugf("no value for metric %s : %s", m.cfg.Name, err)
+			return
+		}
+	}
+	/*counters can't decrease*/
+	if m.counter != nil && value < 0 {
+		holder.logger.Debugf("negative value %f for counter %s, not added", value, m.cfg.Name)
+		return
+	}
+
+	key := strings.Join(values, "\xff")
+	m.lock.Lock()
+	if _, ok := m.series[key]; !ok {
+		if len(m.series) >= m.cfg.MaxSeries {
+			m.lock.Unlock()
+			holder.logger.Debugf("max series (%d) of metric %s reached, %v dropped", m.cfg.MaxSeries, m.cfg.Name, values)
+			ScenarioMetricsDropped.With(prometheus.Labels{"name": m.cfg.Name}).Inc()
+			return
+		}
+		m.series[key] = struct{}{}
+	}
+	m.lock.Unlock()
+
+	if m.counter != nil {
+		m.counter.WithLabelValues(values...).Add(value)
+	} else {
+		m.histogram.WithLabelValues(values...).Observe(value)
+	}
+}
+
+func (m *scenarioMetric) collector() prometheus.Collector {
+	if m.counter != nil {
+		return m.counter
+	}
+	return m.histogram
+}
+
+//observeMetrics feeds the metrics of the scenario with an event matching its filter
+func observeMetrics(holder *BucketFactory, env map[string]interface{}) {
+	for _, m := range holder.metrics {
+		m.observe(holder, env)
+	}
+}
+
+//metricsRegistry holds the metrics of the loaded scenarios, and exports them as a single prometheus collector
+type metricsRegistry struct {
+	lock    sync.RWMutex
+	metrics map[string]*scenarioMetric
+}
+
+//ScenarioMetrics is the collector of the metrics of all the scenarios, to register on the prometheus listener
+var ScenarioMetrics = &metricsRegistry{metrics: make(map[string]*scenarioMetric)}
+
+//register adds the metric, replacing the one of the same scenario (ie. on reload)
+func (r *metricsRegistry) register(m *scenarioMetric) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if prev, ok := r.metrics[m.cfg.Name]; ok && prev.scenario != m.scenario {
+		return fmt.Errorf("metric %s is already defined by scenario %s", m.cfg.Name, prev.scenario)
+	}
+	r.metrics[m.cfg.Name] = m
+	return nil
+}
+
+func (r *metricsRegistry) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.metrics = make(map[string]*scenarioMetric)
+}
+
+//Describe sends no description : the collector is unchecked, as the metrics change with the loaded scenarios
+func (r *metricsRegistry) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (r *metricsRegistry) Collect(ch chan<- prometheus.Metric) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, m := range r.metrics {
+		m.collector().Collect(ch)
+	}
+}
+
+//loadMetrics compiles and registers the metrics of the scenario
+func loadMetrics(bucketFactory *BucketFactory) error {
+	bucketFactory.metrics = nil
+	for _, cfg := range bucketFactory.Metrics {
+		if cfg == nil {
+			return fmt.Errorf("empty metric")
+		}
+		m, err := newScenarioMetric(bucketFactory, cfg)
+		if err != nil {
+			return err
+		}
+		if err := ScenarioMetrics.register(m); err != nil {
+			return err
+		}
+		bucketFactory.metrics = append(bucketFactory.metrics, m)
+	}
+	return nil
+}
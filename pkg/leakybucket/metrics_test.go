@@ -0,0 +1,94 @@
+package leakybucket
+
+import (
+	"testing"
+
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestScenarioMetrics(t *testing.T) {
+	var buckets *Buckets = NewBuckets()
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_metric", Description: "test_metric", Type: "metric", Filter: "evt.Meta.status == '404'",
+			Metrics: []*MetricCfg{
+				{Name: "test_http_404_total", Labels: map[string]string{"vhost": "evt.Meta.vhost"}, MaxSeries: 2},
+				{Name: "test_http_404_bytes", Type: MetricHistogram, Value: "evt.Meta.bytes", Buckets: []float64{100, 1000}},
+			}},
+	}
+	if err := LoadBucket(&Holders[0]); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+
+	for _, vhost := range []string{"a.example", "a.example", "b.example", "c.example"} {
+		in := types.Event{Meta: map[string]string{"status": "404", "vhost": vhost, "bytes": "512"}}
+		if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+			t.Fatalf("while pouring item : %s", err)
+		}
+	}
+	in := types.Event{Meta: map[string]string{"status": "200", "vhost": "a.example", "bytes": "512"}}
+	if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+		t.Fatalf("while pouring item : %s", err)
+	}
+
+	/*metric scenarios don't have buckets*/
+	count := 0
+	buckets.Bucket_map.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	if count != 0 {
+		t.Fatalf("expected no bucket, got %d", count)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(ScenarioMetrics)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("while gathering : %s", err)
+	}
+	counters := map[string]float64{}
+	histogramCount := uint64(0)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch family.GetName() {
+			case "test_http_404_total":
+				counters[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+			case "test_http_404_bytes":
+				histogramCount += metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	/*the third vhost is beyond the max series*/
+	if len(counters) != 2 || counters["a.example"] != 2 || counters["b.example"] != 1 {
+		t.Fatalf("unexpected counters : %v", counters)
+	}
+	if histogramCount != 4 {
+		t.Fatalf("expected 4 observations, got %d", histogramCount)
+	}
+
+	/*a metric can't be defined by two scenarios*/
+	other := BucketFactory{Name: "test_other", Description: "test_other", Type: "metric", Filter: "true",
+		Metrics: []*MetricCfg{{Name: "test_http_404_total"}}}
+	if err := LoadBucket(&other); err == nil {
+		t.Fatalf("expected duplicate metric to fail")
+	}
+}
+
+func TestMetricsConfig(t *testing.T) {
+	var CfgTests = []cfgTest{
+		//metric without metrics
+		{BucketFactory{Name: "test", Description: "test1", Type: "metric", Filter: "true"}, false, false},
+		//reserved prefix
+		{BucketFactory{Name: "test", Description: "test1", Type: "metric", Filter: "true", Metrics: []*MetricCfg{{Name: "cs_test"}}}, false, false},
+		//histogram without value
+		{BucketFactory{Name: "test", Description: "test1", Type: "metric", Filter: "true", Metrics: []*MetricCfg{{Name: "test_h", Type: MetricHistogram}}}, false, false},
+		//invalid label name
+		{BucketFactory{Name: "test", Description: "test1", Type: "metric", Filter: "true", Metrics: []*MetricCfg{{Name: "test_c", Labels: map[string]string{"bad-label": "evt.Meta.x"}}}}, false, false},
+		//metrics on a leaky bucket
+		{BucketFactory{Name: "test", Description: "test1", Type: "leaky", Capacity: 1, LeakSpeed: "1s", Filter: "true", Metrics: []*MetricCfg{{Name: "test_leaky_total"}}}, true, true},
+	}
+	if err := runTest(CfgTests); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
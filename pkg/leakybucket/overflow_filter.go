@@ -38,8 +38,13 @@ func NewOverflowFilter(g *BucketFactory) (*OverflowFilter, error) {
 
 func (u *OverflowFilter) OnBucketOverflow(Bucket *BucketFactory) func(*Leaky, types.RuntimeAlert, *Queue) (types.RuntimeAlert, *Queue) {
 	return func(l *Leaky, s types.RuntimeAlert, q *Queue) (types.RuntimeAlert, *Queue) {
+		/*the errors of the queue helpers are kept in the queue they're called on*/
+		queue := &Queue{Queue: q.Queue, L: q.L}
 		el, err := expr.Run(u.FilterRuntime, exprhelpers.GetExprEnv(map[string]interface{}{
-			"queue": q, "signal": s, "leaky": l}))
+			"queue": queue, "signal": s, "leaky": l}))
+		if err == nil {
+			err = queue.Err()
+		}
 		if err != nil {
 			l.logger.Errorf("Failed running overflow filter: %s", err)
 			return s, q
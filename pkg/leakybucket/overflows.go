@@ -3,8 +3,10 @@ package leakybucket
 import (
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
 	"github.com/crowdsecurity/crowdsec/pkg/models"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 	"github.com/davecgh/go-spew/spew"
@@ -37,17 +39,7 @@ func SourceFromEvent(evt types.Event, leaky *Leaky) (map[string]models.Source, e
 				/*the original bucket was target IPs, check that we do have range*/
 				if *v.Scope == types.Ip {
 					if v.Range != "" {
-						src := models.Source{}
-						src.AsName = v.AsName
-						src.AsNumber = v.AsNumber
-						src.Cn = v.Cn
-						src.Latitude = v.Latitude
-						src.Longitude = v.Longitude
-						src.Range = v.Range
-						src.Value = new(string)
-						src.Scope = new(string)
-						*src.Value = v.Range
-						*src.Scope = leaky.scopeType.Scope
+						src := rangeFromIpSource(v)
 						srcs[*src.Value] = src
 					} else {
 						log.Warningf("bucket %s requires scope Range, but none was provided. It seems that the %s wasn't enriched to include its range.", leaky.Name, *v.Value)
@@ -63,58 +55,16 @@ func SourceFromEvent(evt types.Event, leaky *Leaky) (map[string]models.Source, e
 	src := models.Source{}
 	switch leaky.scopeType.Scope {
 	case types.Range, types.Ip:
-		if v, ok := evt.Meta["source_ip"]; ok {
-			if net.ParseIP(v) == nil {
-				return srcs, fmt.Errorf("scope is %s but '%s' isn't a valid ip", leaky.scopeType.Scope, v)
-			} else {
-				src.IP = v
-			}
-		} else {
-			return srcs, fmt.Errorf("scope is %s but Meta[source_ip] doesn't exist", leaky.scopeType.Scope)
-		}
-
-		src.Scope = &leaky.scopeType.Scope
-		if v, ok := evt.Enriched["ASNumber"]; ok {
-			src.AsNumber = v
-		}
-		if v, ok := evt.Enriched["IsoCode"]; ok {
-			src.Cn = v
-		}
-		if v, ok := evt.Enriched["ASNOrg"]; ok {
-			src.AsName = v
-		}
-		if v, ok := evt.Enriched["Latitude"]; ok {
-			l, err := strconv.ParseFloat(v, 32)
-			if err != nil {
-				log.Warningf("bad latitude %s : %s", v, err)
-			}
-			src.Latitude = float32(l)
-		}
-		if v, ok := evt.Enriched["Longitude"]; ok {
-			l, err := strconv.ParseFloat(v, 32)
-			if err != nil {
-				log.Warningf("bad longitude %s : %s", v, err)
-			}
-			src.Longitude = float32(l)
-		}
-		if v, ok := evt.Meta["SourceRange"]; ok && v != "" {
-			_, ipNet, err := net.ParseCIDR(v)
-			if err != nil {
-				return srcs, fmt.Errorf("Declared range %s of %s can't be parsed", v, src.IP)
-			} else if ipNet != nil {
-				src.Range = ipNet.String()
-				leaky.logger.Tracef("Valid range from %s : %s", src.IP, src.Range)
-			}
-		}
-		if leaky.scopeType.Scope == types.Ip {
-			src.Value = &src.IP
-		} else if leaky.scopeType.Scope == types.Range {
-			src.Value = &src.Range
+		src, err := sourceFromMeta(evt, leaky.scopeType.Scope, leaky)
+		if err != nil {
+			return srcs, err
 		}
 		srcs[*src.Value] = src
 	default:
 		if leaky.scopeType.RunTimeFilter != nil {
-			retValue, err := expr.Run(leaky.scopeType.RunTimeFilter, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &evt}))
+			env := exprhelpers.AcquireEvtEnv(&evt)
+			retValue, err := expr.Run(leaky.scopeType.RunTimeFilter, env)
+			exprhelpers.ReleaseEvtEnv(env)
 			if err != nil {
 				return srcs, errors.Wrapf(err, "while running scope filter")
 			}
@@ -134,8 +84,80 @@ func SourceFromEvent(evt types.Event, leaky *Leaky) (map[string]models.Source, e
 	return srcs, nil
 }
 
+//rangeFromIpSource returns the source of scope Range of the range of an ip source
+func rangeFromIpSource(v models.Source) models.Source {
+	src := models.Source{}
+	src.AsName = v.AsName
+	src.AsNumber = v.AsNumber
+	src.Cn = v.Cn
+	src.Latitude = v.Latitude
+	src.Longitude = v.Longitude
+	src.Range = v.Range
+	src.Value = new(string)
+	src.Scope = new(string)
+	*src.Value = v.Range
+	*src.Scope = types.Range
+	return src
+}
+
+//sourceFromMeta builds the source of scope Ip or Range of an event from its source_ip and its enrichment
+func sourceFromMeta(evt types.Event, scope string, leaky *Leaky) (models.Source, error) {
+	src := models.Source{}
+	if v, ok := evt.Meta["source_ip"]; ok {
+		if net.ParseIP(v) == nil {
+			return src, fmt.Errorf("scope is %s but '%s' isn't a valid ip", scope, v)
+		} else {
+			src.IP = v
+		}
+	} else {
+		return src, fmt.Errorf("scope is %s but Meta[source_ip] doesn't exist", scope)
+	}
+
+	src.Scope = new(string)
+	*src.Scope = scope
+	if v, ok := evt.Enriched["ASNumber"]; ok {
+		src.AsNumber = v
+	}
+	if v, ok := evt.Enriched["IsoCode"]; ok {
+		src.Cn = v
+	}
+	if v, ok := evt.Enriched["ASNOrg"]; ok {
+		src.AsName = v
+	}
+	if v, ok := evt.Enriched["Latitude"]; ok {
+		l, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			log.Warningf("bad latitude %s : %s", v, err)
+		}
+		src.Latitude = float32(l)
+	}
+	if v, ok := evt.Enriched["Longitude"]; ok {
+		l, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			log.Warningf("bad longitude %s : %s", v, err)
+		}
+		src.Longitude = float32(l)
+	}
+	if v, ok := evt.Meta["SourceRange"]; ok && v != "" {
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			return src, fmt.Errorf("Declared range %s of %s can't be parsed", v, src.IP)
+		} else if ipNet != nil {
+			src.Range = ipNet.String()
+			leaky.logger.Tracef("Valid range from %s : %s", src.IP, src.Range)
+		}
+	}
+	if scope == types.Ip {
+		src.Value = &src.IP
+	} else if scope == types.Range {
+		src.Value = &src.Range
+	}
+	return src, nil
+}
+
 //EventsFromQueue iterates the queue to collect & prepare meta-datas from alert
-func EventsFromQueue(queue *Queue) []*models.Event {
+//If a redaction policy is provided, it is applied to every meta before it leaves the agent
+func EventsFromQueue(queue *Queue, redaction *csconfig.RedactionCfg) []*models.Event {
 
 	events := []*models.Event{}
 
@@ -145,6 +167,13 @@ func EventsFromQueue(queue *Queue) []*models.Event {
 		}
 		meta := models.Meta{}
 		for k, v := range evt.Meta {
+			if redaction != nil {
+				var keep bool
+				if v, keep = redaction.RedactMeta(k, v); !keep {
+					log.Tracef("meta '%s' dropped by redaction policy", k)
+					continue
+				}
+			}
 			subMeta := models.MetaItems0{Key: k, Value: v}
 			meta = append(meta, &subMeta)
 		}
@@ -199,6 +228,26 @@ func alertFormatSource(leaky *Leaky, queue *Queue) (map[string]models.Source, st
 	return sources, source_type, nil
 }
 
+//alertLabels returns the sorted 'key:value' labels of the alerts of the scenario, with its severity and confidence
+func alertLabels(bucketFactory *BucketFactory) []string {
+	labels := make(map[string]string, len(bucketFactory.Labels)+2)
+	for key, value := range bucketFactory.Labels {
+		labels[key] = value
+	}
+	if bucketFactory.Severity != "" {
+		labels[types.SeverityLabel] = bucketFactory.Severity
+	}
+	if bucketFactory.Confidence != 0 {
+		labels[types.ConfidenceLabel] = strconv.Itoa(bucketFactory.Confidence)
+	}
+	ret := make([]string, 0, len(labels))
+	for key, value := range labels {
+		ret = append(ret, types.JoinLabel(key, value))
+	}
+	sort.Strings(ret)
+	return ret
+}
+
 //NewAlert will generate a RuntimeAlert and its APIAlert(s) from a bucket that overflowed
 func NewAlert(leaky *Leaky, queue *Queue) (types.RuntimeAlert, error) {
 
@@ -241,9 +290,20 @@ func NewAlert(leaky *Leaky, queue *Queue) (types.RuntimeAlert, error) {
 	runtimeAlert.Mapkey = leaky.Mapkey
 
 	//Get the sources from Leaky/Queue
-	sources, source_scope, err := alertFormatSource(leaky, queue)
-	if err != nil {
-		return runtimeAlert, errors.Wrap(err, "unable to collect sources from bucket")
+	var sources map[string]models.Source
+	var source_scope string
+	incident := false
+	if leaky.BucketConfig.Sources != nil {
+		/*the bucket recorded the sources of the events it got, the alert is an incident if there is more than one*/
+		sources = incidentSources(leaky, queue)
+		source_scope = leaky.BucketConfig.Sources.Scope
+		incident = len(sources) > 1
+	}
+	if len(sources) == 0 {
+		sources, source_scope, err = alertFormatSource(leaky, queue)
+		if err != nil {
+			return runtimeAlert, errors.Wrap(err, "unable to collect sources from bucket")
+		}
 	}
 	runtimeAlert.Sources = sources
 	//Include source info in format string
@@ -259,8 +319,20 @@ func NewAlert(leaky *Leaky, queue *Queue) (types.RuntimeAlert, error) {
 		sourceStr = "UNKNOWN"
 	}
 	*apiAlert.Message = fmt.Sprintf("%s %s performed '%s' (%d events over %s) at %s", source_scope, sourceStr, leaky.Name, leaky.Total_count, leaky.Ovflw_ts.Sub(leaky.First_ts), leaky.Last_ts)
+	if incident {
+		if target, ok := incidentTarget(leaky); ok {
+			*apiAlert.Message += fmt.Sprintf(" against %s", target)
+		}
+		apiAlert.Meta = incidentMeta(leaky, sources)
+	}
+	apiAlert.Labels = alertLabels(leaky.BucketConfig)
 	//Get the events from Leaky/Queue
-	apiAlert.Events = EventsFromQueue(queue)
+	if ctx := leaky.BucketConfig.alertContext; ctx != nil {
+		apiAlert.Meta = append(apiAlert.Meta, contextMeta(leaky, ctx, queue)...)
+		apiAlert.Events = EventsFromQueue(keptEvents(queue, ctx.cfg.Events), leaky.BucketConfig.redaction)
+	} else {
+		apiAlert.Events = EventsFromQueue(queue, leaky.BucketConfig.redaction)
+	}
 
 	//Loop over the Sources and generate appropriate number of ApiAlerts
 	for _, srcValue := range sources {
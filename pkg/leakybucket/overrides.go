@@ -0,0 +1,92 @@
+package leakybucket
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+/*
+The overrides file holds local changes to the settings of the scenarios, by scenario name. They are merged on top of the scenarios
+when they are loaded : hub scenarios can be tuned without being modified, so they aren't tainted and keep being upgraded.
+*/
+
+//ScenarioOverride is the part of a scenario that can be overridden locally
+type ScenarioOverride struct {
+	Capacity   *int              `yaml:"capacity,omitempty"`
+	LeakSpeed  *string           `yaml:"leakspeed,omitempty"`
+	Duration   *string           `yaml:"duration,omitempty"`
+	Blackhole  *string           `yaml:"blackhole,omitempty"`
+	CacheSize  *int              `yaml:"cache_size,omitempty"`
+	MaxBuckets *int              `yaml:"max_buckets,omitempty"`
+	Exclude    string            `yaml:"exclude,omitempty"` //events matching this expression are ignored by the scenario, on top of its filter
+	Labels     map[string]string `yaml:"labels,omitempty"`  //merged with the labels of the scenario
+}
+
+//LoadScenariosOverrides reads the overrides of the scenarios, by scenario name. A missing file means there are no overrides
+func LoadScenariosOverrides(path string) (map[string]ScenarioOverride, error) {
+	overrides := make(map[string]ScenarioOverride)
+	if path == "" {
+		return overrides, nil
+	}
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return overrides, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("while reading %s : %s", path, err)
+	}
+	if err := yaml.UnmarshalStrict(body, &overrides); err != nil {
+		return nil, fmt.Errorf("while unmarshaling %s : %s", path, err)
+	}
+	return overrides, nil
+}
+
+//applyOverride merges override on top of the scenario, and returns the settings it changed
+func applyOverride(bucketFactory *BucketFactory, override ScenarioOverride) []string {
+	changed := []string{}
+	if override.Capacity != nil {
+		bucketFactory.Capacity = *override.Capacity
+		changed = append(changed, "capacity")
+	}
+	if override.LeakSpeed != nil {
+		bucketFactory.LeakSpeed = *override.LeakSpeed
+		changed = append(changed, "leakspeed")
+	}
+	if override.Duration != nil {
+		bucketFactory.Duration = *override.Duration
+		changed = append(changed, "duration")
+	}
+	if override.Blackhole != nil {
+		bucketFactory.Blackhole = *override.Blackhole
+		changed = append(changed, "blackhole")
+	}
+	if override.CacheSize != nil {
+		bucketFactory.CacheSize = *override.CacheSize
+		changed = append(changed, "cache_size")
+	}
+	if override.MaxBuckets != nil {
+		bucketFactory.MaxBuckets = *override.MaxBuckets
+		changed = append(changed, "max_buckets")
+	}
+	if override.Exclude != "" {
+		if bucketFactory.Filter == "" {
+			bucketFactory.Filter = fmt.Sprintf("!(%s)", override.Exclude)
+		} else {
+			bucketFactory.Filter = fmt.Sprintf("(%s) && !(%s)", bucketFactory.Filter, override.Exclude)
+		}
+		changed = append(changed, "filter")
+	}
+	if len(override.Labels) > 0 {
+		if bucketFactory.Labels == nil {
+			bucketFactory.Labels = make(map[string]string)
+		}
+		for k, v := range override.Labels {
+			bucketFactory.Labels[k] = v
+		}
+		changed = append(changed, "labels")
+	}
+	return changed
+}
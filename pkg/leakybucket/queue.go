@@ -1,6 +1,13 @@
 package leakybucket
 
 import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 	log "github.com/sirupsen/logrus"
 )
@@ -8,7 +15,8 @@ import (
 // Queue holds a limited size queue
 type Queue struct {
 	Queue []types.Event
-	L     int //capacity
+	L     int   //capacity
+	err   error //first error of the helpers, cf. Err
 }
 
 // NewQueue create a new queue with a size of l
@@ -40,3 +48,115 @@ func (q *Queue) Add(m types.Event) {
 func (q *Queue) GetQueue() []types.Event {
 	return q.Queue
 }
+
+/*
+The following helpers are exposed to the condition of 'conditional' buckets as `queue`.
+Sum, Ratio and Distinct take an expression evaluated against each event (as `evt`) of the queue,
+it is compiled once (see compileQueueExpr) and must be a string literal in the condition.
+expr's methods can't return errors : on error, they return 0 and keep the error, that the caller of the condition gets with Err.
+*/
+
+var queueExprs sync.Map
+
+func compileQueueExpr(expression string) (*vm.Program, error) {
+	if program, ok := queueExprs.Load(expression); ok {
+		return program.(*vm.Program), nil
+	}
+	program, err := expr.Compile(expression, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
+	if err != nil {
+		return nil, err
+	}
+	queueExprs.Store(expression, program)
+	return program, nil
+}
+
+//Err returns the first error of the helpers called on the queue
+func (q *Queue) Err() error {
+	return q.err
+}
+
+func (q *Queue) forEach(expression string, fn func(interface{})) error {
+	program, err := compileQueueExpr(expression)
+	if err != nil {
+		return q.fail(fmt.Errorf("invalid expression '%s' : %s", expression, err))
+	}
+	env := exprhelpers.AcquireEvtEnv(nil)
+	defer exprhelpers.ReleaseEvtEnv(env)
+	for idx := range q.Queue {
+		env["evt"] = &q.Queue[idx]
+		output, err := expr.Run(program, env)
+		if err != nil {
+			return q.fail(fmt.Errorf("while running '%s' : %s", expression, err))
+		}
+		fn(output)
+	}
+	return nil
+}
+
+func (q *Queue) fail(err error) error {
+	if q.err == nil {
+		q.err = err
+	}
+	return err
+}
+
+//Count returns the number of events in the queue
+func (q *Queue) Count() int {
+	return len(q.Queue)
+}
+
+//Sum returns the sum of expression for all events, non-numeric results are ignored
+func (q *Queue) Sum(expression string) float64 {
+	var sum float64
+	err := q.forEach(expression, func(output interface{}) {
+		switch out := output.(type) {
+		case int:
+			sum += float64(out)
+		case int64:
+			sum += float64(out)
+		case float64:
+			sum += out
+		case string:
+			if value, err := strconv.ParseFloat(out, 64); err == nil {
+				sum += value
+			}
+		}
+	})
+	if err != nil {
+		return 0
+	}
+	return sum
+}
+
+//Ratio returns the share (between 0 and 1) of events for which expression is true
+func (q *Queue) Ratio(expression string) float64 {
+	if len(q.Queue) == 0 {
+		return 0
+	}
+	matches := 0
+	err := q.forEach(expression, func(output interface{}) {
+		if out, ok := output.(bool); ok && out {
+			matches++
+		}
+	})
+	if err != nil {
+		return 0
+	}
+	return float64(matches) / float64(len(q.Queue))
+}
+
+//Distinct returns the number of distinct values of expression
+func (q *Queue) Distinct(expression string) int {
+	values := make(map[string]struct{})
+	err := q.forEach(expression, func(output interface{}) {
+		if out, ok := output.(string); ok {
+			values[out] = struct{}{}
+		} else {
+			values[fmt.Sprintf("%v", output)] = struct{}{}
+		}
+	})
+	if err != nil {
+		return 0
+	}
+	return len(values)
+}
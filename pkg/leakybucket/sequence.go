@@ -0,0 +1,120 @@
+package leakybucket
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+)
+
+//SequenceStep is one step of a sequence bucket : Count events matching Filter must be seen before moving to the next step
+type SequenceStep struct {
+	Filter string `yaml:"filter"`
+	Count  int    `yaml:"count,omitempty"` //defaults to 1
+}
+
+/*
+Sequence overflows when the events of a partition match all the steps in order, within the bucket duration.
+The current step of each bucket is held by the bucket itself (Leaky.SequenceStep/SequenceCount) so that it's dumped with the bucket state.
+Events that don't match the current step are discarded, the events of the steps are kept in the queue and end up in the alert.
+*/
+type Sequence struct {
+	steps   []*vm.Program
+	counts  []int
+	filters []string
+	window  time.Duration
+	guard   *exprhelpers.ExprGuard
+	DumbProcessor
+}
+
+func NewSequence(bucketFactory *BucketFactory) (*Sequence, error) {
+	s := Sequence{
+		window: bucketFactory.duration,
+		guard:  bucketFactory.guard,
+	}
+	for idx, step := range bucketFactory.Steps {
+		if step.Filter == "" {
+			return nil, fmt.Errorf("step %d has no filter", idx)
+		}
+		program, err := expr.Compile(step.Filter, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter '%s' in step %d : %v", step.Filter, idx, err)
+		}
+		if err := exprhelpers.CheckReturnKind(step.Filter, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}}), reflect.Bool); err != nil {
+			return nil, fmt.Errorf("invalid filter in step %d : %v", idx, err)
+		}
+		if step.Count < 0 {
+			return nil, fmt.Errorf("invalid count %d in step %d", step.Count, idx)
+		}
+		count := step.Count
+		if count == 0 {
+			count = 1
+		}
+		s.steps = append(s.steps, program)
+		s.counts = append(s.counts, count)
+		s.filters = append(s.filters, step.Filter)
+	}
+	return &s, nil
+}
+
+func (s *Sequence) reset(l *Leaky) {
+	l.SequenceStep = 0
+	l.SequenceCount = 0
+	l.Total_count = 0
+	l.First_ts = time.Time{}
+	l.Queue.Queue = make([]types.Event, 0)
+}
+
+func (s *Sequence) OnBucketPour(bucketFactory *BucketFactory) func(types.Event, *Leaky) *types.Event {
+	return func(msg types.Event, l *Leaky) *types.Event {
+		now := eventTime(l, msg)
+		if !l.First_ts.IsZero() && now.Sub(l.First_ts) > s.window {
+			l.logger.Debugf("sequence window expired at step %d/%d, start over", l.SequenceStep+1, len(s.steps))
+			s.reset(l)
+		}
+		/*the bucket might have been restored from a state dumped with different steps*/
+		if l.SequenceStep >= len(s.steps) {
+			s.reset(l)
+		}
+
+		env := exprhelpers.AcquireEvtEnv(&msg)
+		match, err := s.guard.RunBool(s.steps[l.SequenceStep], env)
+		exprhelpers.ReleaseEvtEnv(env)
+		if err != nil {
+			l.logger.Errorf("failed step %d filter : %v", l.SequenceStep, err)
+			return nil
+		}
+		if !match {
+			l.logger.Tracef("event doesn't match step %d (%s), discard", l.SequenceStep, s.filters[l.SequenceStep])
+			return nil
+		}
+
+		l.SequenceCount++
+		if l.SequenceCount < s.counts[l.SequenceStep] {
+			l.logger.Debugf("step %d/%d : %d/%d events", l.SequenceStep+1, len(s.steps), l.SequenceCount, s.counts[l.SequenceStep])
+			return &msg
+		}
+		if l.SequenceStep+1 < len(s.steps) {
+			l.logger.Debugf("step %d/%d completed", l.SequenceStep+1, len(s.steps))
+			l.SequenceStep++
+			l.SequenceCount = 0
+			return &msg
+		}
+
+		/*the whole chain is completed*/
+		l.logger.Infof("Sequence completed, bucket overflow")
+		l.Total_count += 1
+		if l.First_ts.IsZero() {
+			l.First_ts = now
+		}
+		l.Last_ts = now
+		l.Ovflw_ts = now
+		l.Queue.Add(msg)
+		l.Out <- l.Queue
+		return nil
+	}
+}
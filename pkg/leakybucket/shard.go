@@ -0,0 +1,437 @@
+package leakybucket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+The live buckets are spread over a fixed set of shards, according to their key. A shard is a single routine owning its buckets :
+it creates them, pours the events in them, destroys them, and makes them expire with a timer wheel. There is no routine per bucket,
+and as a given bucket is only ever touched by its shard, the pouring routines never have to wait for a bucket to be available.
+The shards keep Buckets.Bucket_map up to date to find the buckets by key, but the buckets found there must not be read outside
+of their shard : the readers (dumps, snapshots, cscli buckets ...) go through the shards with Buckets.visit.
+*/
+
+const (
+	//shardQueueSize is the number of requests a shard can hold before the pouring routines wait for it
+	shardQueueSize = 256
+	//shardMaxPending is the number of overflows a shard holds while the output routines are busy, the next ones are dropped
+	shardMaxPending = 8192
+)
+
+var BucketsOutputDropped = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cs_bucket_output_dropped_total",
+		Help: "Total overflows and underflows of buckets dropped because the output routines didn't keep up.",
+	},
+	[]string{"name"},
+)
+
+var (
+	//wheelTick is the resolution of the buckets expiration
+	wheelTick = 100 * time.Millisecond
+	//wheelSlots is the size of the timer wheel, further deadlines are rescheduled when the wheel comes around
+	wheelSlots = 1024
+)
+
+type shardOp int
+
+const (
+	opPour shardOp = iota
+	opCancel
+	opKill
+	opRestore
+	opVisit
+	opForget
+	opOutput
+	opFlush
+	opStop
+)
+
+type shardRequest struct {
+	op       shardOp
+	key      string
+	holder   *BucketFactory   //opPour, opCancel and opOutput
+	groupby  string           //opPour
+	reserved bool             //opPour, the pouring routine accounted for the bucket (cf. reserveLive)
+	evt      types.Event      //opPour and opOutput
+	bucket   *Leaky           //opKill, opRestore and opForget
+	visit    func(*Leaky)     //opVisit
+	out      chan types.Event //opOutput
+	done     *sync.WaitGroup
+}
+
+//shardOutput is an event waiting to be sent by a shard. The bucket that sent it, if any, stays in LeakyRoutineCount until it is sent
+type shardOutput struct {
+	out    chan types.Event
+	evt    types.Event
+	bucket *Leaky
+}
+
+type bucketShard struct {
+	in       chan shardRequest
+	buckets  map[string]*Leaky
+	registry *sync.Map
+	creating *sync.Map
+	wheel    *timerWheel
+	pending  []shardOutput
+	dropping bool
+}
+
+func newBucketShard(registry *sync.Map, creating *sync.Map) *bucketShard {
+	return &bucketShard{
+		in:       make(chan shardRequest, shardQueueSize),
+		buckets:  make(map[string]*Leaky),
+		registry: registry,
+		creating: creating,
+		wheel:    newTimerWheel(wheelTick, wheelSlots),
+	}
+}
+
+func (s *bucketShard) run() {
+	var (
+		ticker *time.Ticker
+		ticks  <-chan time.Time
+	)
+
+	defer types.CatchPanic("crowdsec/bucketShard")
+
+	for {
+		/*the ticker only runs while some buckets are waiting for their deadline*/
+		if s.wheel.count > 0 && ticker == nil {
+			ticker = time.NewTicker(s.wheel.tick)
+			ticks = ticker.C
+		} else if s.wheel.count == 0 && ticker != nil {
+			ticker.Stop()
+			ticker, ticks = nil, nil
+		}
+		/*the overflows are sent in order, without blocking the shard if the output routines are slow*/
+		var (
+			out  chan types.Event
+			next types.Event
+		)
+		if len(s.pending) > 0 {
+			out, next = s.pending[0].out, s.pending[0].evt
+		}
+
+		select {
+		case req := <-s.in:
+			if req.op == opStop {
+				s.stop()
+				if ticker != nil {
+					ticker.Stop()
+				}
+				req.done.Done()
+				return
+			}
+			s.handle(req)
+			if req.done != nil {
+				req.done.Done()
+			}
+		case <-ticks:
+			for _, bucket := range s.wheel.advance(time.Now()) {
+				s.end(bucket, expireLeaky(bucket))
+			}
+		case out <- next:
+			s.sent(s.pending[0])
+			s.pending[0] = shardOutput{}
+			s.pending = s.pending[1:]
+		}
+	}
+}
+
+func (s *bucketShard) handle(req shardRequest) {
+	switch req.op {
+	case opPour:
+		s.pour(req)
+	case opCancel:
+		bucket, ok := s.buckets[req.key]
+		if !ok {
+			req.holder.logger.Debugf("No bucket %s to cancel", req.key)
+			return
+		}
+		bucket.logger.Debugf("Bucket canceled, destroy")
+		BucketsCanceled.With(prometheus.Labels{"name": bucket.Name}).Inc()
+		s.end(bucket, types.Event{Type: types.OVFLW, Overflow: types.RuntimeAlert{Mapkey: bucket.Mapkey}})
+	case opKill:
+		if req.bucket.isDead() {
+			return
+		}
+		req.bucket.logger.Debugf("Bucket externally killed, destroy")
+		s.end(req.bucket, types.Event{Type: types.OVFLW, Overflow: types.RuntimeAlert{Mapkey: req.bucket.Mapkey}})
+	case opRestore:
+		s.restore(req.bucket)
+	case opVisit:
+		if req.key != "" {
+			if bucket, ok := s.buckets[req.key]; ok {
+				req.visit(bucket)
+			}
+			return
+		}
+		for _, bucket := range s.buckets {
+			req.visit(bucket)
+		}
+	case opForget:
+		s.forget(req.bucket)
+	case opOutput:
+		s.queue(shardOutput{out: req.out, evt: req.evt}, req.holder.Name, req.holder.logger)
+	case opFlush:
+		/*the requests sent before were processed*/
+	}
+}
+
+func (s *bucketShard) pour(req shardRequest) {
+	holder := req.holder
+	if req.reserved {
+		/*the bucket is accounted for by startLeaky from now on, if it's created*/
+		defer func() {
+			releaseLive(holder)
+			s.creating.Delete(req.key)
+		}()
+	}
+	bucket, ok := s.buckets[req.key]
+	replaced := false
+	/*let's see if this time-bucket should have expired */
+	if ok && bucket.Mode == TIMEMACHINE && !bucket.First_ts.IsZero() {
+		var d time.Time
+		if err := d.UnmarshalText([]byte(req.evt.MarshaledTime)); err != nil {
+			holder.logger.Warningf("Failed unmarshaling event time (%s) : %v", req.evt.MarshaledTime, err)
+		}
+		if d.After(bucket.Last_ts.Add(bucket.Duration)) {
+			/*the expired bucket is replaced, but it still leaks until its deadline*/
+			bucket.logger.Tracef("bucket is expired (curr event: %s, bucket deadline: %s), replace it", d, bucket.Last_ts.Add(bucket.Duration))
+			delete(s.buckets, req.key)
+			ok = false
+			replaced = true
+		}
+	}
+	if !ok {
+		if !req.reserved && !replaced && overCap(holder) {
+			holder.logger.Debugf("max number of live buckets reached, no bucket for %s", req.key)
+			BucketsEvicted.With(prometheus.Labels{"name": holder.Name}).Inc()
+			return
+		}
+		if bucket = s.create(req); bucket == nil {
+			return
+		}
+	}
+	if ofw := pourLeaky(bucket, req.evt); ofw != nil {
+		s.end(bucket, overflowLeaky(bucket, ofw))
+		return
+	}
+	s.schedule(bucket)
+}
+
+func (s *bucketShard) create(req shardRequest) *Leaky {
+	var fresh_bucket *Leaky
+
+	holder := req.holder
+	holder.logger.Debugf("Creating bucket %s", req.key)
+	switch req.evt.ExpectMode {
+	case TIMEMACHINE:
+		fresh_bucket = NewTimeMachine(*holder)
+		holder.logger.Debugf("Creating TimeMachine bucket")
+	case LIVE:
+		fresh_bucket = NewLeaky(*holder)
+		holder.logger.Debugf("Creating Live bucket")
+	case HYBRID:
+		fresh_bucket = NewHybrid(*holder)
+		holder.logger.Debugf("Creating Hybrid bucket")
+	default:
+		holder.logger.Fatalf("input event has no expected mode, malformed : %+v", req.evt)
+	}
+	fresh_bucket.Mapkey = req.key
+	fresh_bucket.Partition = req.groupby
+	if err := startLeaky(fresh_bucket); err != nil {
+		return nil
+	}
+	s.buckets[req.key] = fresh_bucket
+	s.registry.Store(req.key, fresh_bucket)
+	holder.logger.Debugf("Created new bucket %s", req.key)
+	return fresh_bucket
+}
+
+func (s *bucketShard) restore(bucket *Leaky) {
+	if err := startLeaky(bucket); err != nil {
+		return
+	}
+	s.buckets[bucket.Mapkey] = bucket
+	s.registry.Store(bucket.Mapkey, bucket)
+	/*a live bucket restored from a snapshot must expire even if it doesn't get any event*/
+	if bucket.Mode == LIVE && !bucket.Last_ts.IsZero() && bucket.Duration != 0 {
+		bucket.deadline = bucket.Last_ts.Add(bucket.Duration)
+		s.schedule(bucket)
+	}
+	if bucket.Mode == HYBRID && !bucket.Last_ts.IsZero() && bucket.Duration != 0 {
+		bucket.deadline = hybridDeadline(bucket)
+		s.schedule(bucket)
+	}
+}
+
+func (s *bucketShard) schedule(bucket *Leaky) {
+	/*a scheduled bucket that got events since is rescheduled when its slot comes up*/
+	if bucket.scheduled || bucket.deadline.IsZero() {
+		return
+	}
+	s.wheel.add(bucket, time.Now())
+}
+
+/*
+end marks the bucket as dead and queues its last event. The bucket stays in Bucket_map until this event is handed to
+the output routines (or the bucket is replaced), as it did when each bucket had its own routine.
+*/
+func (s *bucketShard) end(bucket *Leaky, evt types.Event) {
+	markDead(bucket)
+	if s.buckets[bucket.Mapkey] == bucket {
+		delete(s.buckets, bucket.Mapkey)
+	}
+	if bucket.AllOut == nil {
+		/*nobody listens*/
+		endLeaky(bucket)
+		return
+	}
+	s.queue(shardOutput{out: bucket.AllOut, evt: evt, bucket: bucket}, bucket.Name, bucket.logger)
+}
+
+//queue holds the event until the output routines take it. Past shardMaxPending events, it is dropped rather than blocking the shard
+func (s *bucketShard) queue(output shardOutput, name string, logger *log.Entry) {
+	if len(s.pending) >= shardMaxPending {
+		if !s.dropping {
+			logger.Warningf("%d overflows are waiting for the output routines, drop the next ones", len(s.pending))
+			s.dropping = true
+		}
+		BucketsOutputDropped.With(prometheus.Labels{"name": name}).Inc()
+		s.sent(output)
+		return
+	}
+	s.dropping = false
+	s.pending = append(s.pending, output)
+}
+
+//sent is called once the last event of a bucket left the shard. A bucket without alert (ie. underflow) is forgotten right away
+func (s *bucketShard) sent(output shardOutput) {
+	if output.bucket == nil {
+		return
+	}
+	endLeaky(output.bucket)
+	if output.evt.Overflow.Alert == nil {
+		s.forget(output.bucket)
+	}
+}
+
+//forget removes the ended bucket from Bucket_map, unless a new bucket of the same key replaced it
+func (s *bucketShard) forget(bucket *Leaky) {
+	if s.buckets[bucket.Mapkey] == bucket {
+		/*still live*/
+		return
+	}
+	if stored, ok := s.registry.Load(bucket.Mapkey); ok && stored.(*Leaky) == bucket {
+		s.registry.Delete(bucket.Mapkey)
+	}
+}
+
+//stop ends all the buckets of the shard without sending anything, nobody listens anymore
+func (s *bucketShard) stop() {
+	for _, output := range s.pending {
+		if output.bucket != nil {
+			endLeaky(output.bucket)
+		}
+	}
+	s.pending = nil
+	for _, bucket := range s.buckets {
+		markDead(bucket)
+		endLeaky(bucket)
+	}
+	s.buckets = make(map[string]*Leaky)
+	/*the buckets replaced while in time-machine mode are only in the wheel*/
+	for _, bucket := range s.wheel.drain() {
+		if !bucket.isDead() {
+			markDead(bucket)
+			endLeaky(bucket)
+		}
+	}
+}
+
+/*
+timerWheel schedules the expiration of the buckets of a shard : each slot holds the buckets expiring during a tick.
+The buckets are never moved when they get events : when their slot comes up, the ones whose deadline moved are scheduled again,
+as are the ones whose deadline is beyond the wheel.
+*/
+type timerWheel struct {
+	tick  time.Duration
+	slots [][]*Leaky
+	pos   int
+	now   time.Time //start of the tick of the current slot
+	count int
+}
+
+func newTimerWheel(tick time.Duration, size int) *timerWheel {
+	return &timerWheel{
+		tick:  tick,
+		slots: make([][]*Leaky, size),
+	}
+}
+
+func (w *timerWheel) add(bucket *Leaky, now time.Time) {
+	if w.count == 0 {
+		w.now = now
+	}
+	/*the slot after the one of the deadline, so that buckets never expire early*/
+	ticks := int(bucket.deadline.Sub(w.now)/w.tick) + 1
+	if ticks < 1 {
+		ticks = 1
+	}
+	if ticks >= len(w.slots) {
+		ticks = len(w.slots) - 1
+	}
+	idx := (w.pos + ticks) % len(w.slots)
+	w.slots[idx] = append(w.slots[idx], bucket)
+	bucket.scheduled = true
+	w.count++
+}
+
+//advance moves the wheel up to now, and returns the buckets that reached their deadline
+func (w *timerWheel) advance(now time.Time) []*Leaky {
+	var expired []*Leaky
+
+	for w.count > 0 && !now.Before(w.now.Add(w.tick)) {
+		w.now = w.now.Add(w.tick)
+		w.pos = (w.pos + 1) % len(w.slots)
+		slot := w.slots[w.pos]
+		w.slots[w.pos] = slot[:0]
+		w.count -= len(slot)
+		for i, bucket := range slot {
+			slot[i] = nil
+			bucket.scheduled = false
+			if bucket.isDead() {
+				continue
+			}
+			if bucket.deadline.After(now) {
+				w.add(bucket, now)
+				continue
+			}
+			expired = append(expired, bucket)
+		}
+	}
+	return expired
+}
+
+//drain empties the wheel and returns the buckets it held
+func (w *timerWheel) drain() []*Leaky {
+	var ret []*Leaky
+
+	for i, slot := range w.slots {
+		for _, bucket := range slot {
+			bucket.scheduled = false
+			ret = append(ret, bucket)
+		}
+		w.slots[i] = nil
+	}
+	w.count = 0
+	return ret
+}
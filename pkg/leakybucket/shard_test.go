@@ -0,0 +1,327 @@
+package leakybucket
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestTimerWheel(t *testing.T) {
+	start := time.Now()
+	wheel := newTimerWheel(10*time.Millisecond, 8)
+
+	soon := &Leaky{Name: "soon", deadline: start.Add(15 * time.Millisecond)}
+	later := &Leaky{Name: "later", deadline: start.Add(45 * time.Millisecond)}
+	/*beyond the 80ms of the wheel*/
+	far := &Leaky{Name: "far", deadline: start.Add(200 * time.Millisecond)}
+	dead := &Leaky{Name: "dead", deadline: start.Add(15 * time.Millisecond), dead: 1}
+	for _, bucket := range []*Leaky{soon, later, far, dead} {
+		wheel.add(bucket, start)
+	}
+
+	expect := func(at time.Duration, names ...string) {
+		expired := wheel.advance(start.Add(at))
+		if len(expired) != len(names) {
+			t.Fatalf("at %s : expected %v to expire, got %d buckets", at, names, len(expired))
+		}
+		for i, bucket := range expired {
+			if bucket.Name != names[i] {
+				t.Fatalf("at %s : expected %v to expire, got %s", at, names, bucket.Name)
+			}
+			if bucket.deadline.After(start.Add(at)) {
+				t.Fatalf("%s expired before its deadline", bucket.Name)
+			}
+		}
+	}
+	expect(10 * time.Millisecond)
+	expect(20*time.Millisecond, "soon")
+	/*later got an event : it isn't moved, but rescheduled when its slot comes up*/
+	later.deadline = start.Add(65 * time.Millisecond)
+	expect(50 * time.Millisecond)
+	expect(70*time.Millisecond, "later")
+	expect(150 * time.Millisecond)
+	if wheel.count != 1 {
+		t.Fatalf("expected only far to be scheduled, got %d buckets", wheel.count)
+	}
+	expect(210*time.Millisecond, "far")
+	if wheel.count != 0 {
+		t.Fatalf("expected an empty wheel, got %d buckets", wheel.count)
+	}
+}
+
+func TestShardExpiry(t *testing.T) {
+	var buckets *Buckets = NewBuckets()
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_expiry_leaky", Description: "test_expiry_leaky", Type: "leaky", Capacity: 5, LeakSpeed: "50ms",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+		BucketFactory{Name: "test_expiry_counter", Description: "test_expiry_counter", Type: "counter", Capacity: -1, Duration: "200ms",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+	}
+	for idx := range Holders {
+		if err := LoadBucket(&Holders[idx]); err != nil {
+			t.Fatalf("while loading (%d/%d): %s", idx, len(Holders), err)
+		}
+		Holders[idx].ret = make(chan types.Event, 10)
+	}
+	liveCount := func() int64 {
+		return atomic.LoadInt64(Holders[0].liveCount) + atomic.LoadInt64(Holders[1].liveCount)
+	}
+	now, _ := time.Now().MarshalText()
+	for i := 0; i < 2; i++ {
+		in := types.Event{Meta: map[string]string{"source_ip": "1.2.3.4"}, MarshaledTime: string(now)}
+		if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+			t.Fatalf("while pouring item : %s", err)
+		}
+	}
+	buckets.flush()
+	if count := liveCount(); count != 2 {
+		t.Fatalf("expected 2 live buckets, got %d", count)
+	}
+
+	/*the leaky bucket underflows after 300ms*/
+	select {
+	case ret := <-Holders[0].ret:
+		if ret.Overflow.Alert != nil || ret.Overflow.Mapkey != GetKey(Holders[0], "1.2.3.4") {
+			t.Fatalf("expected an underflow, got %+v", ret.Overflow)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("leaky bucket didn't underflow")
+	}
+	/*the counter overflows after 200ms*/
+	select {
+	case ret := <-Holders[1].ret:
+		if ret.Overflow.Alert == nil || *ret.Overflow.Alert.EventsCount != 2 {
+			t.Fatalf("expected an overflow of 2 events, got %+v", ret.Overflow)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("counter didn't overflow")
+	}
+	/*the buckets end once their last event is sent*/
+	time.Sleep(50 * time.Millisecond)
+	if count := liveCount(); count != 0 {
+		t.Fatalf("expected no live buckets, got %d", count)
+	}
+	/*the underflowed bucket is forgotten once sent, the overflowed one stays until it is replaced*/
+	if err := expectBucketKeys(buckets, Holders[1], "1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+	/*the buckets are stopped, nothing is poured anymore*/
+	in := types.Event{Meta: map[string]string{"source_ip": "1.2.3.5"}, MarshaledTime: string(now)}
+	if ok, _ := PourItemToHolders(in, Holders, buckets); ok {
+		t.Fatalf("event poured in stopped buckets")
+	}
+}
+
+func TestShardForget(t *testing.T) {
+	var buckets *Buckets = NewBuckets()
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_forget", Description: "test_forget", Type: "leaky", Capacity: 5, LeakSpeed: "10m",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+	}
+	if err := LoadBucket(&Holders[0]); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	Holders[0].ret = make(chan types.Event, 10)
+	now, _ := time.Now().MarshalText()
+	in := types.Event{Meta: map[string]string{"source_ip": "1.2.3.4"}, MarshaledTime: string(now)}
+	key := GetKey(Holders[0], "1.2.3.4")
+
+	if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+		t.Fatalf("while pouring item : %s", err)
+	}
+	buckets.flush()
+	biface, _ := buckets.Bucket_map.Load(key)
+	old := biface.(*Leaky)
+	buckets.kill(old)
+	/*the killed bucket is replaced before it's forgotten : the new one must stay*/
+	if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+		t.Fatalf("while pouring item : %s", err)
+	}
+	buckets.forget(old)
+	biface, ok := buckets.Bucket_map.Load(key)
+	if !ok || biface.(*Leaky) == old {
+		t.Fatalf("the new bucket was forgotten")
+	}
+	buckets.forget(biface.(*Leaky))
+	if _, ok := buckets.Bucket_map.Load(key); !ok {
+		t.Fatalf("a live bucket was forgotten")
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+}
+
+//TestShardReaders reads the buckets while they are poured, to be run with -race
+func TestShardReaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crowdsec-readers-")
+	if err != nil {
+		t.Fatalf("while creating temp dir : %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var buckets *Buckets = NewBucketsWithShards(4)
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "test_readers", Description: "test_readers", Type: "leaky", Capacity: 1000, LeakSpeed: "10m",
+			Filter: "true", GroupBy: "evt.Meta.target_user", Sources: &SourcesCfg{}, MaxBuckets: 8},
+	}
+	if err := LoadBucket(&Holders[0]); err != nil {
+		t.Fatalf("while loading : %s", err)
+	}
+	Holders[0].ret = make(chan types.Event, 10)
+	now, _ := time.Now().MarshalText()
+
+	var pouring sync.WaitGroup
+	for routine := 0; routine < 4; routine++ {
+		pouring.Add(1)
+		go func(routine int) {
+			defer pouring.Done()
+			for i := 0; i < 500; i++ {
+				in := types.Event{Meta: map[string]string{"target_user": fmt.Sprintf("user%d", i%10),
+					"source_ip": fmt.Sprintf("1.2.%d.%d", routine, i%250)}, MarshaledTime: string(now)}
+				if _, err := PourItemToHolders(in, Holders, buckets); err != nil {
+					t.Errorf("while pouring item : %s", err)
+					return
+				}
+			}
+		}(routine)
+	}
+	stop := make(chan struct{})
+	var reading sync.WaitGroup
+	reading.Add(1)
+	go func() {
+		defer reading.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := SnapshotBucketsState(filepath.Join(dir, "snapshot.json"), buckets, Holders); err != nil {
+				t.Errorf("while writing snapshot : %s", err)
+				return
+			}
+			for _, info := range ListBuckets(buckets, "", "", 5) {
+				InspectBucket(buckets, info.Key)
+			}
+			file, err := DumpBucketsStateAt(time.Now(), dir, buckets, Holders)
+			if err != nil {
+				t.Errorf("while dumping : %s", err)
+				return
+			}
+			os.Remove(file)
+		}
+	}()
+	pouring.Wait()
+	close(stop)
+	reading.Wait()
+
+	if infos := ListBuckets(buckets, "test_readers", "", 0); len(infos) == 0 || len(infos) > 8 {
+		t.Fatalf("expected at most 8 buckets, got %d", len(infos))
+	}
+	if err := ShutdownAllBuckets(buckets); err != nil {
+		t.Fatalf("while shuting down buckets : %s", err)
+	}
+}
+
+func loadBenchHolders(b *testing.B) []BucketFactory {
+	var Holders = []BucketFactory{
+		BucketFactory{Name: "bench_leaky", Description: "bench_leaky", Type: "leaky", Capacity: 5, LeakSpeed: "1s",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+		BucketFactory{Name: "bench_counter", Description: "bench_counter", Type: "counter", Capacity: -1, Duration: "1m",
+			Filter: "true", GroupBy: "evt.Meta.source_ip"},
+	}
+	ret := make(chan types.Event, 100)
+	for idx := range Holders {
+		if err := LoadBucket(&Holders[idx]); err != nil {
+			b.Fatalf("while loading (%d/%d): %s", idx, len(Holders), err)
+		}
+		Holders[idx].ret = ret
+	}
+	/*the overflows have to be consumed*/
+	go func() {
+		for range ret {
+		}
+	}()
+	return Holders
+}
+
+func benchEvents(partitions int) []types.Event {
+	now, _ := time.Now().MarshalText()
+	events := make([]types.Event, partitions)
+	for i := range events {
+		events[i] = types.Event{Meta: map[string]string{"source_ip": fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)}, MarshaledTime: string(now)}
+	}
+	return events
+}
+
+//BenchmarkPourPartitions pours the events of many partitions from a single routine
+func BenchmarkPourPartitions(b *testing.B) {
+	for _, partitions := range []int{10, 10000} {
+		b.Run(fmt.Sprintf("%d", partitions), func(b *testing.B) {
+			log.SetLevel(log.ErrorLevel)
+			defer log.SetLevel(log.InfoLevel)
+
+			var buckets *Buckets = NewBuckets()
+			defer ShutdownAllBuckets(buckets)
+			Holders := loadBenchHolders(b)
+			events := benchEvents(partitions)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if _, err := PourItemToHolders(events[n%partitions], Holders, buckets); err != nil {
+					b.Fatalf("while pouring item : %s", err)
+				}
+			}
+		})
+	}
+}
+
+//BenchmarkPourParallel pours the events of many partitions from concurrent routines, like the buckets_routines of crowdsec
+func BenchmarkPourParallel(b *testing.B) {
+	log.SetLevel(log.ErrorLevel)
+	defer log.SetLevel(log.InfoLevel)
+
+	var buckets *Buckets = NewBuckets()
+	defer ShutdownAllBuckets(buckets)
+	Holders := loadBenchHolders(b)
+	events := benchEvents(10000)
+	var next int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			evt := events[atomic.AddInt64(&next, 1)%int64(len(events))]
+			if _, err := PourItemToHolders(evt, Holders, buckets); err != nil {
+				b.Fatalf("while pouring item : %s", err)
+			}
+		}
+	})
+}
+
+func BenchmarkTimerWheel(b *testing.B) {
+	start := time.Now()
+	wheel := newTimerWheel(wheelTick, wheelSlots)
+	bucketsList := make([]Leaky, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		now := start.Add(time.Duration(n) * time.Millisecond)
+		bucket := &bucketsList[n%len(bucketsList)]
+		bucket.deadline = now.Add(time.Duration(n%600) * time.Second)
+		if !bucket.scheduled {
+			wheel.add(bucket, now)
+		}
+		wheel.advance(now)
+	}
+}
@@ -0,0 +1,226 @@
+package leakybucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+Snapshots are written by live crowdsec at regular interval, and restored when it starts, so that a crash or an upgrade
+doesn't reset the buckets. Unlike the dumps (cf. DumpBucketsStateAt), they hold the blackhole memory of the scenarios as well,
+and the buckets that expired in the meantime are skipped at restore.
+The previous snapshot is kept (with a .1 suffix) in case the latest one is unreadable.
+*/
+type bucketsSnapshot struct {
+	Time       time.Time                             `json:"time"`
+	Buckets    map[string]Leaky                      `json:"buckets"`
+	Blackholes map[string][]HiddenKey                `json:"blackholes,omitempty"`
+	Baselines  map[string]map[string]AnomalyBaseline `json:"baselines,omitempty"`
+}
+
+func previousSnapshot(path string) string {
+	return path + ".1"
+}
+
+//SnapshotBucketsState writes atomically the state of the live buckets and of the blackholes of holders to path
+func SnapshotBucketsState(path string, buckets *Buckets, holders []BucketFactory) error {
+	now := time.Now()
+	snapshot := bucketsSnapshot{
+		Time:       now,
+		Buckets:    make(map[string]Leaky),
+		Blackholes: make(map[string][]HiddenKey),
+	}
+	/*the buckets are copied by their shards, as they go on meanwhile*/
+	visited := buckets.visit("", func(val *Leaky) {
+		/*buckets without events are being created, overflowed ones are on their way out*/
+		if val.First_ts.IsZero() || !val.Ovflw_ts.IsZero() {
+			return
+		}
+		if underflowAt(val, now) {
+			return
+		}
+		snapshot.Buckets[val.Mapkey] = copyLeaky(val)
+	})
+	if !visited {
+		/*don't replace the last snapshot with an empty one*/
+		return fmt.Errorf("buckets are stopped")
+	}
+	for _, holder := range holders {
+		for _, processor := range holder.processors {
+			if blackhole, ok := processor.(*Blackhole); ok {
+				if hidden := blackhole.hiddenKeysAt(now); len(hidden) > 0 {
+					snapshot.Blackholes[holder.Name] = hidden
+				}
+			}
+		}
+	}
+	snapshot.Baselines = holdersBaselines(holders)
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal buckets : %s", err)
+	}
+
+	tmpFd, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file : %s", err)
+	}
+	tmpFileName := tmpFd.Name()
+	if _, err := tmpFd.Write(body); err != nil {
+		tmpFd.Close()
+		os.Remove(tmpFileName)
+		return fmt.Errorf("failed to write %s : %s", tmpFileName, err)
+	}
+	/*the snapshot must be on disk before it replaces the previous one*/
+	if err := tmpFd.Sync(); err != nil {
+		tmpFd.Close()
+		os.Remove(tmpFileName)
+		return fmt.Errorf("failed to sync %s : %s", tmpFileName, err)
+	}
+	if err := tmpFd.Close(); err != nil {
+		os.Remove(tmpFileName)
+		return fmt.Errorf("failed to close %s : %s", tmpFileName, err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Rename(path, previousSnapshot(path)); err != nil {
+			log.Warningf("failed to keep previous buckets snapshot : %s", err)
+		}
+	}
+	if err := os.Rename(tmpFileName, path); err != nil {
+		os.Remove(tmpFileName)
+		return fmt.Errorf("failed to move snapshot to %s : %s", path, err)
+	}
+	log.Debugf("Snapshot of %d live buckets written to %s (%d bytes)", len(snapshot.Buckets), path, len(body))
+	return nil
+}
+
+func readSnapshot(path string) (*bucketsSnapshot, error) {
+	var snapshot bucketsSnapshot
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		return nil, fmt.Errorf("can't unmarshal %s : %s", path, err)
+	}
+	if snapshot.Time.IsZero() {
+		return nil, fmt.Errorf("%s has no snapshot time", path)
+	}
+	return &snapshot, nil
+}
+
+/*
+RestoreBucketsSnapshot restores the buckets and blackholes of the latest valid snapshot written at path by SnapshotBucketsState.
+Snapshots older than maxAge (if not 0) are ignored, as are the buckets that underflowed since, and the buckets of scenarios that aren't loaded anymore.
+*/
+func RestoreBucketsSnapshot(path string, maxAge time.Duration, buckets *Buckets, holders []BucketFactory) error {
+	for _, file := range []string{path, previousSnapshot(path)} {
+		snapshot, err := readSnapshot(file)
+		if os.IsNotExist(err) {
+			log.Debugf("no buckets snapshot at %s", file)
+			continue
+		}
+		if err != nil {
+			log.Warningf("ignoring buckets snapshot : %s", err)
+			continue
+		}
+		if maxAge != 0 && time.Since(snapshot.Time) > maxAge {
+			log.Warningf("buckets snapshot %s is too old (%s), don't restore it", file, snapshot.Time)
+			return nil
+		}
+		restoreSnapshot(snapshot, buckets, holders)
+		return nil
+	}
+	log.Infof("No buckets snapshot to restore")
+	return nil
+}
+
+func restoreSnapshot(snapshot *bucketsSnapshot, buckets *Buckets, holders []BucketFactory) {
+	now := time.Now()
+	factories := make(map[string]BucketFactory)
+	for _, holder := range holders {
+		factories[holder.Name] = holder
+	}
+
+	restored := 0
+	stale := 0
+	for k, v := range snapshot.Buckets {
+		if _, ok := buckets.Bucket_map.Load(k); ok {
+			log.Warningf("bucket %s already exists, don't restore it", k)
+			continue
+		}
+		factory, ok := factories[v.Name]
+		if !ok {
+			log.Warningf("scenario %s isn't loaded anymore, don't restore bucket %s", v.Name, k)
+			continue
+		}
+		if v.Mode != LIVE && v.Mode != HYBRID {
+			log.Warningf("bucket %s isn't a live bucket, don't restore it", k)
+			continue
+		}
+		tbucket := restoreBucket(k, v, factory)
+		if tbucket == nil {
+			continue
+		}
+		tbucket.logger = factory.logger
+		if underflowAt(tbucket, now) {
+			stale++
+			continue
+		}
+		startBucket(tbucket, buckets)
+		restored++
+	}
+
+	hidden := 0
+	for name, keys := range snapshot.Blackholes {
+		factory, ok := factories[name]
+		if !ok {
+			continue
+		}
+		for _, processor := range factory.processors {
+			if blackhole, ok := processor.(*Blackhole); ok {
+				hidden += blackhole.restoreHiddenKeys(keys, now)
+			}
+		}
+	}
+	learned := restoreHoldersBaselines(snapshot.Baselines, factories, now)
+	log.Infof("Restored %d buckets (%d expired), %d blackholed partitions and %d anomaly baselines from snapshot of %s", restored, stale, hidden, learned, snapshot.Time)
+}
+
+//holdersBaselines returns the anomaly baselines of the scenarios of holders, by scenario
+func holdersBaselines(holders []BucketFactory) map[string]map[string]AnomalyBaseline {
+	ret := make(map[string]map[string]AnomalyBaseline)
+	for _, holder := range holders {
+		for _, processor := range holder.processors {
+			if anomaly, ok := processor.(*Anomaly); ok {
+				if baselines := anomaly.dumpBaselines(); len(baselines) > 0 {
+					ret[holder.Name] = baselines
+				}
+			}
+		}
+	}
+	return ret
+}
+
+//restoreHoldersBaselines restores the anomaly baselines of the scenarios that are still loaded, and returns how many were
+func restoreHoldersBaselines(baselines map[string]map[string]AnomalyBaseline, factories map[string]BucketFactory, now time.Time) int {
+	learned := 0
+	for name, scenarioBaselines := range baselines {
+		factory, ok := factories[name]
+		if !ok {
+			continue
+		}
+		for _, processor := range factory.processors {
+			if anomaly, ok := processor.(*Anomaly); ok {
+				learned += anomaly.restoreBaselines(scenarioBaselines, now)
+			}
+		}
+	}
+	return learned
+}
@@ -0,0 +1,177 @@
+package leakybucket
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+/*
+A scenario can be a template : its `params` are substituted in its string values with the text/template syntax ({{ .param }}).
+A value that is only a parameter (ie. capacity: "{{ .threshold }}") takes the value of the parameter as is, so that it keeps its type.
+Its `instances` instantiate it several times with different parameters : each instance is a scenario of its own.
+*/
+
+//ScenarioInstance is an instantiation of a scenario template
+type ScenarioInstance struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description,omitempty"` //defaults to the description of the template
+	Params      map[string]interface{} `yaml:"params,omitempty"`      //overrides the defaults of the template
+}
+
+//paramOnly matches the values that are only a parameter
+var paramOnly = regexp.MustCompile(`^\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}$`)
+
+//expandScenario instantiates the scenario document, and returns the resulting scenarios
+func expandScenario(document yaml.MapSlice) ([]BucketFactory, error) {
+	var (
+		params    map[string]interface{}
+		instances []ScenarioInstance
+		body      yaml.MapSlice
+		name      string
+	)
+
+	for _, item := range document {
+		switch item.Key {
+		case "params":
+			if err := convertYaml(item.Value, &params); err != nil {
+				return nil, fmt.Errorf("invalid params : %s", err)
+			}
+		case "instances":
+			if err := convertYaml(item.Value, &instances); err != nil {
+				return nil, fmt.Errorf("invalid instances : %s", err)
+			}
+		default:
+			if item.Key == "name" {
+				name, _ = item.Value.(string)
+			}
+			body = append(body, item)
+		}
+	}
+
+	if len(instances) == 0 {
+		bucketFactory, err := instantiateScenario(body, params, ScenarioInstance{})
+		if err != nil {
+			return nil, err
+		}
+		return []BucketFactory{bucketFactory}, nil
+	}
+
+	ret := []BucketFactory{}
+	names := make(map[string]bool)
+	for idx, instance := range instances {
+		if instance.Name == "" {
+			return nil, fmt.Errorf("instance %d of %s has no name", idx, name)
+		}
+		if names[instance.Name] {
+			return nil, fmt.Errorf("instance %s of %s is declared twice", instance.Name, name)
+		}
+		names[instance.Name] = true
+		bucketFactory, err := instantiateScenario(body, params, instance)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s of %s : %s", instance.Name, name, err)
+		}
+		bucketFactory.Template = name
+		ret = append(ret, bucketFactory)
+	}
+	return ret, nil
+}
+
+func instantiateScenario(body yaml.MapSlice, defaults map[string]interface{}, instance ScenarioInstance) (BucketFactory, error) {
+	var bucketFactory BucketFactory
+
+	params := make(map[string]interface{}, len(defaults))
+	for k, v := range defaults {
+		params[k] = v
+	}
+	for k, v := range instance.Params {
+		if _, ok := defaults[k]; !ok {
+			return bucketFactory, fmt.Errorf("unknown param '%s'", k)
+		}
+		params[k] = v
+	}
+
+	rendered, err := renderValue(body, params)
+	if err != nil {
+		return bucketFactory, err
+	}
+	if err := convertYaml(rendered, &bucketFactory); err != nil {
+		return bucketFactory, err
+	}
+	if instance.Name != "" {
+		bucketFactory.Name = instance.Name
+	}
+	if instance.Description != "" {
+		bucketFactory.Description = instance.Description
+	}
+	if len(params) > 0 {
+		bucketFactory.Params = params
+	}
+	return bucketFactory, nil
+}
+
+//renderValue substitutes the params in all the strings of value
+func renderValue(value interface{}, params map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		if match := paramOnly.FindStringSubmatch(strings.TrimSpace(v)); match != nil {
+			param, ok := params[match[1]]
+			if !ok {
+				return nil, fmt.Errorf("unknown param '%s' in '%s'", match[1], v)
+			}
+			return param, nil
+		}
+		tmpl, err := template.New("").Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template '%s' : %s", v, err)
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, params); err != nil {
+			return nil, fmt.Errorf("while rendering '%s' : %s", v, err)
+		}
+		return out.String(), nil
+	case yaml.MapSlice:
+		ret := make(yaml.MapSlice, len(v))
+		for idx, item := range v {
+			rendered, err := renderValue(item.Value, params)
+			if err != nil {
+				return nil, err
+			}
+			ret[idx] = yaml.MapItem{Key: item.Key, Value: rendered}
+		}
+		return ret, nil
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for idx, item := range v {
+			rendered, err := renderValue(item, params)
+			if err != nil {
+				return nil, err
+			}
+			ret[idx] = rendered
+		}
+		return ret, nil
+	}
+	return value, nil
+}
+
+//convertYaml decodes a generic yaml value into out, rejecting unknown fields
+func convertYaml(value interface{}, out interface{}) error {
+	body, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalStrict(body, out)
+}
+
+//instanceHash is the hash of an instance of a scenario template, distinct for each instance of the same file
+func instanceHash(fileHash string, name string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fileHash+":"+name)))
+}
@@ -0,0 +1,132 @@
+package leakybucket
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crowdsecurity/crowdsec/pkg/csconfig"
+	"github.com/crowdsecurity/crowdsec/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+//ThrottleScenario is the scenario of the alerts summarizing the alerts dropped because a cap was reached
+const ThrottleScenario = "crowdsec/alerts-cap"
+
+var AlertsThrottled = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cs_alerts_throttled_total",
+		Help: "Total alerts not sent to the API because the max rate of alerts was reached.",
+	},
+	[]string{"name"},
+)
+
+/*
+AlertsThrottle caps the number of alerts sent in each interval, for each scenario and for all the scenarios. The blackholes only dedup
+the overflows of a partition : a widespread false positive (ie. a misparsed field) can still overflow for thousands of partitions.
+The alerts beyond the caps are dropped, and summarized at the end of the interval by one alert per scenario, without any decision.
+*/
+type AlertsThrottle struct {
+	cfg     csconfig.AlertsCapCfg
+	lock    sync.Mutex //the throttle is shared by the output routines
+	start   time.Time  //start of the current interval
+	total   int
+	counts  map[string]int
+	dropped map[string]int
+	reasons map[string]string
+}
+
+//NewAlertsThrottle returns the throttle of cfg, nil if there is no cap
+func NewAlertsThrottle(cfg *csconfig.AlertsCapCfg) *AlertsThrottle {
+	if cfg == nil {
+		return nil
+	}
+	return &AlertsThrottle{
+		cfg:     *cfg,
+		counts:  make(map[string]int),
+		dropped: make(map[string]int),
+		reasons: make(map[string]string),
+	}
+}
+
+func (t *AlertsThrottle) scenarioCap(scenario string) int {
+	if max, ok := t.cfg.Scenarios[scenario]; ok {
+		return max
+	}
+	return t.cfg.MaxPerScenario
+}
+
+//Allow counts the alert, and returns false if it must be dropped
+func (t *AlertsThrottle) Allow(alert types.RuntimeAlert, now time.Time) bool {
+	if t == nil || alert.Alert == nil || alert.Alert.Scenario == nil {
+		return true
+	}
+	scenario := *alert.Alert.Scenario
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.start.IsZero() {
+		t.start = now
+	}
+	if now.Sub(t.start) >= t.cfg.Interval && len(t.dropped) == 0 {
+		/*nothing to summarize, start a new interval right away*/
+		t.reset(now)
+	}
+
+	reason := ""
+	if max := t.scenarioCap(scenario); max > 0 && t.counts[scenario] >= max {
+		reason = fmt.Sprintf("more than %d alerts of this scenario", max)
+	} else if t.cfg.MaxAlerts > 0 && t.total >= t.cfg.MaxAlerts {
+		reason = fmt.Sprintf("more than %d alerts of all scenarios", t.cfg.MaxAlerts)
+	}
+	if reason == "" {
+		t.counts[scenario]++
+		t.total++
+		return true
+	}
+	if t.dropped[scenario] == 0 {
+		log.Warningf("scenario %s reached the max rate of alerts (%s in %s) : its alerts are dropped until %s", scenario, reason, t.cfg.Interval,
+			t.start.Add(t.cfg.Interval).Format(time.RFC3339))
+		t.reasons[scenario] = reason
+	}
+	t.dropped[scenario]++
+	AlertsThrottled.With(prometheus.Labels{"name": scenario}).Inc()
+	return false
+}
+
+func (t *AlertsThrottle) reset(now time.Time) {
+	t.start = now
+	t.total = 0
+	t.counts = make(map[string]int)
+	t.dropped = make(map[string]int)
+	t.reasons = make(map[string]string)
+}
+
+//Summaries returns the alerts summarizing the alerts dropped in the interval once it's over (or right away if force is set)
+func (t *AlertsThrottle) Summaries(now time.Time, force bool) []types.RuntimeAlert {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.start.IsZero() || (!force && now.Sub(t.start) < t.cfg.Interval) {
+		return nil
+	}
+	scenarios := make([]string, 0, len(t.dropped))
+	for scenario := range t.dropped {
+		scenarios = append(scenarios, scenario)
+	}
+	sort.Strings(scenarios)
+	ret := make([]types.RuntimeAlert, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		count := t.dropped[scenario]
+		message := fmt.Sprintf("%d alerts of scenario %s were dropped between %s and %s (%s in %s)", count, scenario,
+			t.start.Format(time.RFC3339), now.Format(time.RFC3339), t.reasons[scenario], t.cfg.Interval)
+		log.Warningf("%s", message)
+		ret = append(ret, newAgentAlert(ThrottleScenario, scenario, count, t.scenarioCap(scenario), message))
+	}
+	t.reset(now)
+	return ret
+}
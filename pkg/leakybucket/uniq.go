@@ -5,6 +5,7 @@ import (
 	"github.com/antonmedv/expr/vm"
 
 	"github.com/crowdsecurity/crowdsec/pkg/exprhelpers"
+	"github.com/crowdsecurity/crowdsec/pkg/sketch"
 	"github.com/crowdsecurity/crowdsec/pkg/types"
 )
 
@@ -26,6 +27,18 @@ func (u *Uniq) OnBucketPour(bucketFactory *BucketFactory) func(types.Event, *Lea
 			return &msg
 		}
 		leaky.logger.Tracef("Uniq '%s' -> '%s'", bucketFactory.Distinct, element)
+		/*in probabilistic mode, the values are kept in a bloom filter instead of being looked up in the queue*/
+		if bucketFactory.Probabilistic != nil {
+			if leaky.DistinctFilter == nil {
+				leaky.DistinctFilter = sketch.NewBloomFilter(bucketFactory.Probabilistic.expectedItems(), bucketFactory.Probabilistic.errorRate())
+			}
+			if leaky.DistinctFilter.TestAndAdd(element) {
+				leaky.logger.Debugf("Uniq(%s) : ko, discard event", element)
+				return nil
+			}
+			leaky.logger.Debugf("Uniq(%s) : ok", element)
+			return &msg
+		}
 		for _, evt := range leaky.Queue.GetQueue() {
 			if val, err := getElement(evt, u.DistinctCompiled); err == nil && val == element {
 				leaky.logger.Debugf("Uniq(%s) : ko, discard event", element)
@@ -55,7 +68,9 @@ func (u *Uniq) OnBucketInit(bucketFactory *BucketFactory) error {
 
 // getElement computes a string from an event and a filter
 func getElement(msg types.Event, cFilter *vm.Program) (string, error) {
-	el, err := expr.Run(cFilter, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &msg}))
+	env := exprhelpers.AcquireEvtEnv(&msg)
+	defer exprhelpers.ReleaseEvtEnv(env)
+	el, err := expr.Run(cFilter, env)
 	if err != nil {
 		return "", err
 	}
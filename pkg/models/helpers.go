@@ -1,5 +1,10 @@
 package models
 
+import (
+	"strconv"
+	"strings"
+)
+
 func (a *Alert) HasRemediation() bool {
 	return true
 }
@@ -17,3 +22,57 @@ func (a *Alert) GetScenario() string {
 	}
 	return *a.Scenario
 }
+
+func (a *Alert) GetValue() string {
+	if a.Source == nil || a.Source.Value == nil {
+		return ""
+	}
+	return *a.Source.Value
+}
+
+func (a *Alert) GetMeta(key string) string {
+	for _, meta := range a.Meta {
+		if meta != nil && meta.Key == key {
+			return meta.Value
+		}
+	}
+	return ""
+}
+
+//GetIncident returns the id of the incident the alert is part of, if any
+func (a *Alert) GetIncident() string {
+	return a.GetMeta("incident")
+}
+
+//GetSourcesCount returns the number of sources of the incident the alert is part of, 1 if it isn't part of one
+func (a *Alert) GetSourcesCount() int {
+	count, err := strconv.Atoi(a.GetMeta("incident_sources"))
+	if err != nil || count < 1 {
+		return 1
+	}
+	return count
+}
+
+//GetLabel returns the value of the 'key:value' label of the alert
+func (a *Alert) GetLabel(key string) string {
+	for _, label := range a.Labels {
+		if kv := strings.SplitN(label, ":", 2); len(kv) == 2 && kv[0] == key {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+//GetSeverity returns the severity of the scenario of the alert, if any
+func (a *Alert) GetSeverity() string {
+	return a.GetLabel("severity")
+}
+
+//GetConfidence returns the confidence (0-100) of the scenario of the alert, 0 if it has none
+func (a *Alert) GetConfidence() int {
+	confidence, err := strconv.Atoi(a.GetLabel("confidence"))
+	if err != nil {
+		return 0
+	}
+	return confidence
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"net"
+	"reflect"
 	"strings"
 
 	"github.com/antonmedv/expr"
@@ -36,11 +37,13 @@ type Node struct {
 	//OnSuccess allows to tag a node to be able to move log to next stage on success
 	OnSuccess string `yaml:"onsuccess,omitempty"`
 	rn        string //this is only for us in debug, a random generated name for each node
+	position  string //where the node is defined (file and index of the node, then of the child nodes), to track unnamed nodes
 	//Filter is executed at runtime (with current log line as context)
 	//and must succeed or node is exited
 	Filter        string                    `yaml:"filter,omitempty"`
 	RunTimeFilter *vm.Program               `yaml:"-" json:"-"` //the actual compiled filter
 	ExprDebugger  *exprhelpers.ExprDebugger `yaml:"-" json:"-"` //used to debug expression by printing the content of each variable of the expression
+	guard         *exprhelpers.ExprGuard    //counts the faults of filter and statics expressions, the node is skipped once quarantined
 	//If node has leafs, execute all of them until one asks for a 'break'
 	LeavesNodes []Node `yaml:"nodes,omitempty"`
 	//Flag used to describe when to 'break' or return an 'error'
@@ -114,27 +117,25 @@ func (n *Node) process(p *types.Event, ctx UnixParserCtx) (bool, error) {
 	clog := n.logger
 
 	clog.Tracef("Event entering node")
+	if n.guard.Quarantined() {
+		clog.Debugf("Event leaving node : ko (quarantined)")
+		return false, nil
+	}
+	env := exprhelpers.AcquireEvtEnv(p)
+	defer exprhelpers.ReleaseEvtEnv(env)
 	if n.RunTimeFilter != nil {
 		//Evaluate node's filter
-		output, err := expr.Run(n.RunTimeFilter, exprhelpers.GetExprEnv(map[string]interface{}{"evt": p}))
+		out, err := n.guard.RunBool(n.RunTimeFilter, env)
 		if err != nil {
 			clog.Warningf("failed to run filter : %v", err)
 			clog.Debugf("Event leaving node : ko")
 			return false, nil
 		}
-
-		switch out := output.(type) {
-		case bool:
-			if n.Debug {
-				n.ExprDebugger.Run(clog, out, exprhelpers.GetExprEnv(map[string]interface{}{"evt": p}))
-			}
-			if !out {
-				clog.Debugf("Event leaving node : ko (failed filter)")
-				return false, nil
-			}
-		default:
-			clog.Warningf("Expr '%s' returned non-bool, abort : %T", n.Filter, output)
-			clog.Debugf("Event leaving node : ko")
+		if n.Debug {
+			n.ExprDebugger.Run(clog, out, env)
+		}
+		if !out {
+			clog.Debugf("Event leaving node : ko (failed filter)")
 			return false, nil
 		}
 		NodeState = true
@@ -190,7 +191,7 @@ func (n *Node) process(p *types.Event, ctx UnixParserCtx) (bool, error) {
 	}
 	/* run whitelist expression tests anyway */
 	for eidx, e := range n.Whitelist.B_Exprs {
-		output, err := expr.Run(e.Filter, exprhelpers.GetExprEnv(map[string]interface{}{"evt": p}))
+		output, err := expr.Run(e.Filter, env)
 		if err != nil {
 			clog.Warningf("failed to run whitelist expr : %v", err)
 			clog.Debugf("Event leaving node : ko")
@@ -199,7 +200,7 @@ func (n *Node) process(p *types.Event, ctx UnixParserCtx) (bool, error) {
 		switch out := output.(type) {
 		case bool:
 			if n.Debug {
-				e.ExprDebugger.Run(clog, out, exprhelpers.GetExprEnv(map[string]interface{}{"evt": p}))
+				e.ExprDebugger.Run(clog, out, env)
 			}
 			if out {
 				clog.Infof("Event is whitelisted by Expr !")
@@ -385,12 +386,26 @@ func (n *Node) compile(pctx *UnixParserCtx, ectx []EnricherCtx) error {
 
 	n.logger.Tracef("Compiling : %s", dumpr.Sdump(n))
 
+	/*unnamed nodes are tracked by their stage and position, that are the same from one run (or reload) to the other*/
+	if n.Name != "" {
+		n.guard = exprhelpers.NewExprGuard("parser", n.Name, n.logger)
+	} else {
+		n.guard = exprhelpers.NewExprGuard("parser", fmt.Sprintf("%s/%s", n.Stage, n.position), n.logger)
+	}
+
 	//compile filter if present
 	if n.Filter != "" {
 		n.RunTimeFilter, err = expr.Compile(n.Filter, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
 		if err != nil {
 			return fmt.Errorf("compilation of '%s' failed: %v", n.Filter, err)
 		}
+		if err := exprhelpers.CheckReturnKind(n.Filter, exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}}), reflect.Bool); err != nil {
+			return fmt.Errorf("invalid filter : %v", err)
+		}
+		/*postoverflows can look into the history when the local API runs in the same process*/
+		if err := exprhelpers.CheckHistoryDurations(n.Filter); err != nil {
+			return fmt.Errorf("invalid filter : %v", err)
+		}
 
 		if n.Debug {
 			n.ExprDebugger, err = exprhelpers.NewDebugger(n.Filter, expr.Env(exprhelpers.GetExprEnv(map[string]interface{}{"evt": &types.Event{}})))
@@ -464,6 +479,7 @@ func (n *Node) compile(pctx *UnixParserCtx, ectx []EnricherCtx) error {
 				n.LeavesNodes[idx].Profiling = true
 			}
 			n.LeavesNodes[idx].Stage = n.Stage
+			n.LeavesNodes[idx].position = fmt.Sprintf("%s/%d", n.position, idx)
 			err = n.LeavesNodes[idx].compile(pctx, ectx)
 			if err != nil {
 				return err
@@ -128,6 +128,7 @@ func testOneParser(pctx *UnixParserCtx, ectx []EnricherCtx, dir string, b *testi
 	count := 1
 	if b != nil {
 		count = b.N
+		b.ReportAllocs()
 		b.ResetTimer()
 	}
 	for n := 0; n < count; n++ {
@@ -6,7 +6,6 @@ package parser
 */
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -22,7 +21,6 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 
-	"github.com/antonmedv/expr"
 )
 
 /* ok, this is kinda experimental, I don't know how bad of an idea it is .. */
@@ -110,13 +108,15 @@ func (n *Node) ProcessStatics(statics []types.ExtraField, event *types.Event) er
 	//(meta||key) + (static||reference||expr)
 	var value string
 	clog := n.logger
+	env := exprhelpers.AcquireEvtEnv(event)
+	defer exprhelpers.ReleaseEvtEnv(env)
 
 	for _, static := range statics {
 		value = ""
 		if static.Value != "" {
 			value = static.Value
 		} else if static.RunTimeValue != nil {
-			output, err := expr.Run(static.RunTimeValue, exprhelpers.GetExprEnv(map[string]interface{}{"evt": event}))
+			output, err := n.guard.Run(static.RunTimeValue, env)
 			if err != nil {
 				clog.Warningf("failed to run RunTimeValue : %v", err)
 				continue
@@ -127,8 +127,9 @@ func (n *Node) ProcessStatics(statics []types.ExtraField, event *types.Event) er
 			case int:
 				value = strconv.Itoa(out)
 			default:
-				clog.Fatalf("unexpected return type for RunTimeValue : %T", output)
-				return errors.New("unexpected return type for RunTimeValue")
+				clog.Warningf("unexpected return type for RunTimeValue : %T", output)
+				n.guard.Fault(fmt.Errorf("unexpected return type for RunTimeValue : %T", output))
+				continue
 			}
 		}
 
@@ -13,6 +13,7 @@ import (
 	"io"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -63,9 +64,10 @@ func LoadStages(stageFiles []Stagefile, pctx *UnixParserCtx, ectx []EnricherCtx)
 		dec := yaml.NewDecoder(yamlFile)
 		dec.SetStrict(true)
 		nodesCount := 0
-		for {
+		for position := 0; ; position++ {
 			node := Node{}
 			node.OnSuccess = "continue" //default behaviour is to continue
+			node.position = fmt.Sprintf("%s:%d", filepath.Base(stageFile.Filename), position)
 			err = dec.Decode(&node)
 			if err != nil {
 				if err == io.EOF {
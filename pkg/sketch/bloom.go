@@ -0,0 +1,78 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+)
+
+//BloomFilter tests the membership of values with a bounded false positive rate, and no false negatives
+type BloomFilter struct {
+	Bits   []byte `json:"bits"`
+	Hashes uint32 `json:"hashes"`
+}
+
+//NewBloomFilter returns a BloomFilter sized to hold expectedItems values with a false positive rate of errorRate (ie. 0.01 for 1%)
+func NewBloomFilter(expectedItems int, errorRate float64) *BloomFilter {
+	if expectedItems <= 0 {
+		expectedItems = 1
+	}
+	if errorRate <= 0 || errorRate >= 1 {
+		errorRate = 0.01
+	}
+	size := math.Ceil(-float64(expectedItems) * math.Log(errorRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(size / float64(expectedItems) * math.Ln2)
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &BloomFilter{
+		Bits:   make([]byte, (uint64(size)+7)/8),
+		Hashes: uint32(hashes),
+	}
+}
+
+//Validate checks the consistency of a deserialized BloomFilter
+func (b *BloomFilter) Validate() error {
+	if len(b.Bits) == 0 {
+		return fmt.Errorf("empty bloom filter")
+	}
+	if b.Hashes == 0 {
+		return fmt.Errorf("bloom filter without hashes")
+	}
+	return nil
+}
+
+/*the positions are derived from two halves of one hash (Kirsch-Mitzenmacher)*/
+func (b *BloomFilter) positions(value string, fn func(uint64) bool) bool {
+	x := hash64(value)
+	h1, h2 := x&0xffffffff, x>>32
+	size := uint64(len(b.Bits)) * 8
+	for i := uint64(0); i < uint64(b.Hashes); i++ {
+		if !fn((h1 + i*h2) % size) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *BloomFilter) Add(value string) {
+	b.positions(value, func(pos uint64) bool {
+		b.Bits[pos/8] |= 1 << (pos % 8)
+		return true
+	})
+}
+
+//Test returns true if value was probably added, false if it was definitely not
+func (b *BloomFilter) Test(value string) bool {
+	return b.positions(value, func(pos uint64) bool {
+		return b.Bits[pos/8]&(1<<(pos%8)) != 0
+	})
+}
+
+//TestAndAdd adds value and returns the result of Test before the addition
+func (b *BloomFilter) TestAndAdd(value string) bool {
+	present := b.Test(value)
+	if !present {
+		b.Add(value)
+	}
+	return present
+}
@@ -0,0 +1,18 @@
+package sketch
+
+import (
+	"hash/fnv"
+)
+
+//hash64 returns a well distributed 64 bits hash of value (fnv-1a followed by the murmur3 finalizer, fnv alone has a poor avalanche on short strings)
+func hash64(value string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
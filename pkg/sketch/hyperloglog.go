@@ -0,0 +1,90 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	minPrecision = 4
+	maxPrecision = 16
+)
+
+//HyperLogLog estimates the number of distinct values added to it, using 2^Precision bytes whatever the number of values
+type HyperLogLog struct {
+	Precision uint8  `json:"precision"`
+	Registers []byte `json:"registers"`
+}
+
+//NewHyperLogLog returns a HyperLogLog whose standard error is at most errorRate (ie. 0.01 for 1%), within the supported precisions
+func NewHyperLogLog(errorRate float64) *HyperLogLog {
+	precision := uint8(maxPrecision)
+	if errorRate > 0 && errorRate < 1 {
+		/*the standard error is 1.04/sqrt(m)*/
+		m := math.Pow(1.04/errorRate, 2)
+		precision = uint8(math.Ceil(math.Log2(m)))
+	}
+	if precision < minPrecision {
+		precision = minPrecision
+	}
+	if precision > maxPrecision {
+		precision = maxPrecision
+	}
+	return &HyperLogLog{
+		Precision: precision,
+		Registers: make([]byte, 1<<precision),
+	}
+}
+
+//Validate checks the consistency of a deserialized HyperLogLog
+func (h *HyperLogLog) Validate() error {
+	if h.Precision < minPrecision || h.Precision > maxPrecision {
+		return fmt.Errorf("invalid precision %d", h.Precision)
+	}
+	if len(h.Registers) != 1<<h.Precision {
+		return fmt.Errorf("expected %d registers, got %d", 1<<h.Precision, len(h.Registers))
+	}
+	return nil
+}
+
+func (h *HyperLogLog) Add(value string) {
+	x := hash64(value)
+	idx := x >> (64 - h.Precision)
+	/*the index bits are shifted out, the trailing bit bounds the rank*/
+	w := x<<h.Precision | 1<<(h.Precision-1)
+	rank := uint8(bits.LeadingZeros64(w) + 1)
+	if rank > h.Registers[idx] {
+		h.Registers[idx] = rank
+	}
+}
+
+//Count returns the estimated number of distinct values
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.Registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.Registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	var alpha float64
+	switch len(h.Registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	estimate := alpha * m * m / sum
+	/*small range correction : linear counting is more accurate*/
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
@@ -0,0 +1,82 @@
+package sketch
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHyperLogLogCount(t *testing.T) {
+	for _, errorRate := range []float64{0.01, 0.05} {
+		for _, n := range []int{0, 10, 1000, 100000} {
+			h := NewHyperLogLog(errorRate)
+			for i := 0; i < n; i++ {
+				h.Add(fmt.Sprintf("192.168.%d.%d", i/256, i%256))
+				//duplicates must not be counted
+				h.Add(fmt.Sprintf("192.168.%d.%d", i/256, i%256))
+			}
+			count := float64(h.Count())
+			//allow 3 standard errors
+			assert.InDelta(t, float64(n), count, 3*errorRate*float64(n)+1, "error rate %f, %d values", errorRate, n)
+		}
+	}
+}
+
+func TestHyperLogLogPrecision(t *testing.T) {
+	assert.Equal(t, uint8(14), NewHyperLogLog(0.01).Precision)
+	assert.Equal(t, uint8(minPrecision), NewHyperLogLog(0.9).Precision)
+	assert.Equal(t, uint8(maxPrecision), NewHyperLogLog(0.0001).Precision)
+	assert.Equal(t, uint8(maxPrecision), NewHyperLogLog(0).Precision)
+}
+
+func TestBloomFilter(t *testing.T) {
+	n := 10000
+	errorRate := 0.01
+	b := NewBloomFilter(n, errorRate)
+	for i := 0; i < n; i++ {
+		b.TestAndAdd(fmt.Sprintf("/path/%d", i))
+	}
+	//no false negatives
+	for i := 0; i < n; i++ {
+		require.True(t, b.Test(fmt.Sprintf("/path/%d", i)))
+	}
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if b.Test(fmt.Sprintf("/other/%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, float64(falsePositives)/float64(n), 2*errorRate)
+}
+
+func TestSerialization(t *testing.T) {
+	h := NewHyperLogLog(0.02)
+	b := NewBloomFilter(100, 0.01)
+	for i := 0; i < 50; i++ {
+		h.Add(fmt.Sprintf("%d", i))
+		b.Add(fmt.Sprintf("%d", i))
+	}
+	body, err := json.Marshal(struct {
+		H *HyperLogLog
+		B *BloomFilter
+	}{h, b})
+	require.NoError(t, err)
+
+	restored := struct {
+		H *HyperLogLog
+		B *BloomFilter
+	}{}
+	require.NoError(t, json.Unmarshal(body, &restored))
+	require.NoError(t, restored.H.Validate())
+	require.NoError(t, restored.B.Validate())
+	assert.Equal(t, h.Count(), restored.H.Count())
+	assert.True(t, restored.B.Test("42"))
+	assert.True(t, math.Abs(float64(restored.H.Count())-50) < 5)
+
+	assert.Error(t, (&HyperLogLog{Precision: 10, Registers: make([]byte, 12)}).Validate())
+	assert.Error(t, (&BloomFilter{}).Validate())
+}
@@ -1,70 +1,180 @@
 package types
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+//dataDownloadTimeout bounds a download of a data file, so that an unresponsive source can't hang its caller
+const dataDownloadTimeout = 5 * time.Minute
+
+var dataClient = &http.Client{Timeout: dataDownloadTimeout}
+
 type DataSource struct {
-	SourceURL string `yaml:"source_url"`
-	DestPath  string `yaml:"dest_file"`
-	Type      string `yaml:"type"`
+	SourceURL       string `yaml:"source_url"`
+	DestPath        string `yaml:"dest_file"`
+	Type            string `yaml:"type"`
+	RefreshInterval string `yaml:"refresh_interval,omitempty"` //if set, crowdsec checks the source for updates at this interval (ie. '6h')
 }
 
 type DataSet struct {
 	Data []*DataSource `yaml:"data,omitempty"`
 }
 
-func downloadFile(url string, destPath string) error {
-	log.Debugf("downloading %s in %s", url, destPath)
-	req, err := http.NewRequest("GET", url, nil)
+//DataStatus is stored next to each downloaded data file (<dest_file>.meta) to allow conditional requests
+type DataStatus struct {
+	SourceURL    string    `json:"source_url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	LastCheck    time.Time `json:"last_check"`
+	LastUpdate   time.Time `json:"last_update"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+//GetRefreshInterval returns the parsed refresh_interval, 0 if it's not set
+func (d *DataSource) GetRefreshInterval() (time.Duration, error) {
+	if d.RefreshInterval == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(d.RefreshInterval)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("invalid refresh_interval '%s' for %s : %s", d.RefreshInterval, d.DestPath, err)
 	}
+	return interval, nil
+}
+
+func dataStatusPath(destPath string) string {
+	return destPath + ".meta"
+}
 
-	resp, err := http.DefaultClient.Do(req)
+//GetDataStatus reads the status of a downloaded data file, an empty status is returned if there is none
+func GetDataStatus(destPath string) (*DataStatus, error) {
+	status := &DataStatus{}
+	body, err := ioutil.ReadFile(dataStatusPath(destPath))
 	if err != nil {
-		return err
+		if os.IsNotExist(err) {
+			return status, nil
+		}
+		return status, err
 	}
-	defer resp.Body.Close()
+	if err := json.Unmarshal(body, status); err != nil {
+		return status, fmt.Errorf("while reading %s : %s", dataStatusPath(destPath), err)
+	}
+	return status, nil
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+func writeDataStatus(destPath string, status *DataStatus) error {
+	body, err := json.MarshalIndent(status, "", " ")
 	if err != nil {
 		return err
 	}
+	return ioutil.WriteFile(dataStatusPath(destPath), body, 0644)
+}
+
+/*
+downloadFile streams url to a temporary file that is renamed to destPath once complete, so that readers never see a partial file.
+When conditional is true and the previous download is still present, ETag/If-Modified-Since are sent and a 304 leaves the file untouched.
+It returns true if destPath was (re)written.
+*/
+func downloadFile(ctx context.Context, url string, destPath string, conditional bool) (bool, error) {
+	log.Debugf("downloading %s in %s", url, destPath)
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download response 'HTTP %d' : %s", resp.StatusCode, string(body))
+	status, err := GetDataStatus(destPath)
+	if err != nil {
+		log.Warningf("ignoring data status : %s", err)
+		status = &DataStatus{}
+	}
+	if status.SourceURL != url {
+		status = &DataStatus{SourceURL: url}
 	}
+	status.LastCheck = time.Now()
 
-	file, err := os.OpenFile(destPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	updated, err := fetchFile(ctx, url, destPath, status, conditional)
+	status.LastError = ""
 	if err != nil {
-		return err
+		status.LastError = err.Error()
 	}
+	if werr := writeDataStatus(destPath, status); werr != nil {
+		log.Warningf("unable to write data status for %s : %s", destPath, werr)
+	}
+	return updated, err
+}
 
-	_, err = file.WriteString(string(body))
+func fetchFile(ctx context.Context, url string, destPath string, status *DataStatus, conditional bool) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if _, err := os.Stat(destPath); conditional && err == nil {
+		if status.ETag != "" {
+			req.Header.Set("If-None-Match", status.ETag)
+		}
+		if status.LastModified != "" {
+			req.Header.Set("If-Modified-Since", status.LastModified)
+		}
 	}
 
-	err = file.Sync()
+	resp, err := dataClient.Do(req)
 	if err != nil {
-		return err
+		return false, err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	if resp.StatusCode == http.StatusNotModified {
+		log.Debugf("%s not modified", url)
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("download response 'HTTP %d' : %s", resp.StatusCode, string(body))
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(destPath), filepath.Base(destPath)+".tmp-")
+	if err != nil {
+		return false, err
+	}
+	/*no-op once renamed*/
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
+		tmpFile.Close()
+		return false, fmt.Errorf("while downloading %s : %s", url, err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return false, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmpFile.Name(), destPath); err != nil {
+		return false, err
+	}
+
+	status.ETag = resp.Header.Get("ETag")
+	status.LastModified = resp.Header.Get("Last-Modified")
+	status.LastUpdate = time.Now()
+	return true, nil
 }
 
 func GetData(data []*DataSource, dataDir string) error {
 	for _, dataS := range data {
 		destPath := path.Join(dataDir, dataS.DestPath)
 		log.Infof("downloading data '%s' in '%s'", dataS.SourceURL, destPath)
-		err := downloadFile(dataS.SourceURL, destPath)
+		_, err := downloadFile(context.Background(), dataS.SourceURL, destPath, false)
 		if err != nil {
 			return err
 		}
@@ -72,3 +182,19 @@ func GetData(data []*DataSource, dataDir string) error {
 
 	return nil
 }
+
+/*
+RefreshData checks if the source of the data file changed (using ETag/If-Modified-Since) and downloads it if needed, it returns true if the file was updated.
+The download is aborted when ctx is done.
+*/
+func RefreshData(ctx context.Context, dataS *DataSource, dataDir string) (bool, error) {
+	destPath := path.Join(dataDir, dataS.DestPath)
+	updated, err := downloadFile(ctx, dataS.SourceURL, destPath, true)
+	if err != nil {
+		return false, err
+	}
+	if updated {
+		log.Infof("data '%s' updated from '%s'", destPath, dataS.SourceURL)
+	}
+	return updated, nil
+}
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshData(t *testing.T) {
+	content := "1.2.3.4\n"
+	etag := `"v1"`
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(content))
+	}))
+	defer ts.Close()
+
+	dataDir, err := ioutil.TempDir("", "crowdsec-data")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	source := &DataSource{SourceURL: ts.URL, DestPath: "ips.txt", Type: "string", RefreshInterval: "1h"}
+	require.NoError(t, GetData([]*DataSource{source}, dataDir))
+	body, err := ioutil.ReadFile(filepath.Join(dataDir, "ips.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(body))
+
+	status, err := GetDataStatus(filepath.Join(dataDir, "ips.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, etag, status.ETag)
+	assert.False(t, status.LastUpdate.IsZero())
+
+	//unchanged source : 304
+	updated, err := RefreshData(context.Background(), source, dataDir)
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	//changed source
+	content = "5.6.7.8\n"
+	etag = `"v2"`
+	updated, err = RefreshData(context.Background(), source, dataDir)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	body, err = ioutil.ReadFile(filepath.Join(dataDir, "ips.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(body))
+	assert.Equal(t, 3, requests)
+
+	//no temporary file left behind
+	files, err := ioutil.ReadDir(dataDir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	interval, err := source.GetRefreshInterval()
+	require.NoError(t, err)
+	assert.Equal(t, "1h0m0s", interval.String())
+}
+
+func TestRefreshDataError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	dataDir, err := ioutil.TempDir("", "crowdsec-data")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	source := &DataSource{SourceURL: ts.URL, DestPath: "ips.txt", Type: "string"}
+	_, err = RefreshData(context.Background(), source, dataDir)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dataDir, "ips.txt"))
+	assert.True(t, os.IsNotExist(err))
+	status, err := GetDataStatus(filepath.Join(dataDir, "ips.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, status.LastError, "HTTP 500")
+}
+
+func TestRefreshDataCanceled(t *testing.T) {
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	dataDir, err := ioutil.TempDir("", "crowdsec-data")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	//the source never answers : the refresh stops when it is canceled
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	source := &DataSource{SourceURL: ts.URL, DestPath: "ips.txt", Type: "string"}
+	_, err = RefreshData(ctx, source, dataDir)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dataDir, "ips.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
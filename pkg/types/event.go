@@ -1,6 +1,7 @@
 package types
 
 import (
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -59,6 +60,37 @@ const (
 	Filter    = "Filter"
 )
 
+//The metas of the alerts of an incident : the alerts of the distinct sources of a distributed attack, from a single overflow
+const (
+	IncidentMeta        = "incident"         //the id of the incident
+	IncidentTargetMeta  = "incident_target"  //the partition of the bucket, ie. the targeted user or host
+	IncidentSourcesMeta = "incident_sources" //the number of sources of the incident
+	IncidentLeaderMeta  = "incident_leader"  //the value of the source that carries the decisions taken once for the whole incident
+)
+
+//The labels of the alerts set from the attributes of their scenario
+const (
+	SeverityLabel   = "severity"   //one of the severity levels
+	ConfidenceLabel = "confidence" //how confident the scenario is of its detections, from 0 to 100
+)
+
+//SeverityLevels are the severities a scenario can have, from the lowest to the highest
+var SeverityLevels = []string{"info", "low", "medium", "high", "critical"}
+
+//JoinLabel returns the label 'key:value' as carried by the alerts
+func JoinLabel(key string, value string) string {
+	return key + ":" + value
+}
+
+//SplitLabel returns the key and value of the label 'key:value', the label without colon is a key with an empty value
+func SplitLabel(label string) (string, string) {
+	kv := strings.SplitN(label, ":", 2)
+	if len(kv) < 2 {
+		return kv[0], ""
+	}
+	return kv[0], kv[1]
+}
+
 //Move in leakybuckets
 type ScopeType struct {
 	Scope         string `yaml:"type"`